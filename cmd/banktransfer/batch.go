@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"strings"
+
+	"go-design-patterns/structural/facade"
+)
+
+// batchColumns are the columns a batch file may have and whether each is
+// required.
+var batchColumns = []struct {
+	name     string
+	required bool
+}{
+	{"id", false},
+	{"from_bank", true},
+	{"from_account", true},
+	{"pin", true},
+	{"to_bank", true},
+	{"to_account", true},
+	{"amount", true},
+	{"currency", false},
+	{"idempotency_key", false},
+}
+
+// readBatch reads batch items from CSV with a header row naming the
+// columns, in any order. Rows without a currency use currency.
+func readBatch(r io.Reader, currency string) ([]facade.BatchItem, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, usagef("batch file is empty")
+	}
+	if err != nil {
+		return nil, &usageError{err: err}
+	}
+
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		index[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	known := make(map[string]bool, len(batchColumns))
+	for _, column := range batchColumns {
+		known[column.name] = true
+		if _, present := index[column.name]; column.required && !present {
+			return nil, usagef("batch file: missing column %q", column.name)
+		}
+	}
+	for _, name := range header {
+		if name = strings.ToLower(strings.TrimSpace(name)); !known[name] {
+			return nil, usagef("batch file: unknown column %q", name)
+		}
+	}
+
+	var items []facade.BatchItem
+	for {
+		row, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, &usageError{err: err}
+		}
+		field := func(name string) string {
+			if i, present := index[name]; present {
+				return strings.TrimSpace(row[i])
+			}
+			return ""
+		}
+
+		rowCurrency := field("currency")
+		if rowCurrency == "" {
+			rowCurrency = currency
+		}
+		line, _ := cr.FieldPos(0)
+		amount, err := facade.ParseMoney(field("amount"), rowCurrency)
+		if err != nil {
+			return nil, usagef("batch file line %d: %v", line, err)
+		}
+		items = append(items, facade.BatchItem{
+			ID:             field("id"),
+			FromBank:       field("from_bank"),
+			FromAccount:    field("from_account"),
+			Pin:            field("pin"),
+			ToBank:         field("to_bank"),
+			ToAccount:      field("to_account"),
+			Amount:         amount,
+			IdempotencyKey: field("idempotency_key"),
+		})
+	}
+	if len(items) == 0 {
+		return nil, usagef("batch file has no transfers")
+	}
+	return items, nil
+}
@@ -0,0 +1,600 @@
+// Command banktransfer drives the bank transfer facade and its simulated
+// banks from the command line.
+//
+// Usage:
+//
+//	banktransfer <command> [flags]
+//
+// Commands:
+//
+//	transfer    move money from one account to another
+//	batch       run the transfers listed in a CSV file
+//	history     list past transfers
+//	balance     show the balance of an account
+//	list-banks  list the banks of the directory
+//
+// Every command accepts -json to print JSON instead of text, -state DIR to
+// keep balances, history and idempotency keys in DIR between runs (without
+// it every run starts from the demo accounts) and -audit FILE to append the
+// facade's events to a hash-chained audit log. Runs sharing a state
+// directory must not overlap.
+//
+// Results go to stdout and errors to stderr. The exit code is 0 on success,
+// 1 if the operation failed, 2 for usage errors, 3 if some items of a batch
+// did not succeed and 4 if a bank was unavailable and retrying may help.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"go-design-patterns/structural/facade"
+)
+
+const (
+	exitOK          = 0
+	exitFailed      = 1
+	exitUsage       = 2
+	exitPartial     = 3
+	exitUnavailable = 4
+)
+
+// usageError is a mistake in the command line or its input files.
+type usageError struct {
+	err error
+}
+
+func (e *usageError) Error() string {
+	return e.err.Error()
+}
+
+func (e *usageError) Unwrap() error {
+	return e.err
+}
+
+func usagef(format string, args ...any) error {
+	return &usageError{err: fmt.Errorf(format, args...)}
+}
+
+// partialError reports a batch in which only some items succeeded.
+type partialError struct {
+	failed, incomplete, skipped int
+}
+
+func (e *partialError) Error() string {
+	return fmt.Sprintf("%d items failed, %d incomplete, %d skipped", e.failed, e.incomplete, e.skipped)
+}
+
+// output is the result of a command, printed as text or JSON.
+type output interface {
+	writeText(w io.Writer) error
+}
+
+type command struct {
+	name    string
+	summary string
+	run     func(ctx context.Context, args []string, stdin io.Reader, stderr io.Writer) (output, *options, error)
+}
+
+var commands = []command{
+	{"transfer", "move money from one account to another", runTransfer},
+	{"batch", "run the transfers listed in a CSV file", runBatch},
+	{"history", "list past transfers", runHistory},
+	{"balance", "show the balance of an account", runBalance},
+	{"list-banks", "list the banks of the directory", runListBanks},
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	code := run(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr)
+	stop()
+	os.Exit(code)
+}
+
+// run executes the command line args and returns the exit code.
+func run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		printUsage(stderr)
+		return exitUsage
+	}
+	if args[0] == "help" || args[0] == "-h" || args[0] == "-help" || args[0] == "--help" {
+		printUsage(stdout)
+		return exitOK
+	}
+
+	for _, cmd := range commands {
+		if cmd.name != args[0] {
+			continue
+		}
+		out, opts, err := cmd.run(ctx, args[1:], stdin, stderr)
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		asJSON := opts != nil && opts.json
+		if out != nil {
+			if writeErr := writeOutput(stdout, out, asJSON); writeErr != nil {
+				err = errors.Join(err, writeErr)
+			}
+		}
+		if err != nil {
+			writeError(stderr, err, asJSON)
+		}
+		return exitCode(err)
+	}
+
+	fmt.Fprintf(stderr, "banktransfer: unknown command %q\n\n", args[0])
+	printUsage(stderr)
+	return exitUsage
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: banktransfer <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-11s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run 'banktransfer <command> -h' for the flags of a command.")
+}
+
+func writeOutput(w io.Writer, out output, asJSON bool) error {
+	if !asJSON {
+		return out.writeText(w)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+func writeError(w io.Writer, err error, asJSON bool) {
+	if !asJSON {
+		fmt.Fprintf(w, "banktransfer: %v\n", err)
+		return
+	}
+	json.NewEncoder(w).Encode(struct {
+		Error    string `json:"error"`
+		ExitCode int    `json:"exit_code"`
+	}{err.Error(), exitCode(err)})
+}
+
+// exitCode maps the error of a command to the exit code of the process.
+func exitCode(err error) int {
+	var usageErr *usageError
+	var partialErr *partialError
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.As(err, &usageErr):
+		return exitUsage
+	case errors.As(err, &partialErr):
+		return exitPartial
+	case facade.IsTransient(err):
+		return exitUnavailable
+	default:
+		return exitFailed
+	}
+}
+
+// options are the flags shared by every command.
+type options struct {
+	json    bool
+	state   string
+	audit   string
+	timeout time.Duration
+}
+
+func newFlagSet(name, usage string, stderr io.Writer) (*flag.FlagSet, *options) {
+	opts := &options{}
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: banktransfer %s %s\n\nFlags:\n", name, usage)
+		fs.PrintDefaults()
+	}
+	fs.BoolVar(&opts.json, "json", false, "print JSON instead of text")
+	fs.StringVar(&opts.state, "state", "", "keep balances, history and idempotency keys in `dir` between runs")
+	fs.StringVar(&opts.audit, "audit", "", "append events to the hash-chained audit log `file`")
+	fs.DurationVar(&opts.timeout, "timeout", 30*time.Second, "give up after this long")
+	return fs, opts
+}
+
+// parseFlags parses args, which must not contain positional arguments.
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return &usageError{err: err}
+	}
+	if fs.NArg() > 0 {
+		return usagef("unexpected argument %q", fs.Arg(0))
+	}
+	return nil
+}
+
+// required reports the first of the named flags left empty.
+func required(fs *flag.FlagSet, names ...string) error {
+	for _, name := range names {
+		if fs.Lookup(name).Value.String() == "" {
+			return usagef("flag -%s is required", name)
+		}
+	}
+	return nil
+}
+
+// app is a facade set up from the shared flags.
+type app struct {
+	facade  *facade.BankTransferFacade
+	backend *facade.Backend
+	audit   *facade.AuditLog
+	state   string
+}
+
+// newBackend creates the backend of the demo banks; tests replace it with
+// one that hashes PINs more cheaply.
+var newBackend = func() (*facade.Backend, error) {
+	return facade.NewDemoBackend()
+}
+
+// open creates the facade over the demo banks, restoring the state
+// directory if one is given.
+func open(opts *options) (*app, error) {
+	backend, err := newBackend()
+	if err != nil {
+		return nil, err
+	}
+	a := &app{backend: backend, state: opts.state}
+	facadeOpts := []facade.Option{facade.WithBackend(backend)}
+
+	if opts.state != "" {
+		if err := os.MkdirAll(opts.state, 0o700); err != nil {
+			return nil, fmt.Errorf("create state directory: %w", err)
+		}
+		ledger, err := facade.LoadLedger(filepath.Join(opts.state, "ledger.json"))
+		switch {
+		case err == nil:
+			backend.Ledger = ledger
+		case !errors.Is(err, os.ErrNotExist):
+			return nil, err
+		}
+		history, err := facade.NewFileHistoryRepository(filepath.Join(opts.state, "history.json"))
+		if err != nil {
+			return nil, err
+		}
+		idempotency, err := facade.NewFileIdempotencyStore(filepath.Join(opts.state, "idempotency.json"), 0)
+		if err != nil {
+			return nil, err
+		}
+		facadeOpts = append(facadeOpts, facade.WithHistoryRepository(history), facade.WithIdempotencyStore(idempotency))
+	}
+
+	if opts.audit != "" {
+		audit, err := facade.OpenAuditLog(opts.audit)
+		if err != nil {
+			return nil, err
+		}
+		a.audit = audit
+		facadeOpts = append(facadeOpts, facade.WithEventSink(audit))
+	}
+
+	a.facade, err = facade.NewBankTransferFacade(facadeOpts...)
+	if err != nil {
+		a.close()
+		return nil, err
+	}
+	return a, nil
+}
+
+// bank returns the name of the bank with the given name or clearing code.
+func (a *app) bank(nameOrCode string) string {
+	if info, err := a.facade.LookupBank(nameOrCode); err == nil {
+		return info.Name
+	}
+	return nameOrCode
+}
+
+// save writes the balances to the state directory, if there is one.
+func (a *app) save() error {
+	if a.state == "" {
+		return nil
+	}
+	return a.backend.Ledger.Save(filepath.Join(a.state, "ledger.json"))
+}
+
+func (a *app) close() error {
+	if a.audit == nil {
+		return nil
+	}
+	return errors.Join(a.audit.Err(), a.audit.Close())
+}
+
+// withApp opens the app for opts, runs fn and closes the app again.
+func withApp(ctx context.Context, opts *options, fn func(ctx context.Context, a *app) (output, error)) (output, *options, error) {
+	a, err := open(opts)
+	if err != nil {
+		return nil, opts, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, opts.timeout)
+	defer cancel()
+
+	out, err := fn(ctx, a)
+	return out, opts, errors.Join(err, a.close())
+}
+
+// receiptOutput prints a transfer receipt.
+type receiptOutput facade.Receipt
+
+func (r receiptOutput) writeText(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "%s %s %s/%s -> %s/%s %s + fee %s credited %s\n",
+		r.TransactionID, r.Status, r.FromBank, r.FromAccount, r.ToBank, r.ToAccount, r.Amount, r.Fee, r.Credited)
+	return err
+}
+
+func runTransfer(ctx context.Context, args []string, stdin io.Reader, stderr io.Writer) (output, *options, error) {
+	fs, opts := newFlagSet("transfer", "-from-bank BANK -from-account NO -pin PIN -to-bank BANK -to-account NO -amount AMOUNT [flags]", stderr)
+	fromBank := fs.String("from-bank", "", "source `bank` name or code")
+	fromAccount := fs.String("from-account", "", "source account `number`")
+	pin := fs.String("pin", "", "`PIN` of the source account")
+	toBank := fs.String("to-bank", "", "destination `bank` name or code")
+	toAccount := fs.String("to-account", "", "destination account `number`")
+	amountText := fs.String("amount", "", "`amount` to transfer, e.g. 100.00")
+	currency := fs.String("currency", "THB", "`currency` of the amount")
+	key := fs.String("key", "", "idempotency `key`; repeating a transfer with the same key does not move money again")
+	if err := parseFlags(fs, args); err != nil {
+		return nil, opts, err
+	}
+	if err := required(fs, "from-bank", "from-account", "pin", "to-bank", "to-account", "amount"); err != nil {
+		return nil, opts, err
+	}
+	amount, err := facade.ParseMoney(*amountText, *currency)
+	if err != nil {
+		return nil, opts, &usageError{err: err}
+	}
+
+	return withApp(ctx, opts, func(ctx context.Context, a *app) (output, error) {
+		var transferOpts []facade.TransferOption
+		if *key != "" {
+			transferOpts = append(transferOpts, facade.WithIdempotencyKey(*key))
+		}
+
+		receipt, err := a.facade.Transfer(ctx, *fromAccount, *pin, a.bank(*fromBank), *toAccount, a.bank(*toBank), amount, transferOpts...)
+		err = errors.Join(err, a.save())
+		if receipt.TransactionID == "" {
+			return nil, err
+		}
+		return receiptOutput(receipt), err
+	})
+}
+
+// batchOutput prints a batch report.
+type batchOutput facade.BatchReport
+
+func (r batchOutput) writeText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tSTATUS\tTRANSACTION\tDETAIL")
+	for _, result := range r.Results {
+		transaction := "-"
+		if result.Receipt != nil {
+			transaction = result.Receipt.TransactionID
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", result.ID, result.Status, transaction, result.Reason)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	transferred := make([]string, len(r.Summary.Transferred))
+	for i, amount := range r.Summary.Transferred {
+		transferred[i] = amount.String()
+	}
+	_, err := fmt.Fprintf(w, "%d succeeded, %d failed, %d incomplete, %d skipped; transferred %s in %s\n",
+		r.Summary.Succeeded, r.Summary.Failed, r.Summary.Incomplete, r.Summary.Skipped, strings.Join(transferred, ", "), r.Summary.Duration.Round(time.Millisecond))
+	return err
+}
+
+func runBatch(ctx context.Context, args []string, stdin io.Reader, stderr io.Writer) (output, *options, error) {
+	fs, opts := newFlagSet("batch", "-file FILE [flags]", stderr)
+	file := fs.String("file", "", "CSV `file` of transfers, or - for stdin")
+	currency := fs.String("currency", "THB", "`currency` of rows without a currency column")
+	concurrency := fs.Int("concurrency", 4, "number of transfers in flight")
+	if err := parseFlags(fs, args); err != nil {
+		return nil, opts, err
+	}
+	if err := required(fs, "file"); err != nil {
+		return nil, opts, err
+	}
+
+	in := stdin
+	if *file != "-" {
+		f, err := os.Open(*file)
+		if err != nil {
+			return nil, opts, &usageError{err: err}
+		}
+		defer f.Close()
+		in = f
+	}
+	items, err := readBatch(in, *currency)
+	if err != nil {
+		return nil, opts, err
+	}
+
+	return withApp(ctx, opts, func(ctx context.Context, a *app) (output, error) {
+		for i := range items {
+			items[i].FromBank = a.bank(items[i].FromBank)
+			items[i].ToBank = a.bank(items[i].ToBank)
+		}
+		report := a.facade.ExecuteBatch(ctx, facade.TransferBatch{Items: items, Concurrency: *concurrency})
+		summary := report.Summary
+		var err error
+		switch {
+		case summary.Succeeded == summary.Total:
+		case summary.Succeeded == 0:
+			err = fmt.Errorf("no transfer of the batch succeeded")
+		default:
+			err = &partialError{failed: summary.Failed, incomplete: summary.Incomplete, skipped: summary.Skipped}
+		}
+		return batchOutput(report), errors.Join(err, a.save())
+	})
+}
+
+// historyOutput prints receipts as a table, or as CSV if csv is set.
+type historyOutput struct {
+	receipts []facade.Receipt
+	csv      bool
+}
+
+func (h historyOutput) writeText(w io.Writer) error {
+	if h.csv {
+		return facade.WriteStatementCSV(w, h.receipts)
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "CREATED\tTRANSACTION\tFROM\tTO\tAMOUNT\tFEE\tSTATUS")
+	for _, r := range h.receipts {
+		fmt.Fprintf(tw, "%s\t%s\t%s/%s\t%s/%s\t%s\t%s\t%s\n", r.CreatedAt.Format(time.DateTime), r.TransactionID,
+			r.FromBank, r.FromAccount, r.ToBank, r.ToAccount, r.Amount, r.Fee, r.Status)
+	}
+	return tw.Flush()
+}
+
+func (h historyOutput) MarshalJSON() ([]byte, error) {
+	if h.receipts == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(h.receipts)
+}
+
+func runHistory(ctx context.Context, args []string, stdin io.Reader, stderr io.Writer) (output, *options, error) {
+	fs, opts := newFlagSet("history", "[flags]", stderr)
+	bank := fs.String("bank", "", "only transfers from or to `bank`")
+	account := fs.String("account", "", "only transfers from or to account `number`")
+	status := fs.String("status", "", "only transfers that ended in `state`, e.g. committed")
+	since := fs.String("since", "", "only transfers created at or after `time` (YYYY-MM-DD or RFC 3339)")
+	until := fs.String("until", "", "only transfers created before `time` (YYYY-MM-DD or RFC 3339)")
+	asCSV := fs.Bool("csv", false, "print a CSV statement instead of a table")
+	if err := parseFlags(fs, args); err != nil {
+		return nil, opts, err
+	}
+	filter := facade.HistoryFilter{Bank: *bank, Account: *account, Status: facade.TransferState(*status)}
+	var err error
+	if filter.From, err = parseTime("since", *since); err != nil {
+		return nil, opts, err
+	}
+	if filter.To, err = parseTime("until", *until); err != nil {
+		return nil, opts, err
+	}
+
+	return withApp(ctx, opts, func(ctx context.Context, a *app) (output, error) {
+		receipts, err := a.facade.History(filter)
+		if err != nil {
+			return nil, err
+		}
+		return historyOutput{receipts: receipts, csv: *asCSV}, nil
+	})
+}
+
+// parseTime parses the value of a time flag; empty means no bound.
+func parseTime(name, value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.ParseInLocation(time.DateOnly, value, time.Local); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, usagef("flag -%s: %q is neither YYYY-MM-DD nor RFC 3339", name, value)
+	}
+	return t, nil
+}
+
+// balanceOutput prints the balance of an account.
+type balanceOutput struct {
+	Bank      string       `json:"bank"`
+	AccountNo string       `json:"account_no"`
+	Balance   facade.Money `json:"balance"`
+}
+
+func (b balanceOutput) writeText(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "%s/%s %s\n", b.Bank, b.AccountNo, b.Balance)
+	return err
+}
+
+func runBalance(ctx context.Context, args []string, stdin io.Reader, stderr io.Writer) (output, *options, error) {
+	fs, opts := newFlagSet("balance", "-bank BANK -account NO -pin PIN [flags]", stderr)
+	bank := fs.String("bank", "", "`bank` name or code of the account")
+	account := fs.String("account", "", "account `number`")
+	pin := fs.String("pin", "", "`PIN` of the account")
+	if err := parseFlags(fs, args); err != nil {
+		return nil, opts, err
+	}
+	if err := required(fs, "bank", "account", "pin"); err != nil {
+		return nil, opts, err
+	}
+
+	return withApp(ctx, opts, func(ctx context.Context, a *app) (output, error) {
+		balance, err := a.facade.Balance(ctx, *account, *pin, a.bank(*bank))
+		if err != nil {
+			return nil, err
+		}
+		return balanceOutput{Bank: a.bank(*bank), AccountNo: *account, Balance: balance}, nil
+	})
+}
+
+// bankOutput describes a bank of the directory.
+type bankOutput struct {
+	Code          string `json:"code"`
+	Name          string `json:"name"`
+	FullName      string `json:"full_name"`
+	AccountLength int    `json:"account_length,omitempty"`
+	Registered    bool   `json:"registered"`
+}
+
+type banksOutput []bankOutput
+
+func (b banksOutput) writeText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "CODE\tNAME\tFULL NAME\tDIGITS\tREGISTERED")
+	for _, bank := range b {
+		digits := "any"
+		if bank.AccountLength > 0 {
+			digits = fmt.Sprint(bank.AccountLength)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%t\n", bank.Code, bank.Name, bank.FullName, digits, bank.Registered)
+	}
+	return tw.Flush()
+}
+
+func runListBanks(ctx context.Context, args []string, stdin io.Reader, stderr io.Writer) (output, *options, error) {
+	fs, opts := newFlagSet("list-banks", "[flags]", stderr)
+	if err := parseFlags(fs, args); err != nil {
+		return nil, opts, err
+	}
+
+	return withApp(ctx, opts, func(ctx context.Context, a *app) (output, error) {
+		registered := make(map[string]bool)
+		for _, name := range a.facade.Banks() {
+			registered[name] = true
+		}
+		banks := banksOutput{}
+		for _, info := range a.facade.Directory().List() {
+			banks = append(banks, bankOutput{
+				Code:          info.Code,
+				Name:          info.Name,
+				FullName:      info.FullName,
+				AccountLength: info.AccountLength,
+				Registered:    registered[info.Name],
+			})
+		}
+		return banks, nil
+	})
+}
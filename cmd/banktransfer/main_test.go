@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go-design-patterns/structural/facade"
+)
+
+func TestMain(m *testing.M) {
+	newBackend = cheapBackend
+	os.Exit(m.Run())
+}
+
+// cheapBackend is the demo backend with cheap PIN hashes.
+func cheapBackend() (*facade.Backend, error) {
+	return facade.NewDemoBackend(facade.WithPinHashIterations(1000))
+}
+
+// runCLI runs the command line args with stdin as input.
+func runCLI(t *testing.T, stdin string, args ...string) (code int, stdout, stderr string) {
+	t.Helper()
+	var out, errOut bytes.Buffer
+	code = run(context.Background(), args, strings.NewReader(stdin), &out, &errOut)
+	return code, out.String(), errOut.String()
+}
+
+const batchHeader = "id,from_bank,from_account,pin,to_bank,to_account,amount\n"
+
+func TestRunExitCodes(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		stdin      string
+		backend    func() (*facade.Backend, error)
+		wantCode   int
+		wantStdout string
+		wantStderr string
+	}{
+		{name: "no command", wantCode: exitUsage, wantStderr: "Usage: banktransfer"},
+		{name: "help", args: []string{"help"}, wantCode: exitOK, wantStdout: "Usage: banktransfer"},
+		{name: "unknown command", args: []string{"refund"}, wantCode: exitUsage, wantStderr: `unknown command "refund"`},
+		{name: "unknown flag", args: []string{"balance", "-iban", "x"}, wantCode: exitUsage, wantStderr: "flag provided but not defined"},
+		{name: "missing flag", args: []string{"transfer", "-from-bank", "KBank"}, wantCode: exitUsage, wantStderr: "flag -from-account is required"},
+		{name: "positional argument", args: []string{"list-banks", "extra"}, wantCode: exitUsage, wantStderr: `unexpected argument "extra"`},
+		{
+			name:       "bad amount",
+			args:       []string{"transfer", "-from-bank", "KBank", "-from-account", "0123456789", "-pin", "1234", "-to-bank", "KBank", "-to-account", "9876543210", "-amount", "ten"},
+			wantCode:   exitUsage,
+			wantStderr: "banktransfer:",
+		},
+		{name: "empty batch", args: []string{"batch", "-file", "-"}, wantCode: exitUsage, wantStderr: "batch file is empty"},
+		{name: "batch without rows", args: []string{"batch", "-file", "-"}, stdin: batchHeader, wantCode: exitUsage, wantStderr: "batch file has no transfers"},
+		{
+			name:       "wrong PIN",
+			args:       []string{"balance", "-bank", "KBank", "-account", "0123456789", "-pin", "0000"},
+			wantCode:   exitFailed,
+			wantStderr: "login failed",
+		},
+		{
+			name: "partial batch",
+			args: []string{"batch", "-file", "-"},
+			stdin: batchHeader +
+				"pay,KBank,0123456789,1234,KBank,9876543210,100.00\n" +
+				"locked,SiamBank,0123456789,0000,KBank,9876543210,100.00\n",
+			wantCode:   exitPartial,
+			wantStdout: "1 succeeded, 0 failed, 0 incomplete, 1 skipped; transferred 100.00 THB",
+			wantStderr: "0 items failed, 0 incomplete, 1 skipped",
+		},
+		{
+			name:       "batch with nothing succeeding",
+			args:       []string{"batch", "-file", "-"},
+			stdin:      batchHeader + "big,KBank,9876543210,4321,KBank,0123456789,50.00\n",
+			wantCode:   exitFailed,
+			wantStdout: "0 succeeded, 1 failed",
+			wantStderr: "no transfer of the batch succeeded",
+		},
+		{
+			name: "unavailable bank",
+			args: []string{"balance", "-bank", "KBank", "-account", "0123456789", "-pin", "1234", "-timeout", "50ms"},
+			backend: func() (*facade.Backend, error) {
+				backend, err := cheapBackend()
+				if err == nil {
+					backend.SetLatency("KBank", time.Hour)
+				}
+				return backend, err
+			},
+			wantCode:   exitUnavailable,
+			wantStderr: "deadline exceeded",
+		},
+		{
+			name:       "JSON error",
+			args:       []string{"balance", "-json", "-bank", "KBank", "-account", "0123456789", "-pin", "0000"},
+			wantCode:   exitFailed,
+			wantStderr: `"exit_code":1`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.backend != nil {
+				newBackend = tt.backend
+				defer func() { newBackend = cheapBackend }()
+			}
+			code, stdout, stderr := runCLI(t, tt.stdin, tt.args...)
+			if code != tt.wantCode {
+				t.Errorf("exit code %d, want %d\nstdout: %s\nstderr: %s", code, tt.wantCode, stdout, stderr)
+			}
+			if !strings.Contains(stdout, tt.wantStdout) {
+				t.Errorf("stdout %q does not contain %q", stdout, tt.wantStdout)
+			}
+			if !strings.Contains(stderr, tt.wantStderr) {
+				t.Errorf("stderr %q does not contain %q", stderr, tt.wantStderr)
+			}
+		})
+	}
+}
+
+func TestRunKeepsState(t *testing.T) {
+	state := t.TempDir()
+	transfer := []string{"transfer", "-state", state, "-key", "rent-march",
+		"-from-bank", "KBank", "-from-account", "0123456789", "-pin", "1234",
+		"-to-bank", "KBank", "-to-account", "9876543210", "-amount", "100.00"}
+
+	// Repeating the transfer with its key does not move the money again
+	for i := 0; i < 2; i++ {
+		if code, _, stderr := runCLI(t, "", transfer...); code != exitOK {
+			t.Fatalf("transfer %d: exit code %d: %s", i, code, stderr)
+		}
+	}
+	code, stdout, stderr := runCLI(t, "", "balance", "-state", state, "-bank", "KBank", "-account", "9876543210", "-pin", "4321")
+	if code != exitOK || stdout != "KBank/9876543210 100.00 THB\n" {
+		t.Errorf("balance after reopening = %d %q %s, want 100.00 THB", code, stdout, stderr)
+	}
+	code, stdout, _ = runCLI(t, "", "history", "-state", state, "-csv")
+	if code != exitOK || strings.Count(stdout, "committed") != 1 {
+		t.Errorf("history after reopening = %d %q, want one committed transfer", code, stdout)
+	}
+
+	// The saved ledger loads back with the same balances
+	ledger, err := facade.LoadLedger(filepath.Join(state, "ledger.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	balance, err := ledger.Balance(facade.AccountID{Bank: "KBank", AccountNo: "0123456789"})
+	if err != nil || balance.Decimal() != "9900.00" {
+		t.Errorf("saved source balance %s, %v; want 9900.00", balance.Decimal(), err)
+	}
+	if err := ledger.Verify(); err != nil {
+		t.Error(err)
+	}
+}
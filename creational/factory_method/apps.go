@@ -0,0 +1,199 @@
+package factorymethod
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Store is an app store devices install apps from
+type Store string
+
+const (
+	GooglePlay Store = "Google Play Store"
+	AppStore   Store = "App Store"
+)
+
+var (
+	// ErrAppNotFound is returned for an app the catalog does not list
+	ErrAppNotFound = errors.New("app not found")
+	// ErrAppExists is returned when a catalog lists an app twice
+	ErrAppExists = errors.New("app already in catalog")
+	// ErrAppUnavailable is returned for an app the device's store does not carry
+	ErrAppUnavailable = errors.New("app not available in store")
+	// ErrPlatformTooOld is returned for an app that needs a newer platform version
+	ErrPlatformTooOld = errors.New("platform version too old for app")
+	// ErrInsufficientStorage is returned when an app does not fit on the device
+	ErrInsufficientStorage = errors.New("insufficient storage")
+	// ErrAppInstalled is returned when installing an app twice
+	ErrAppInstalled = errors.New("app already installed")
+	// ErrAppNotInstalled is returned when uninstalling an app that is not installed
+	ErrAppNotInstalled = errors.New("app not installed")
+)
+
+// AppError describes a failed operation on a single app
+type AppError struct {
+	Op  string // "add", "install" or "uninstall"
+	App string
+	Err error
+}
+
+func (e *AppError) Error() string {
+	return fmt.Sprintf("%s app %q: %v", e.Op, e.App, e.Err)
+}
+
+func (e *AppError) Unwrap() error {
+	return e.Err
+}
+
+// App is an app of a Catalog
+type App struct {
+	Name string
+	Size ByteSize
+	// MinVersion lists the stores that carry the app and the oldest
+	// platform version each of them offers it for
+	MinVersion map[Store]Version
+}
+
+// Catalog lists the apps devices can install. It is safe for concurrent use
+type Catalog struct {
+	mu   sync.RWMutex
+	apps map[string]App
+}
+
+// NewCatalog creates a catalog of apps
+func NewCatalog(apps ...App) (*Catalog, error) {
+	c := &Catalog{apps: make(map[string]App)}
+	for _, app := range apps {
+		if err := c.Add(app); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// DefaultCatalog returns a catalog of popular apps, which devices use
+// unless given another
+func DefaultCatalog() *Catalog {
+	c, _ := NewCatalog(
+		App{Name: "WhatsApp", Size: 150 * MB, MinVersion: map[Store]Version{GooglePlay: {Major: 5}, AppStore: {Major: 12}}},
+		App{Name: "Instagram", Size: 250 * MB, MinVersion: map[Store]Version{GooglePlay: {Major: 8}, AppStore: {Major: 15}}},
+		App{Name: "Google Maps", Size: 200 * MB, MinVersion: map[Store]Version{GooglePlay: {Major: 8}, AppStore: {Major: 15}}},
+		App{Name: "Files by Google", Size: 50 * MB, MinVersion: map[Store]Version{GooglePlay: {Major: 8}}},
+		App{Name: "Final Cut Camera", Size: 500 * MB, MinVersion: map[Store]Version{AppStore: {Major: 17}}},
+		App{Name: "Genshin Impact", Size: 30 * GB, MinVersion: map[Store]Version{GooglePlay: {Major: 8}, AppStore: {Major: 12}}},
+	)
+	return c
+}
+
+// Add lists an app in the catalog
+func (c *Catalog) Add(app App) error {
+	if app.Name == "" || app.Size <= 0 {
+		return &AppError{Op: "add", App: app.Name, Err: errors.New("app needs a name and a size")}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, exists := c.apps[app.Name]; exists {
+		return &AppError{Op: "add", App: app.Name, Err: ErrAppExists}
+	}
+	c.apps[app.Name] = app
+	return nil
+}
+
+// Lookup returns the app of the given name
+func (c *Catalog) Lookup(name string) (App, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	app, ok := c.apps[name]
+	return app, ok
+}
+
+// Apps returns the apps of the catalog sorted by name
+func (c *Catalog) Apps() []App {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	apps := make([]App, 0, len(c.apps))
+	for _, app := range c.apps {
+		apps = append(apps, app)
+	}
+	sortApps(apps)
+	return apps
+}
+
+func sortApps(apps []App) {
+	sort.Slice(apps, func(i, j int) bool { return apps[i].Name < apps[j].Name })
+}
+
+// SetCatalog replaces the catalog the device installs apps from. Installed
+// apps stay installed
+func (b *BaseDevice) SetCatalog(catalog *Catalog) {
+	b.catalog = catalog
+}
+
+// install checks that the app can be installed from the device's store and
+// fits in its free storage, and records it as installed
+func (b *BaseDevice) install(name string) (App, error) {
+	fail := func(err error) (App, error) {
+		return App{}, &AppError{Op: "install", App: name, Err: err}
+	}
+
+	if b.catalog == nil {
+		return fail(ErrAppNotFound)
+	}
+	app, ok := b.catalog.Lookup(name)
+	if !ok {
+		return fail(ErrAppNotFound)
+	}
+	minVersion, ok := app.MinVersion[b.store]
+	if !ok {
+		return fail(fmt.Errorf("%w: %s", ErrAppUnavailable, b.store))
+	}
+	if b.specs.Platform.Version.Compare(minVersion) < 0 {
+		return fail(fmt.Errorf("%w: needs %s, has %s", ErrPlatformTooOld, minVersion, b.specs.Platform.Version))
+	}
+	if _, installed := b.installed[name]; installed {
+		return fail(ErrAppInstalled)
+	}
+	if free := b.FreeStorage(); app.Size > free {
+		return fail(fmt.Errorf("%w: needs %s, %s free", ErrInsufficientStorage, app.Size, free))
+	}
+
+	if b.installed == nil {
+		b.installed = make(map[string]App)
+	}
+	b.installed[name] = app
+	return app, nil
+}
+
+// Uninstall removes an installed app and frees its storage
+func (b *BaseDevice) Uninstall(appName string) error {
+	if _, installed := b.installed[appName]; !installed {
+		return &AppError{Op: "uninstall", App: appName, Err: ErrAppNotInstalled}
+	}
+	delete(b.installed, appName)
+	return nil
+}
+
+// ListApps returns the installed apps sorted by name
+func (b *BaseDevice) ListApps() []App {
+	apps := make([]App, 0, len(b.installed))
+	for _, app := range b.installed {
+		apps = append(apps, app)
+	}
+	sortApps(apps)
+	return apps
+}
+
+// FreeStorage returns the storage not taken by installed apps
+func (b *BaseDevice) FreeStorage() ByteSize {
+	free := b.specs.Storage
+	for _, app := range b.installed {
+		free -= app.Size
+	}
+	return free
+}
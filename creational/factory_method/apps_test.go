@@ -0,0 +1,214 @@
+package factorymethod_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	fm "go-design-patterns/creational/factory_method"
+)
+
+func testCatalog(t *testing.T) *fm.Catalog {
+	t.Helper()
+	both := map[fm.Store]fm.Version{fm.GooglePlay: {Major: 8}, fm.AppStore: {Major: 15}}
+	catalog, err := fm.NewCatalog(
+		fm.App{Name: "Notes", Size: 1 * fm.GB, MinVersion: both},
+		fm.App{Name: "Game", Size: 2 * fm.GB, MinVersion: both},
+		fm.App{Name: "Movies", Size: 3 * fm.GB, MinVersion: both},
+		fm.App{Name: "Camera", Size: 100 * fm.MB, MinVersion: map[fm.Store]fm.Version{fm.GooglePlay: {Major: 14}}},
+		fm.App{Name: "Shortcuts", Size: 100 * fm.MB, MinVersion: map[fm.Store]fm.Version{fm.AppStore: {Major: 15}}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return catalog
+}
+
+// testAndroid returns an Android 13 device with 4GB of storage
+func testAndroid(t *testing.T) *fm.AndroidDevice {
+	t.Helper()
+	device := fm.NewAndroidDevice(fm.DeviceSpecs{
+		RAM:      3 * fm.GB,
+		Storage:  4 * fm.GB,
+		CPU:      fm.CPU{Family: fm.GenericCPU, Cores: 8},
+		Platform: fm.Platform{Name: "Android", Version: fm.Version{Major: 13}},
+	})
+	device.SetCatalog(testCatalog(t))
+	return device
+}
+
+func appNames(device fm.MobileDevice) []string {
+	var names []string
+	for _, app := range device.ListApps() {
+		names = append(names, app.Name)
+	}
+	return names
+}
+
+func TestInstallApp(t *testing.T) {
+	tests := []struct {
+		app     string
+		wantErr error
+	}{
+		{app: "Notes"},
+		{app: "Camera", wantErr: fm.ErrPlatformTooOld},
+		{app: "Shortcuts", wantErr: fm.ErrAppUnavailable},
+		{app: "Snake", wantErr: fm.ErrAppNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.app, func(t *testing.T) {
+			device := testAndroid(t)
+			err := device.InstallApp(tt.app)
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("InstallApp(%q) = %v", tt.app, err)
+				}
+				if got := appNames(device); !reflect.DeepEqual(got, []string{tt.app}) {
+					t.Errorf("installed apps %v, want [%s]", got, tt.app)
+				}
+				return
+			}
+			var appErr *fm.AppError
+			if !errors.Is(err, tt.wantErr) || !errors.As(err, &appErr) || appErr.Op != "install" || appErr.App != tt.app {
+				t.Fatalf("InstallApp(%q) = %v, want an install AppError matching %v", tt.app, err, tt.wantErr)
+			}
+			if apps := device.ListApps(); len(apps) != 0 || device.FreeStorage() != 4*fm.GB {
+				t.Errorf("failed install left apps %v and %s free", appNames(device), device.FreeStorage())
+			}
+		})
+	}
+}
+
+func TestInstallAppTwice(t *testing.T) {
+	device := testAndroid(t)
+	if err := device.InstallApp("Notes"); err != nil {
+		t.Fatal(err)
+	}
+	if err := device.InstallApp("Notes"); !errors.Is(err, fm.ErrAppInstalled) {
+		t.Errorf("second install = %v, want ErrAppInstalled", err)
+	}
+	if free := device.FreeStorage(); free != 3*fm.GB {
+		t.Errorf("%s free, want 3GB", free)
+	}
+}
+
+func TestStorageAccounting(t *testing.T) {
+	device := testAndroid(t)
+	if free := device.FreeStorage(); free != 4*fm.GB {
+		t.Fatalf("new device has %s free, want 4GB", free)
+	}
+
+	for _, app := range []string{"Game", "Notes"} {
+		if err := device.InstallApp(app); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if free := device.FreeStorage(); free != 1*fm.GB {
+		t.Errorf("%s free after installing 3GB, want 1GB", free)
+	}
+	if got, want := appNames(device), []string{"Game", "Notes"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("installed apps %v, want %v", got, want)
+	}
+
+	if err := device.InstallApp("Movies"); !errors.Is(err, fm.ErrInsufficientStorage) {
+		t.Fatalf("install beyond free storage = %v, want ErrInsufficientStorage", err)
+	}
+
+	if err := device.Uninstall("Game"); err != nil {
+		t.Fatal(err)
+	}
+	if free := device.FreeStorage(); free != 3*fm.GB {
+		t.Errorf("%s free after uninstall, want 3GB", free)
+	}
+	// The app that fills the storage exactly fits
+	if err := device.InstallApp("Movies"); err != nil {
+		t.Fatalf("install into exactly the free storage = %v", err)
+	}
+	if free := device.FreeStorage(); free != 0 {
+		t.Errorf("%s free, want 0B", free)
+	}
+}
+
+func TestUninstall(t *testing.T) {
+	device := testAndroid(t)
+	err := device.Uninstall("Notes")
+	var appErr *fm.AppError
+	if !errors.Is(err, fm.ErrAppNotInstalled) || !errors.As(err, &appErr) || appErr.Op != "uninstall" {
+		t.Fatalf("Uninstall of a missing app = %v, want an uninstall AppError matching ErrAppNotInstalled", err)
+	}
+
+	if err := device.InstallApp("Notes"); err != nil {
+		t.Fatal(err)
+	}
+	if err := device.Uninstall("Notes"); err != nil {
+		t.Fatalf("Uninstall = %v", err)
+	}
+	if apps := device.ListApps(); len(apps) != 0 {
+		t.Errorf("apps %v remain after uninstall", appNames(device))
+	}
+	if err := device.Uninstall("Notes"); !errors.Is(err, fm.ErrAppNotInstalled) {
+		t.Errorf("second Uninstall = %v, want ErrAppNotInstalled", err)
+	}
+	// An uninstalled app can be installed again
+	if err := device.InstallApp("Notes"); err != nil {
+		t.Errorf("reinstall = %v", err)
+	}
+}
+
+func TestInstallAppPlatformGate(t *testing.T) {
+	device := testAndroid(t)
+	if err := device.InstallApp("Camera"); !errors.Is(err, fm.ErrPlatformTooOld) {
+		t.Fatalf("install on Android 13 = %v, want ErrPlatformTooOld", err)
+	}
+	if err := device.Update("14"); err != nil {
+		t.Fatal(err)
+	}
+	if err := device.InstallApp("Camera"); err != nil {
+		t.Errorf("install after updating to Android 14 = %v", err)
+	}
+}
+
+func TestInstallAppStoreGate(t *testing.T) {
+	iphone := fm.NewIosDevice(fm.DeviceSpecs{
+		RAM:      4 * fm.GB,
+		Storage:  64 * fm.GB,
+		CPU:      fm.CPU{Family: fm.AppleA, Generation: 15},
+		Platform: fm.Platform{Name: "iOS", Version: fm.Version{Major: 17}},
+	})
+	iphone.SetCatalog(testCatalog(t))
+	if err := iphone.InstallApp("Shortcuts"); err == nil || errors.Is(err, fm.ErrAppUnavailable) {
+		t.Fatalf("install without an Apple ID = %v, want it refused before the store is checked", err)
+	}
+	iphone.SetAppleID("tester@example.com")
+
+	if err := iphone.InstallApp("Shortcuts"); err != nil {
+		t.Errorf("App Store app on iOS = %v", err)
+	}
+	if err := iphone.InstallApp("Camera"); !errors.Is(err, fm.ErrAppUnavailable) {
+		t.Errorf("Google Play app on iOS = %v, want ErrAppUnavailable", err)
+	}
+
+	android := testAndroid(t)
+	if err := android.InstallApp("Shortcuts"); !errors.Is(err, fm.ErrAppUnavailable) {
+		t.Errorf("App Store app on Android = %v, want ErrAppUnavailable", err)
+	}
+}
+
+func TestCatalog(t *testing.T) {
+	catalog := testCatalog(t)
+	if err := catalog.Add(fm.App{Name: "Notes", Size: fm.MB}); !errors.Is(err, fm.ErrAppExists) {
+		t.Errorf("adding an app twice = %v, want ErrAppExists", err)
+	}
+	if err := catalog.Add(fm.App{Name: "Empty"}); err == nil {
+		t.Error("adding an app without a size succeeded")
+	}
+	if _, ok := catalog.Lookup("Snake"); ok {
+		t.Error("Lookup found an app the catalog does not list")
+	}
+	if app, ok := catalog.Lookup("Game"); !ok || app.Size != 2*fm.GB {
+		t.Errorf("Lookup(Game) = %+v, %v", app, ok)
+	}
+	if got := len(catalog.Apps()); got != 5 {
+		t.Errorf("catalog lists %d apps, want 5", got)
+	}
+}
@@ -3,27 +3,49 @@ package factorymethod
 import (
 	"errors"
 	"fmt"
+	"sync"
 )
 
-// DeviceSpecs represents the specifications of a mobile device
+// DeviceSpecs represents the specifications of a mobile device. Fields left
+// at their zero value are unset, see ParseDeviceSpecs and Validate
 type DeviceSpecs struct {
-	RAM      string
-	Storage  string
-	CPU      string
-	Platform string
+	RAM      ByteSize
+	Storage  ByteSize
+	CPU      CPU
+	Platform Platform
 }
 
-// MobileDevice defines the interface for mobile devices
+// MobileDevice defines the interface for mobile devices. Devices are not
+// safe for concurrent use
 type MobileDevice interface {
 	GetPlatform() string
 	GetSpecs() DeviceSpecs
 	Update(newVersion string) error
 	InstallApp(appName string) error
+	Uninstall(appName string) error
+	ListApps() []App
+	FreeStorage() ByteSize
 }
 
-// BaseDevice contains common functionality for all devices
+// BaseDevice contains common functionality for all devices. It does not
+// synchronize access to its specs or installed apps, so callers sharing a
+// device between goroutines must guard it themselves
 type BaseDevice struct {
-	specs DeviceSpecs
+	specs        DeviceSpecs
+	upgradePaths UpgradePaths
+	catalog      *Catalog
+	store        Store
+	installed    map[string]App
+}
+
+// newBaseDevice creates a device with the default upgrade paths and catalog
+func newBaseDevice(specs DeviceSpecs, store Store) BaseDevice {
+	return BaseDevice{
+		specs:        specs,
+		upgradePaths: DefaultUpgradePaths(),
+		catalog:      DefaultCatalog(),
+		store:        store,
+	}
 }
 
 // AndroidDevice is a concrete implementation of MobileDevice for Android
@@ -34,17 +56,17 @@ type AndroidDevice struct {
 
 // NewAndroidDevice creates a new AndroidDevice with specified specs
 func NewAndroidDevice(specs DeviceSpecs) *AndroidDevice {
-	if specs.Platform == "" {
-		specs.Platform = "Android 13.0"
+	if specs.Platform.Name == "" {
+		specs.Platform = Platform{Name: "Android", Version: Version{Major: 13}}
 	}
 	return &AndroidDevice{
-		BaseDevice:     BaseDevice{specs: specs},
+		BaseDevice:     newBaseDevice(specs, GooglePlay),
 		googleServices: true,
 	}
 }
 
 func (d *AndroidDevice) GetPlatform() string {
-	return d.specs.Platform
+	return d.specs.Platform.String()
 }
 
 func (d *AndroidDevice) GetSpecs() DeviceSpecs {
@@ -55,7 +77,11 @@ func (d *AndroidDevice) Update(newVersion string) error {
 	if !d.googleServices {
 		return errors.New("cannot update: Google Services not available")
 	}
-	d.specs.Platform = fmt.Sprintf("Android %s", newVersion)
+	version, err := d.planUpdate(newVersion)
+	if err != nil {
+		return err
+	}
+	d.specs.Platform.Version = version
 	return nil
 }
 
@@ -63,8 +89,8 @@ func (d *AndroidDevice) InstallApp(appName string) error {
 	if !d.googleServices {
 		return errors.New("cannot install app: Google Play Store not available")
 	}
-	fmt.Printf("Installing %s from Google Play Store\n", appName)
-	return nil
+	_, err := d.install(appName)
+	return err
 }
 
 // IosDevice is a concrete implementation of MobileDevice for iOS
@@ -75,17 +101,17 @@ type IosDevice struct {
 
 // NewIosDevice creates a new IosDevice with specified specs
 func NewIosDevice(specs DeviceSpecs) *IosDevice {
-	if specs.Platform == "" {
-		specs.Platform = "iOS 16.0"
+	if specs.Platform.Name == "" {
+		specs.Platform = Platform{Name: "iOS", Version: Version{Major: 16}}
 	}
 	return &IosDevice{
-		BaseDevice: BaseDevice{specs: specs},
+		BaseDevice: newBaseDevice(specs, AppStore),
 		appleID:    "",
 	}
 }
 
 func (d *IosDevice) GetPlatform() string {
-	return d.specs.Platform
+	return d.specs.Platform.String()
 }
 
 func (d *IosDevice) GetSpecs() DeviceSpecs {
@@ -96,7 +122,11 @@ func (d *IosDevice) Update(newVersion string) error {
 	if d.appleID == "" {
 		return errors.New("cannot update: Apple ID not configured")
 	}
-	d.specs.Platform = fmt.Sprintf("iOS %s", newVersion)
+	version, err := d.planUpdate(newVersion)
+	if err != nil {
+		return err
+	}
+	d.specs.Platform.Version = version
 	return nil
 }
 
@@ -104,8 +134,14 @@ func (d *IosDevice) InstallApp(appName string) error {
 	if d.appleID == "" {
 		return errors.New("cannot install app: Apple ID not configured")
 	}
-	fmt.Printf("Installing %s from App Store\n", appName)
-	return nil
+	_, err := d.install(appName)
+	return err
+}
+
+// SetAppleID signs the device in to an Apple ID, which updates and app
+// installs need
+func (d *IosDevice) SetAppleID(appleID string) {
+	d.appleID = appleID
 }
 
 // DeviceType represents the type of mobile device
@@ -116,40 +152,92 @@ const (
 	IOS     DeviceType = "iOS"
 )
 
-// DeviceFactory is responsible for creating mobile devices
+// DeviceFactory is responsible for creating mobile devices. The device
+// types it can create are registered with it, see Register
 type DeviceFactory struct {
 	defaultSpecs DeviceSpecs
+
+	mu    sync.RWMutex
+	types map[DeviceType]DeviceRegistration
 }
 
-// NewDeviceFactory creates a new DeviceFactory with default specs
+// NewDeviceFactory creates a new DeviceFactory with default specs and the
+// built-in Android and iOS types registered
 func NewDeviceFactory(defaultSpecs DeviceSpecs) *DeviceFactory {
-	return &DeviceFactory{defaultSpecs: defaultSpecs}
+	f := &DeviceFactory{
+		defaultSpecs: defaultSpecs,
+		types:        make(map[DeviceType]DeviceRegistration),
+	}
+	for deviceType, registration := range builtinTypes() {
+		f.types[deviceType] = registration
+	}
+	return f
 }
 
-// CreateDevice is the factory method for creating mobile devices
-func (f *DeviceFactory) CreateDevice(deviceType DeviceType, specs *DeviceSpecs) (MobileDevice, error) {
-	finalSpecs := f.defaultSpecs
-	if specs != nil {
-		finalSpecs = *specs
+// builtinTypes returns the registrations of the device types every factory
+// starts with
+func builtinTypes() map[DeviceType]DeviceRegistration {
+	return map[DeviceType]DeviceRegistration{
+		Android: {
+			Constructor: func(specs DeviceSpecs) (MobileDevice, error) {
+				return NewAndroidDevice(specs), nil
+			},
+			Defaults: DeviceSpecs{Platform: Platform{Name: "Android", Version: Version{Major: 13}}},
+		},
+		IOS: {
+			Constructor: func(specs DeviceSpecs) (MobileDevice, error) {
+				return NewIosDevice(specs), nil
+			},
+			Defaults: DeviceSpecs{Platform: Platform{Name: "iOS", Version: Version{Major: 16}}},
+		},
+	}
+}
+
+// CreateDevice is the factory method for creating mobile devices. Each
+// field of the specs is taken from the first of these that sets it: specs,
+// the factory's default specs and the defaults of the device type. Fields
+// named by Clear skip the factory's default specs. The merged specs are
+// passed to the constructor registered for the type; specs that fail
+// Validate, or name another platform than the type's defaults, are rejected
+// with a SpecsError
+func (f *DeviceFactory) CreateDevice(deviceType DeviceType, specs *DeviceSpecs, opts ...CreateOption) (MobileDevice, error) {
+	registration, err := f.lookup(deviceType)
+	if err != nil {
+		return nil, err
+	}
+	var options createOptions
+	for _, opt := range opts {
+		opt(&options)
 	}
 
-	switch deviceType {
-	case Android:
-		return NewAndroidDevice(finalSpecs), nil
-	case IOS:
-		return NewIosDevice(finalSpecs), nil
-	default:
-		return nil, fmt.Errorf("unsupported device type: %s", deviceType)
+	var overrides DeviceSpecs
+	if specs != nil {
+		overrides = *specs
 	}
+	finalSpecs := mergeSpecs(
+		overrides,
+		f.defaultSpecs.without(options.cleared),
+		registration.Defaults,
+	)
+	if err := finalSpecs.Validate(); err != nil {
+		return nil, err
+	}
+	if want := registration.Defaults.Platform.Name; want != "" && finalSpecs.Platform.Name != want {
+		return nil, &SpecsError{Fields: []*FieldError{{
+			Field: FieldPlatform,
+			Err:   fmt.Errorf("%s cannot run on a %s device", finalSpecs.Platform, deviceType),
+		}}}
+	}
+	return registration.Constructor(finalSpecs)
 }
 
 // Example demonstrates the usage of the improved Factory Method pattern
 func Example() {
 	// Create a factory with default specs
 	defaultSpecs := DeviceSpecs{
-		RAM:     "8GB",
-		Storage: "128GB",
-		CPU:     "Octa-core",
+		RAM:     8 * GB,
+		Storage: 128 * GB,
+		CPU:     CPU{Family: GenericCPU, Cores: 8},
 	}
 	factory := NewDeviceFactory(defaultSpecs)
 
@@ -161,11 +249,10 @@ func Example() {
 	}
 
 	// Create an iOS device with custom specs
-	customSpecs := DeviceSpecs{
-		RAM:      "6GB",
-		Storage:  "256GB",
-		CPU:      "A15 Bionic",
-		Platform: "iOS 17.0",
+	customSpecs, err := ParseDeviceSpecs("6GB", "256GB", "A15 Bionic", "iOS 17.0")
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
 	}
 	iosDevice, err := factory.CreateDevice(IOS, &customSpecs)
 	if err != nil {
@@ -178,9 +265,157 @@ func Example() {
 	fmt.Printf("iOS Device Specs: %+v\n", iosDevice.GetSpecs())
 
 	// Try updating and installing apps
+	install := func(device MobileDevice, app string) {
+		if err := device.InstallApp(app); err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+		fmt.Printf("Installed %s on %s\n", app, device.GetPlatform())
+	}
 	androidDevice.Update("14.0")
-	androidDevice.InstallApp("WhatsApp")
+	install(androidDevice, "WhatsApp")
 
+	install(iosDevice, "Instagram")
+	iosDevice.(*IosDevice).SetAppleID("user@example.com")
 	iosDevice.Update("17.1")
-	iosDevice.InstallApp("Instagram")
+	install(iosDevice, "Instagram")
+
+	// Updates must move forward along a supported upgrade path
+	fmt.Println("Android updated to", androidDevice.GetPlatform())
+	for _, version := range []string{"banana", "13.0", "16.0", "15.0.1-beta.1"} {
+		if err := androidDevice.Update(version); err != nil {
+			fmt.Println("Error:", err)
+			continue
+		}
+		fmt.Println("Android updated to", androidDevice.GetPlatform())
+	}
+	budgetSpecs := DeviceSpecs{
+		RAM:      3 * GB,
+		CPU:      CPU{Family: GenericCPU, Cores: 4},
+		Platform: Platform{Name: "Android", Version: Version{Major: 13}},
+	}
+	budgetDevice, err := factory.CreateDevice(Android, &budgetSpecs)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	if err := budgetDevice.Update("14.0"); errors.Is(err, ErrHardwareTooOld) {
+		fmt.Println("Error:", err)
+	}
+
+	// Plug in a new device type and change the defaults of a built-in one
+	androidGo := DeviceType("Android Go")
+	err = factory.Register(androidGo, DeviceRegistration{
+		Constructor: func(specs DeviceSpecs) (MobileDevice, error) {
+			return NewAndroidDevice(specs), nil
+		},
+		Defaults: DeviceSpecs{Platform: Platform{Name: "Android Go", Version: Version{Major: 13}}},
+	})
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	if err := factory.Register(Android, DeviceRegistration{Constructor: func(specs DeviceSpecs) (MobileDevice, error) {
+		return NewAndroidDevice(specs), nil
+	}}); errors.Is(err, ErrDeviceTypeRegistered) {
+		fmt.Println("Duplicate registration rejected:", err)
+	}
+	if err := factory.SetDefaults(Android, DeviceSpecs{Platform: Platform{Name: "Android", Version: Version{Major: 14}}}); err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	fmt.Println("Registered device types:", factory.Types())
+
+	goSpecs := DeviceSpecs{RAM: 2 * GB, Storage: 32 * GB, CPU: CPU{Family: GenericCPU, Cores: 4}}
+	goDevice, err := factory.CreateDevice(androidGo, &goSpecs)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	fmt.Printf("Android Go Device Specs: %+v\n", goDevice.GetSpecs())
+
+	newAndroid, err := factory.CreateDevice(Android, nil)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	fmt.Println("New Android devices ship with", newAndroid.GetPlatform())
+
+	// Override only some fields: the rest come from the factory's defaults,
+	// then from the defaults of the type
+	bigStorage := DeviceSpecs{Storage: 512 * GB}
+	bigAndroid, err := factory.CreateDevice(Android, &bigStorage)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	fmt.Printf("Android with more storage: %+v\n", bigAndroid.GetSpecs())
+
+	// The factory's generic CPU comes before the one of the iOS defaults;
+	// Clear skips the factory's default so the type's CPU is used
+	iosDefaults := DeviceSpecs{
+		CPU:      CPU{Family: AppleA, Model: "A16 Bionic", Generation: 16},
+		Platform: Platform{Name: "iOS", Version: Version{Major: 17}},
+	}
+	if err := factory.SetDefaults(IOS, iosDefaults); err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	genericIos, err := factory.CreateDevice(IOS, &bigStorage)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	fmt.Printf("iOS with more storage: %+v\n", genericIos.GetSpecs())
+	bigIos, err := factory.CreateDevice(IOS, &bigStorage, Clear(FieldCPU))
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	fmt.Printf("iOS with more storage and the CPU of its type: %+v\n", bigIos.GetSpecs())
+
+	if _, err := factory.CreateDevice("HarmonyOS", nil); errors.Is(err, ErrUnsupportedDeviceType) {
+		fmt.Println("Error:", err)
+	}
+
+	// Devices install apps from their store's catalog and track the storage
+	// the apps take
+	catalog, err := NewCatalog(append(DefaultCatalog().Apps(),
+		App{Name: "Offline Maps", Size: 4 * GB, MinVersion: map[Store]Version{GooglePlay: {Major: 8}}},
+		App{Name: "Pixel Camera", Size: 300 * MB, MinVersion: map[Store]Version{GooglePlay: {Major: 14}}},
+	)...)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	goDevice.(*AndroidDevice).SetCatalog(catalog)
+	for _, app := range []string{"Genshin Impact", "WhatsApp", "Offline Maps", "Final Cut Camera", "Pixel Camera", "Snake"} {
+		install(goDevice, app)
+	}
+	printApps := func(device MobileDevice) {
+		var names []string
+		for _, app := range device.ListApps() {
+			names = append(names, app.Name)
+		}
+		fmt.Printf("Installed apps: %v, %s free\n", names, device.FreeStorage())
+	}
+	printApps(goDevice)
+	if err := goDevice.Uninstall("Genshin Impact"); err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	install(goDevice, "Offline Maps")
+	printApps(goDevice)
+	if err := goDevice.Uninstall("Genshin Impact"); errors.Is(err, ErrAppNotInstalled) {
+		fmt.Println("Error:", err)
+	}
+
+	// Specs are parsed and validated, so impossible devices are rejected
+	if _, err := ParseDeviceSpecs("8 gigs", "128GB", "Octa-core", "Android 13"); err != nil {
+		fmt.Println("Error:", err)
+	}
+	impossible := DeviceSpecs{Storage: 64 * GB, CPU: CPU{Family: "Pentium"}}
+	if _, err := factory.CreateDevice(Android, &impossible, Clear(FieldRAM)); errors.Is(err, ErrInvalidSpecs) {
+		fmt.Println("Error:", err)
+	}
 }
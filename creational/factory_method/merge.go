@@ -0,0 +1,71 @@
+package factorymethod
+
+// SpecField names a field of DeviceSpecs
+type SpecField string
+
+const (
+	FieldRAM      SpecField = "RAM"
+	FieldStorage  SpecField = "Storage"
+	FieldCPU      SpecField = "CPU"
+	FieldPlatform SpecField = "Platform"
+)
+
+// CreateOption configures a single call to CreateDevice
+type CreateOption func(*createOptions)
+
+type createOptions struct {
+	cleared map[SpecField]bool
+}
+
+// Clear keeps fields from being filled in by the factory's default specs
+// for one device, so they fall back to the defaults of the device type
+// instead. Specs passed to CreateDevice still come first. A cleared field
+// that the type does not set either stays unset, and Validate rejects the
+// device
+func Clear(fields ...SpecField) CreateOption {
+	return func(o *createOptions) {
+		if o.cleared == nil {
+			o.cleared = make(map[SpecField]bool)
+		}
+		for _, field := range fields {
+			o.cleared[field] = true
+		}
+	}
+}
+
+// mergeSpecs takes each field from the first layer that sets it
+func mergeSpecs(layers ...DeviceSpecs) DeviceSpecs {
+	var merged DeviceSpecs
+	for _, layer := range layers {
+		if merged.RAM == 0 {
+			merged.RAM = layer.RAM
+		}
+		if merged.Storage == 0 {
+			merged.Storage = layer.Storage
+		}
+		if merged.CPU == (CPU{}) {
+			merged.CPU = layer.CPU
+		}
+		if merged.Platform == (Platform{}) {
+			merged.Platform = layer.Platform
+		}
+	}
+	return merged
+}
+
+// without returns the specs with the given fields unset
+func (s DeviceSpecs) without(fields map[SpecField]bool) DeviceSpecs {
+	if fields[FieldRAM] {
+		s.RAM = 0
+	}
+	if fields[FieldStorage] {
+		s.Storage = 0
+	}
+	if fields[FieldCPU] {
+		s.CPU = CPU{}
+	}
+	if fields[FieldPlatform] {
+		s.Platform = Platform{}
+	}
+	return s
+}
@@ -0,0 +1,116 @@
+package factorymethod_test
+
+import (
+	"reflect"
+	"testing"
+
+	fm "go-design-patterns/creational/factory_method"
+)
+
+func TestCreateDeviceMergesSpecs(t *testing.T) {
+	octaCore := fm.CPU{Family: fm.GenericCPU, Cores: 8}
+	quadCore := fm.CPU{Family: fm.GenericCPU, Cores: 4}
+	android13 := fm.Platform{Name: "Android", Version: fm.Version{Major: 13}}
+
+	// The factory sets RAM and CPU; the type sets every field
+	factory := fm.NewDeviceFactory(fm.DeviceSpecs{RAM: 8 * fm.GB, CPU: octaCore})
+	const phone fm.DeviceType = "Phone"
+	err := factory.Register(phone, fm.DeviceRegistration{
+		Constructor: func(specs fm.DeviceSpecs) (fm.MobileDevice, error) {
+			return fm.NewAndroidDevice(specs), nil
+		},
+		Defaults: fm.DeviceSpecs{RAM: 4 * fm.GB, Storage: 64 * fm.GB, CPU: quadCore, Platform: android13},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		specs *fm.DeviceSpecs
+		opts  []fm.CreateOption
+		want  fm.DeviceSpecs
+	}{
+		{
+			name: "defaults only",
+			want: fm.DeviceSpecs{RAM: 8 * fm.GB, Storage: 64 * fm.GB, CPU: octaCore, Platform: android13},
+		},
+		{
+			name:  "override beats factory default",
+			specs: &fm.DeviceSpecs{RAM: 6 * fm.GB},
+			want:  fm.DeviceSpecs{RAM: 6 * fm.GB, Storage: 64 * fm.GB, CPU: octaCore, Platform: android13},
+		},
+		{
+			name:  "override beats type default",
+			specs: &fm.DeviceSpecs{Storage: 256 * fm.GB},
+			want:  fm.DeviceSpecs{RAM: 8 * fm.GB, Storage: 256 * fm.GB, CPU: octaCore, Platform: android13},
+		},
+		{
+			// Without Clear the factory's 8GB would win
+			name: "clear falls back to the type default",
+			opts: []fm.CreateOption{fm.Clear(fm.FieldRAM)},
+			want: fm.DeviceSpecs{RAM: 4 * fm.GB, Storage: 64 * fm.GB, CPU: octaCore, Platform: android13},
+		},
+		{
+			name: "clear of a field only the type sets",
+			opts: []fm.CreateOption{fm.Clear(fm.FieldStorage)},
+			want: fm.DeviceSpecs{RAM: 8 * fm.GB, Storage: 64 * fm.GB, CPU: octaCore, Platform: android13},
+		},
+		{
+			name:  "clear keeps the override",
+			specs: &fm.DeviceSpecs{CPU: quadCore},
+			opts:  []fm.CreateOption{fm.Clear(fm.FieldCPU)},
+			want:  fm.DeviceSpecs{RAM: 8 * fm.GB, Storage: 64 * fm.GB, CPU: quadCore, Platform: android13},
+		},
+		{
+			name:  "clears accumulate",
+			specs: &fm.DeviceSpecs{Storage: 128 * fm.GB},
+			opts:  []fm.CreateOption{fm.Clear(fm.FieldRAM), fm.Clear(fm.FieldCPU, fm.FieldPlatform)},
+			want:  fm.DeviceSpecs{RAM: 4 * fm.GB, Storage: 128 * fm.GB, CPU: quadCore, Platform: android13},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			device, err := factory.CreateDevice(phone, tt.specs, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := device.GetSpecs(); got != tt.want {
+				t.Errorf("specs %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClearDiffersFromDefaults(t *testing.T) {
+	// The factory sets a CPU the type's own defaults would not pick
+	factory := fm.NewDeviceFactory(fm.DeviceSpecs{RAM: 8 * fm.GB, Storage: 128 * fm.GB, CPU: fm.CPU{Family: fm.GenericCPU, Cores: 8}})
+	appleCPU := fm.CPU{Family: fm.AppleA, Model: "A16 Bionic", Generation: 16}
+	if err := factory.SetDefaults(fm.IOS, fm.DeviceSpecs{CPU: appleCPU, Platform: fm.Platform{Name: "iOS", Version: fm.Version{Major: 17}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	defaulted, err := factory.CreateDevice(fm.IOS, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cleared, err := factory.CreateDevice(fm.IOS, nil, fm.Clear(fm.FieldCPU))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := defaulted.GetSpecs().CPU; got == appleCPU {
+		t.Fatalf("without Clear the device got the type's CPU %s, want the factory's", got)
+	}
+	if got := cleared.GetSpecs().CPU; got != appleCPU {
+		t.Errorf("cleared CPU is %s, want the type's %s", got, appleCPU)
+	}
+	if defaulted.GetSpecs().RAM != cleared.GetSpecs().RAM {
+		t.Error("Clear of the CPU changed the RAM")
+	}
+
+	// A cleared field the type does not set either is left unset
+	_, err = factory.CreateDevice(fm.Android, nil, fm.Clear(fm.FieldCPU))
+	if got := fields(t, err); !reflect.DeepEqual(got, []fm.SpecField{fm.FieldCPU}) {
+		t.Errorf("CreateDevice rejected %v (%v), want [CPU]", got, err)
+	}
+}
@@ -0,0 +1,128 @@
+package factorymethod
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// DeviceConstructor creates a device of one type from the final specs
+type DeviceConstructor func(specs DeviceSpecs) (MobileDevice, error)
+
+// DeviceRegistration describes how a DeviceFactory creates one device type
+type DeviceRegistration struct {
+	Constructor DeviceConstructor
+	// Defaults fill the spec fields left empty when creating a device
+	Defaults DeviceSpecs
+}
+
+var (
+	// ErrDeviceTypeRegistered is returned when a device type is registered twice
+	ErrDeviceTypeRegistered = errors.New("device type already registered")
+	// ErrUnsupportedDeviceType is returned for a device type that is not registered
+	ErrUnsupportedDeviceType = errors.New("unsupported device type")
+	// ErrInvalidRegistration is returned for an empty device type or a nil constructor
+	ErrInvalidRegistration = errors.New("invalid device registration")
+)
+
+// RegistryError describes a failed registry operation for a single device type
+type RegistryError struct {
+	Op   string // "register", "override", "defaults", "unregister" or "lookup"
+	Type DeviceType
+	Err  error
+}
+
+func (e *RegistryError) Error() string {
+	return fmt.Sprintf("%s device type %q: %v", e.Op, e.Type, e.Err)
+}
+
+func (e *RegistryError) Unwrap() error {
+	return e.Err
+}
+
+// Register adds a device type to the factory. It fails if the type is
+// already registered, including the built-in Android and iOS types; use
+// Override to replace those
+func (f *DeviceFactory) Register(deviceType DeviceType, registration DeviceRegistration) error {
+	if deviceType == "" || registration.Constructor == nil {
+		return &RegistryError{Op: "register", Type: deviceType, Err: ErrInvalidRegistration}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, exists := f.types[deviceType]; exists {
+		return &RegistryError{Op: "register", Type: deviceType, Err: ErrDeviceTypeRegistered}
+	}
+	f.types[deviceType] = registration
+	return nil
+}
+
+// Override replaces the registration of a registered device type, e.g. to
+// create test devices in place of real ones
+func (f *DeviceFactory) Override(deviceType DeviceType, registration DeviceRegistration) error {
+	if registration.Constructor == nil {
+		return &RegistryError{Op: "override", Type: deviceType, Err: ErrInvalidRegistration}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, exists := f.types[deviceType]; !exists {
+		return &RegistryError{Op: "override", Type: deviceType, Err: ErrUnsupportedDeviceType}
+	}
+	f.types[deviceType] = registration
+	return nil
+}
+
+// SetDefaults replaces the default specs of a registered device type and
+// keeps its constructor
+func (f *DeviceFactory) SetDefaults(deviceType DeviceType, defaults DeviceSpecs) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	registration, exists := f.types[deviceType]
+	if !exists {
+		return &RegistryError{Op: "defaults", Type: deviceType, Err: ErrUnsupportedDeviceType}
+	}
+	registration.Defaults = defaults
+	f.types[deviceType] = registration
+	return nil
+}
+
+// Unregister removes a device type from the factory
+func (f *DeviceFactory) Unregister(deviceType DeviceType) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, exists := f.types[deviceType]; !exists {
+		return &RegistryError{Op: "unregister", Type: deviceType, Err: ErrUnsupportedDeviceType}
+	}
+	delete(f.types, deviceType)
+	return nil
+}
+
+// Types returns the registered device types in sorted order
+func (f *DeviceFactory) Types() []DeviceType {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	types := make([]DeviceType, 0, len(f.types))
+	for deviceType := range f.types {
+		types = append(types, deviceType)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	return types
+}
+
+// lookup returns the registration of a device type
+func (f *DeviceFactory) lookup(deviceType DeviceType) (DeviceRegistration, error) {
+	f.mu.RLock()
+	registration, exists := f.types[deviceType]
+	f.mu.RUnlock()
+
+	if !exists {
+		return DeviceRegistration{}, &RegistryError{Op: "lookup", Type: deviceType, Err: ErrUnsupportedDeviceType}
+	}
+	return registration, nil
+}
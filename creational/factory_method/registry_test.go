@@ -0,0 +1,144 @@
+package factorymethod_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	fm "go-design-patterns/creational/factory_method"
+)
+
+const tablet fm.DeviceType = "Tablet"
+
+// tabletRegistration creates Android devices marked by their storage, so
+// tests can tell which registration built a device
+func tabletRegistration(storage fm.ByteSize) fm.DeviceRegistration {
+	return fm.DeviceRegistration{
+		Constructor: func(specs fm.DeviceSpecs) (fm.MobileDevice, error) {
+			specs.Storage = storage
+			return fm.NewAndroidDevice(specs), nil
+		},
+		Defaults: fm.DeviceSpecs{
+			RAM:      4 * fm.GB,
+			Storage:  64 * fm.GB,
+			CPU:      fm.CPU{Family: fm.GenericCPU, Cores: 8},
+			Platform: fm.Platform{Name: "Android", Version: fm.Version{Major: 13}},
+		},
+	}
+}
+
+// wantRegistryError checks that err is a RegistryError of op and deviceType
+// matching target
+func wantRegistryError(t *testing.T, err error, op string, deviceType fm.DeviceType, target error) {
+	t.Helper()
+	var registryErr *fm.RegistryError
+	if !errors.As(err, &registryErr) || !errors.Is(err, target) || registryErr.Op != op || registryErr.Type != deviceType {
+		t.Errorf("error %v, want a %s RegistryError for %q matching %v", err, op, deviceType, target)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	factory := fm.NewDeviceFactory(fm.DeviceSpecs{})
+	if got, want := factory.Types(), []fm.DeviceType{fm.Android, fm.IOS}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("new factory has types %v, want %v", got, want)
+	}
+
+	if err := factory.Register(tablet, tabletRegistration(fm.TB)); err != nil {
+		t.Fatal(err)
+	}
+	device, err := factory.CreateDevice(tablet, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if storage := device.GetSpecs().Storage; storage != fm.TB {
+		t.Errorf("device built with storage %s, want the registered constructor's 1TB", storage)
+	}
+	if got, want := factory.Types(), []fm.DeviceType{fm.Android, "Tablet", fm.IOS}; !reflect.DeepEqual(got, want) {
+		t.Errorf("types %v, want %v", got, want)
+	}
+
+	wantRegistryError(t, factory.Register(tablet, tabletRegistration(fm.TB)), "register", tablet, fm.ErrDeviceTypeRegistered)
+	wantRegistryError(t, factory.Register(fm.Android, tabletRegistration(fm.TB)), "register", fm.Android, fm.ErrDeviceTypeRegistered)
+	wantRegistryError(t, factory.Register("", tabletRegistration(fm.TB)), "register", "", fm.ErrInvalidRegistration)
+	wantRegistryError(t, factory.Register("Watch", fm.DeviceRegistration{}), "register", "Watch", fm.ErrInvalidRegistration)
+}
+
+func TestOverride(t *testing.T) {
+	factory := fm.NewDeviceFactory(fm.DeviceSpecs{})
+	wantRegistryError(t, factory.Override(tablet, tabletRegistration(fm.TB)), "override", tablet, fm.ErrUnsupportedDeviceType)
+
+	if err := factory.Register(tablet, tabletRegistration(fm.TB)); err != nil {
+		t.Fatal(err)
+	}
+	wantRegistryError(t, factory.Override(tablet, fm.DeviceRegistration{}), "override", tablet, fm.ErrInvalidRegistration)
+	if err := factory.Override(tablet, tabletRegistration(2*fm.TB)); err != nil {
+		t.Fatal(err)
+	}
+	device, err := factory.CreateDevice(tablet, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if storage := device.GetSpecs().Storage; storage != 2*fm.TB {
+		t.Errorf("device built with storage %s, want the overriding constructor's 2TB", storage)
+	}
+
+	// Built-in types can be overridden too
+	if err := factory.Override(fm.Android, tabletRegistration(2*fm.TB)); err != nil {
+		t.Errorf("Override of a built-in type = %v", err)
+	}
+}
+
+func TestSetDefaults(t *testing.T) {
+	factory := fm.NewDeviceFactory(fm.DeviceSpecs{})
+	wantRegistryError(t, factory.SetDefaults(tablet, fm.DeviceSpecs{}), "defaults", tablet, fm.ErrUnsupportedDeviceType)
+
+	if err := factory.Register(tablet, tabletRegistration(fm.TB)); err != nil {
+		t.Fatal(err)
+	}
+	defaults := tabletRegistration(fm.TB).Defaults
+	defaults.RAM = 12 * fm.GB
+	if err := factory.SetDefaults(tablet, defaults); err != nil {
+		t.Fatal(err)
+	}
+	device, err := factory.CreateDevice(tablet, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The new defaults apply and the constructor is kept
+	if specs := device.GetSpecs(); specs.RAM != 12*fm.GB || specs.Storage != fm.TB {
+		t.Errorf("specs %+v, want 12GB of RAM from the new defaults and 1TB from the constructor", specs)
+	}
+}
+
+func TestUnregister(t *testing.T) {
+	factory := fm.NewDeviceFactory(fm.DeviceSpecs{})
+	wantRegistryError(t, factory.Unregister(tablet), "unregister", tablet, fm.ErrUnsupportedDeviceType)
+
+	if err := factory.Unregister(fm.IOS); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := factory.Types(), []fm.DeviceType{fm.Android}; !reflect.DeepEqual(got, want) {
+		t.Errorf("types %v, want %v", got, want)
+	}
+	_, err := factory.CreateDevice(fm.IOS, nil)
+	wantRegistryError(t, err, "lookup", fm.IOS, fm.ErrUnsupportedDeviceType)
+
+	// A removed type can be registered again
+	if err := factory.Register(fm.IOS, tabletRegistration(fm.TB)); err != nil {
+		t.Errorf("Register after Unregister = %v", err)
+	}
+}
+
+func TestFactoriesDoNotShareTypes(t *testing.T) {
+	first := fm.NewDeviceFactory(fm.DeviceSpecs{})
+	second := fm.NewDeviceFactory(fm.DeviceSpecs{})
+	if err := first.Register(tablet, tabletRegistration(fm.TB)); err != nil {
+		t.Fatal(err)
+	}
+	if err := first.Unregister(fm.Android); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := second.Types(), []fm.DeviceType{fm.Android, fm.IOS}; !reflect.DeepEqual(got, want) {
+		t.Errorf("second factory has types %v, want %v", got, want)
+	}
+}
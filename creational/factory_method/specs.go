@@ -0,0 +1,318 @@
+package factorymethod
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrInvalidSpecs is wrapped by every error about device specs
+	ErrInvalidSpecs = errors.New("invalid device specs")
+	// ErrInvalidByteSize is returned for a size that cannot be parsed
+	ErrInvalidByteSize = errors.New("invalid byte size")
+	// ErrUnknownCPUFamily is returned for a CPU of no known family
+	ErrUnknownCPUFamily = errors.New("unknown CPU family")
+	// ErrInvalidPlatform is returned for a platform that cannot be parsed
+	ErrInvalidPlatform = errors.New("invalid platform")
+)
+
+// FieldError is a problem with one field of DeviceSpecs
+type FieldError struct {
+	Field SpecField
+	Err   error
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Field, e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// SpecsError lists the problems with the fields of DeviceSpecs. It matches
+// ErrInvalidSpecs and the errors of each field
+type SpecsError struct {
+	Fields []*FieldError
+}
+
+func (e *SpecsError) Error() string {
+	problems := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		problems[i] = field.Error()
+	}
+	return fmt.Sprintf("%v: %s", ErrInvalidSpecs, strings.Join(problems, "; "))
+}
+
+func (e *SpecsError) Unwrap() []error {
+	errs := []error{ErrInvalidSpecs}
+	for _, field := range e.Fields {
+		errs = append(errs, field)
+	}
+	return errs
+}
+
+// add records a problem with a field
+func (e *SpecsError) add(field SpecField, err error) {
+	e.Fields = append(e.Fields, &FieldError{Field: field, Err: err})
+}
+
+// errOrNil returns e if it records any problem
+func (e *SpecsError) errOrNil() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+// ByteSize is an amount of memory or storage in bytes. Units are binary, as
+// on device spec sheets: 1KB is 1024 bytes
+type ByteSize int64
+
+const (
+	B  ByteSize = 1
+	KB ByteSize = 1 << (10 * iota)
+	MB
+	GB
+	TB
+)
+
+var byteUnits = []struct {
+	name string
+	size ByteSize
+}{{"TB", TB}, {"GB", GB}, {"MB", MB}, {"KB", KB}, {"B", B}}
+
+var byteSizePattern = regexp.MustCompile(`^(\d+(?:\.\d+)?)\s*([KMGT]?B)$`)
+
+// ParseByteSize parses sizes such as "8GB", "512 MB" or "1.5TB"
+func ParseByteSize(s string) (ByteSize, error) {
+	match := byteSizePattern.FindStringSubmatch(strings.ToUpper(strings.TrimSpace(s)))
+	if match == nil {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidByteSize, s)
+	}
+	value, err := strconv.ParseFloat(match[1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidByteSize, s)
+	}
+	for _, unit := range byteUnits {
+		if unit.name == match[2] {
+			bytes := value * float64(unit.size)
+			// float64(math.MaxInt64) rounds up to 2^63, which no ByteSize holds
+			if bytes >= math.MaxInt64 {
+				return 0, fmt.Errorf("%w: %q is too large", ErrInvalidByteSize, s)
+			}
+			if bytes != math.Trunc(bytes) {
+				return 0, fmt.Errorf("%w: %q is not a whole number of bytes", ErrInvalidByteSize, s)
+			}
+			return ByteSize(bytes), nil
+		}
+	}
+	return 0, fmt.Errorf("%w: %q", ErrInvalidByteSize, s)
+}
+
+// String formats the size in the largest unit it is a whole number of
+func (s ByteSize) String() string {
+	for _, unit := range byteUnits {
+		if s != 0 && s%unit.size == 0 {
+			return fmt.Sprintf("%d%s", s/unit.size, unit.name)
+		}
+	}
+	return fmt.Sprintf("%dB", int64(s))
+}
+
+// CPUFamily is the maker's line a CPU belongs to
+type CPUFamily string
+
+const (
+	// GenericCPU describes a CPU only by its number of cores
+	GenericCPU CPUFamily = "generic"
+	AppleA     CPUFamily = "Apple A"
+	Snapdragon CPUFamily = "Snapdragon"
+	Exynos     CPUFamily = "Exynos"
+	Dimensity  CPUFamily = "Dimensity"
+	Helio      CPUFamily = "Helio"
+	Tensor     CPUFamily = "Tensor"
+	Kirin      CPUFamily = "Kirin"
+)
+
+// cpuFamilies are the families ParseCPU recognises by the first word
+var cpuFamilies = []CPUFamily{Snapdragon, Exynos, Dimensity, Helio, Tensor, Kirin}
+
+// known reports whether the family is one the package knows
+func (f CPUFamily) known() bool {
+	if f == GenericCPU || f == AppleA {
+		return true
+	}
+	for _, family := range cpuFamilies {
+		if f == family {
+			return true
+		}
+	}
+	return false
+}
+
+var coreCounts = map[string]int{"single": 1, "dual": 2, "quad": 4, "hexa": 6, "octa": 8, "deca": 10}
+
+var (
+	coresPattern  = regexp.MustCompile(`(?i)^(\w+)-core$`)
+	appleAPattern = regexp.MustCompile(`^A(\d+)(?:\s.*)?$`)
+)
+
+// CPU describes a device's processor
+type CPU struct {
+	Family CPUFamily
+	// Model is the name within the family, e.g. "A15 Bionic" or "8 Gen 2"
+	Model string
+	// Cores is the number of cores, zero if unknown
+	Cores int
+	// Generation orders the models of the Apple A family, e.g. 15 for the
+	// A15 Bionic; zero for other families
+	Generation int
+}
+
+// ParseCPU parses descriptions such as "Octa-core", "8-core", "A15 Bionic"
+// or "Snapdragon 8 Gen 2"
+func ParseCPU(s string) (CPU, error) {
+	s = strings.TrimSpace(s)
+	if match := coresPattern.FindStringSubmatch(s); match != nil {
+		cores, ok := coreCounts[strings.ToLower(match[1])]
+		if !ok {
+			var err error
+			if cores, err = strconv.Atoi(match[1]); err != nil {
+				return CPU{}, fmt.Errorf("%w: %q", ErrUnknownCPUFamily, s)
+			}
+		}
+		return CPU{Family: GenericCPU, Cores: cores}, nil
+	}
+	if match := appleAPattern.FindStringSubmatch(s); match != nil {
+		generation, _ := strconv.Atoi(match[1])
+		return CPU{Family: AppleA, Model: s, Generation: generation}, nil
+	}
+
+	name, model, _ := strings.Cut(s, " ")
+	for _, family := range cpuFamilies {
+		if strings.EqualFold(name, string(family)) {
+			return CPU{Family: family, Model: strings.TrimSpace(model)}, nil
+		}
+	}
+	return CPU{}, fmt.Errorf("%w: %q", ErrUnknownCPUFamily, s)
+}
+
+// String formats the CPU the way ParseCPU reads it
+func (c CPU) String() string {
+	switch c.Family {
+	case GenericCPU:
+		for word, cores := range coreCounts {
+			if cores == c.Cores {
+				return strings.ToUpper(word[:1]) + word[1:] + "-core"
+			}
+		}
+		return fmt.Sprintf("%d-core", c.Cores)
+	case AppleA:
+		return c.Model
+	}
+	return strings.TrimSpace(string(c.Family) + " " + c.Model)
+}
+
+// validate checks that the CPU is of a known family and has cores
+func (c CPU) validate() error {
+	switch {
+	case c.Family == "":
+		return errors.New("is required")
+	case !c.Family.known():
+		return fmt.Errorf("%w: %q", ErrUnknownCPUFamily, c.Family)
+	case c.Cores < 0:
+		return fmt.Errorf("cannot have %d cores", c.Cores)
+	case c.Family == GenericCPU && c.Cores == 0:
+		return errors.New("generic CPU needs a number of cores")
+	case c.Family == AppleA && c.Generation <= 0:
+		return errors.New("Apple A CPU needs a generation")
+	}
+	return nil
+}
+
+// Platform is an operating system and its version, e.g. "Android 13.0"
+type Platform struct {
+	Name    string
+	Version Version
+}
+
+// ParsePlatform parses a platform name followed by its version
+func ParsePlatform(s string) (Platform, error) {
+	s = strings.TrimSpace(s)
+	i := strings.LastIndex(s, " ")
+	if i <= 0 {
+		return Platform{}, fmt.Errorf("%w: %q needs a name and a version", ErrInvalidPlatform, s)
+	}
+	version, err := ParseVersion(s[i+1:])
+	if err != nil {
+		return Platform{}, fmt.Errorf("%w: %q: %w", ErrInvalidPlatform, s, err)
+	}
+	return Platform{Name: strings.TrimSpace(s[:i]), Version: version}, nil
+}
+
+// String formats the platform the way ParsePlatform reads it
+func (p Platform) String() string {
+	if p.Name == "" {
+		return ""
+	}
+	return p.Name + " " + p.Version.String()
+}
+
+// ParseDeviceSpecs parses the fields of DeviceSpecs from their usual
+// descriptions, e.g. "8GB", "128GB", "Octa-core" and "Android 13.0". Empty
+// descriptions leave their fields unset. The SpecsError names every field
+// that cannot be parsed
+func ParseDeviceSpecs(ram, storage, cpu, platform string) (DeviceSpecs, error) {
+	var specs DeviceSpecs
+	problems := &SpecsError{}
+	var err error
+	if ram != "" {
+		if specs.RAM, err = ParseByteSize(ram); err != nil {
+			problems.add(FieldRAM, err)
+		}
+	}
+	if storage != "" {
+		if specs.Storage, err = ParseByteSize(storage); err != nil {
+			problems.add(FieldStorage, err)
+		}
+	}
+	if cpu != "" {
+		if specs.CPU, err = ParseCPU(cpu); err != nil {
+			problems.add(FieldCPU, err)
+		}
+	}
+	if platform != "" {
+		if specs.Platform, err = ParsePlatform(platform); err != nil {
+			problems.add(FieldPlatform, err)
+		}
+	}
+	return specs, problems.errOrNil()
+}
+
+// Validate checks that the specs describe a device that can exist: RAM and
+// storage are positive and RAM does not exceed storage, the CPU is of a
+// known family and the platform is named. The SpecsError names every field
+// with a problem
+func (s DeviceSpecs) Validate() error {
+	problems := &SpecsError{}
+	if s.RAM <= 0 {
+		problems.add(FieldRAM, fmt.Errorf("must be positive, got %s", s.RAM))
+	} else if s.Storage > 0 && s.RAM > s.Storage {
+		problems.add(FieldRAM, fmt.Errorf("%s exceeds storage of %s", s.RAM, s.Storage))
+	}
+	if s.Storage <= 0 {
+		problems.add(FieldStorage, fmt.Errorf("must be positive, got %s", s.Storage))
+	}
+	if err := s.CPU.validate(); err != nil {
+		problems.add(FieldCPU, err)
+	}
+	if s.Platform.Name == "" {
+		problems.add(FieldPlatform, errors.New("is required"))
+	}
+	return problems.errOrNil()
+}
@@ -0,0 +1,208 @@
+package factorymethod_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	fm "go-design-patterns/creational/factory_method"
+)
+
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    fm.ByteSize
+		wantErr bool
+	}{
+		{in: "8GB", want: 8 * fm.GB},
+		{in: "512 mb", want: 512 * fm.MB},
+		{in: " 1.5TB ", want: 1536 * fm.GB},
+		{in: "64KB", want: 64 * fm.KB},
+		{in: "0B", want: 0},
+		{in: "8388607TB", want: 8388607 * fm.TB},
+		{in: "8388608TB", wantErr: true},
+		{in: "9999999999999999999B", wantErr: true},
+		{in: "0.3B", wantErr: true},
+		{in: "8 gigs", wantErr: true},
+		{in: "-1GB", wantErr: true},
+		{in: "GB", wantErr: true},
+		{in: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := fm.ParseByteSize(tt.in)
+		if tt.wantErr {
+			if !errors.Is(err, fm.ErrInvalidByteSize) {
+				t.Errorf("ParseByteSize(%q) = %d, %v; want ErrInvalidByteSize", tt.in, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ParseByteSize(%q) = %d, %v; want %d", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestByteSizeString(t *testing.T) {
+	tests := map[fm.ByteSize]string{
+		8 * fm.GB:    "8GB",
+		1536 * fm.MB: "1536MB",
+		fm.TB:        "1TB",
+		1000:         "1000B",
+		0:            "0B",
+	}
+	for size, want := range tests {
+		if got := size.String(); got != want {
+			t.Errorf("ByteSize(%d).String() = %q, want %q", int64(size), got, want)
+		}
+	}
+}
+
+func TestParseCPU(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    fm.CPU
+		wantErr error
+	}{
+		{in: "Octa-core", want: fm.CPU{Family: fm.GenericCPU, Cores: 8}},
+		{in: "quad-core", want: fm.CPU{Family: fm.GenericCPU, Cores: 4}},
+		{in: "12-core", want: fm.CPU{Family: fm.GenericCPU, Cores: 12}},
+		{in: "A15 Bionic", want: fm.CPU{Family: fm.AppleA, Model: "A15 Bionic", Generation: 15}},
+		{in: "A17 Pro", want: fm.CPU{Family: fm.AppleA, Model: "A17 Pro", Generation: 17}},
+		{in: "snapdragon 8 Gen 2", want: fm.CPU{Family: fm.Snapdragon, Model: "8 Gen 2"}},
+		{in: "Tensor G3", want: fm.CPU{Family: fm.Tensor, Model: "G3"}},
+		{in: "Pentium 4", wantErr: fm.ErrUnknownCPUFamily},
+		{in: "zillion-core", wantErr: fm.ErrUnknownCPUFamily},
+		{in: "", wantErr: fm.ErrUnknownCPUFamily},
+	}
+	for _, tt := range tests {
+		got, err := fm.ParseCPU(tt.in)
+		if tt.wantErr != nil {
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("ParseCPU(%q) = %+v, %v; want %v", tt.in, got, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ParseCPU(%q) = %+v, %v; want %+v", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestParsePlatform(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    fm.Platform
+		wantErr bool
+	}{
+		{in: "Android 13.0", want: fm.Platform{Name: "Android", Version: fm.Version{Major: 13}}},
+		{in: "Android Go 13", want: fm.Platform{Name: "Android Go", Version: fm.Version{Major: 13}}},
+		{in: "HarmonyOS 4.0.1", want: fm.Platform{Name: "HarmonyOS", Version: fm.Version{Major: 4, Patch: 1}}},
+		{in: "iOS 18.0.0-beta.2", want: fm.Platform{Name: "iOS", Version: fm.Version{Major: 18, PreRelease: "beta.2"}}},
+		{in: "Android", wantErr: true},
+		{in: "iOS banana", wantErr: true},
+		{in: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := fm.ParsePlatform(tt.in)
+		if tt.wantErr {
+			if !errors.Is(err, fm.ErrInvalidPlatform) {
+				t.Errorf("ParsePlatform(%q) = %+v, %v; want ErrInvalidPlatform", tt.in, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ParsePlatform(%q) = %+v, %v; want %+v", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+// fields returns the names of the fields a SpecsError reports, in order.
+func fields(t *testing.T, err error) []fm.SpecField {
+	t.Helper()
+	if err == nil {
+		return nil
+	}
+	var specsErr *fm.SpecsError
+	if !errors.As(err, &specsErr) {
+		t.Fatalf("error %v is not a *SpecsError", err)
+	}
+	if !errors.Is(err, fm.ErrInvalidSpecs) {
+		t.Errorf("error %v does not match ErrInvalidSpecs", err)
+	}
+	var names []fm.SpecField
+	for _, field := range specsErr.Fields {
+		names = append(names, field.Field)
+	}
+	return names
+}
+
+func TestParseDeviceSpecs(t *testing.T) {
+	specs, err := fm.ParseDeviceSpecs("6GB", "256GB", "A15 Bionic", "iOS 17.0")
+	want := fm.DeviceSpecs{
+		RAM:      6 * fm.GB,
+		Storage:  256 * fm.GB,
+		CPU:      fm.CPU{Family: fm.AppleA, Model: "A15 Bionic", Generation: 15},
+		Platform: fm.Platform{Name: "iOS", Version: fm.Version{Major: 17}},
+	}
+	if err != nil || specs != want {
+		t.Fatalf("ParseDeviceSpecs = %+v, %v; want %+v", specs, err, want)
+	}
+
+	specs, err = fm.ParseDeviceSpecs("", "64GB", "", "")
+	if err != nil || specs != (fm.DeviceSpecs{Storage: 64 * fm.GB}) {
+		t.Errorf("ParseDeviceSpecs with empty fields = %+v, %v; want only Storage set", specs, err)
+	}
+
+	_, err = fm.ParseDeviceSpecs("8 gigs", "lots", "Pentium", "Android")
+	wantFields := []fm.SpecField{fm.FieldRAM, fm.FieldStorage, fm.FieldCPU, fm.FieldPlatform}
+	if got := fields(t, err); !reflect.DeepEqual(got, wantFields) {
+		t.Errorf("fields %v, want %v", got, wantFields)
+	}
+	for _, sentinel := range []error{fm.ErrInvalidByteSize, fm.ErrUnknownCPUFamily, fm.ErrInvalidPlatform} {
+		if !errors.Is(err, sentinel) {
+			t.Errorf("error %v does not match %v", err, sentinel)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := fm.DeviceSpecs{
+		RAM:      8 * fm.GB,
+		Storage:  128 * fm.GB,
+		CPU:      fm.CPU{Family: fm.GenericCPU, Cores: 8},
+		Platform: fm.Platform{Name: "Android", Version: fm.Version{Major: 13}},
+	}
+	with := func(change func(*fm.DeviceSpecs)) fm.DeviceSpecs {
+		specs := valid
+		change(&specs)
+		return specs
+	}
+
+	tests := []struct {
+		name  string
+		specs fm.DeviceSpecs
+		want  []fm.SpecField
+	}{
+		{"valid", valid, nil},
+		{"zero RAM", with(func(s *fm.DeviceSpecs) { s.RAM = 0 }), []fm.SpecField{fm.FieldRAM}},
+		{"RAM above storage", with(func(s *fm.DeviceSpecs) { s.RAM = 256 * fm.GB }), []fm.SpecField{fm.FieldRAM}},
+		{"zero storage", with(func(s *fm.DeviceSpecs) { s.Storage = 0 }), []fm.SpecField{fm.FieldStorage}},
+		{"unknown CPU family", with(func(s *fm.DeviceSpecs) { s.CPU = fm.CPU{Family: "Pentium"} }), []fm.SpecField{fm.FieldCPU}},
+		{"generic CPU without cores", with(func(s *fm.DeviceSpecs) { s.CPU.Cores = 0 }), []fm.SpecField{fm.FieldCPU}},
+		{"Apple CPU without generation", with(func(s *fm.DeviceSpecs) { s.CPU = fm.CPU{Family: fm.AppleA} }), []fm.SpecField{fm.FieldCPU}},
+		{"missing platform", with(func(s *fm.DeviceSpecs) { s.Platform = fm.Platform{} }), []fm.SpecField{fm.FieldPlatform}},
+		{"everything missing", fm.DeviceSpecs{}, []fm.SpecField{fm.FieldRAM, fm.FieldStorage, fm.FieldCPU, fm.FieldPlatform}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fields(t, tt.specs.Validate()); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Validate reported %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	err := with(func(s *fm.DeviceSpecs) { s.CPU = fm.CPU{Family: "Pentium"} }).Validate()
+	if !errors.Is(err, fm.ErrUnknownCPUFamily) {
+		t.Errorf("unknown CPU family: %v does not match ErrUnknownCPUFamily", err)
+	}
+}
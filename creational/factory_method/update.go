@@ -0,0 +1,140 @@
+package factorymethod
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrUpdateNotAllowed is matched by every refused platform update
+	ErrUpdateNotAllowed = errors.New("update not allowed")
+	// ErrDowngrade is returned for an update to an older version
+	ErrDowngrade = errors.New("cannot downgrade")
+	// ErrAlreadyInstalled is returned for an update to the installed version
+	ErrAlreadyInstalled = errors.New("version already installed")
+	// ErrUnsupportedVersion is returned for a version the upgrade paths do not list
+	ErrUnsupportedVersion = errors.New("version not supported")
+	// ErrNoUpgradePath is returned when the installed version is too old to
+	// update to the version directly
+	ErrNoUpgradePath = errors.New("no upgrade path")
+	// ErrHardwareTooOld is returned when the device does not meet the
+	// requirements of the version
+	ErrHardwareTooOld = errors.New("hardware does not meet requirements")
+)
+
+// UpdateError is a refused platform update. It matches ErrUpdateNotAllowed
+// and its reason
+type UpdateError struct {
+	Platform string
+	From     Version
+	To       string
+	Err      error
+}
+
+func (e *UpdateError) Error() string {
+	return fmt.Sprintf("cannot update %s %s to %s: %v", e.Platform, e.From, e.To, e.Err)
+}
+
+func (e *UpdateError) Unwrap() []error {
+	return []error{ErrUpdateNotAllowed, e.Err}
+}
+
+// Release is an OS version devices can update to and what it requires
+type Release struct {
+	// Version is the release; its patch versions and pre-releases are
+	// covered by it as well
+	Version Version
+	// MinFrom is the oldest version that can update to the release
+	// directly; zero means any
+	MinFrom  Version
+	MinRAM   ByteSize
+	MinCores int
+	// MinAppleGeneration is the oldest Apple A CPU the release runs on
+	MinAppleGeneration int
+}
+
+// UpgradePaths lists the supported releases of each platform by name,
+// oldest first
+type UpgradePaths map[string][]Release
+
+// DefaultUpgradePaths returns the releases of Android and iOS devices
+// start with
+func DefaultUpgradePaths() UpgradePaths {
+	return UpgradePaths{
+		"Android": {
+			{Version: Version{Major: 12}, MinRAM: 2 * GB, MinCores: 4},
+			{Version: Version{Major: 13}, MinRAM: 2 * GB, MinCores: 4},
+			{Version: Version{Major: 14}, MinRAM: 3 * GB, MinCores: 8},
+			{Version: Version{Major: 15}, MinFrom: Version{Major: 13}, MinRAM: 4 * GB, MinCores: 8},
+		},
+		"iOS": {
+			{Version: Version{Major: 15}, MinRAM: 2 * GB, MinAppleGeneration: 9},
+			{Version: Version{Major: 16}, MinRAM: 2 * GB, MinAppleGeneration: 11},
+			{Version: Version{Major: 17}, MinRAM: 3 * GB, MinAppleGeneration: 12},
+			{Version: Version{Major: 18}, MinFrom: Version{Major: 16}, MinRAM: 4 * GB, MinAppleGeneration: 12},
+		},
+	}
+}
+
+// release returns the release of a platform covering version: the newest
+// listed release of the same major version not newer than it
+func (p UpgradePaths) release(platform string, version Version) (Release, bool) {
+	var found Release
+	ok := false
+	for _, r := range p[platform] {
+		if r.Version.Major == version.Major && r.Version.Minor <= version.Minor {
+			found, ok = r, true
+		}
+	}
+	return found, ok
+}
+
+// SetUpgradePaths replaces the upgrade paths Update checks against. A nil
+// table only protects against downgrades
+func (b *BaseDevice) SetUpgradePaths(paths UpgradePaths) {
+	b.upgradePaths = paths
+}
+
+// planUpdate parses newVersion and checks that the device may update to
+// it: the version must be newer than the installed one and, if the upgrade
+// paths list the platform, be a listed release that the installed version
+// can reach directly and whose hardware requirements the device meets
+func (b *BaseDevice) planUpdate(newVersion string) (Version, error) {
+	current := b.specs.Platform
+	refuse := func(err error) (Version, error) {
+		return Version{}, &UpdateError{Platform: current.Name, From: current.Version, To: newVersion, Err: err}
+	}
+
+	target, err := ParseVersion(newVersion)
+	if err != nil {
+		return refuse(err)
+	}
+	switch target.Compare(current.Version) {
+	case 0:
+		return refuse(ErrAlreadyInstalled)
+	case -1:
+		return refuse(ErrDowngrade)
+	}
+
+	if _, listed := b.upgradePaths[current.Name]; !listed {
+		return target, nil
+	}
+	release, ok := b.upgradePaths.release(current.Name, target)
+	if !ok {
+		return refuse(ErrUnsupportedVersion)
+	}
+	if current.Version.Compare(release.MinFrom) < 0 {
+		return refuse(fmt.Errorf("%w: update to %s first", ErrNoUpgradePath, release.MinFrom))
+	}
+
+	specs := b.specs
+	switch {
+	case specs.RAM < release.MinRAM:
+		return refuse(fmt.Errorf("%w: needs %s of RAM, has %s", ErrHardwareTooOld, release.MinRAM, specs.RAM))
+	case specs.CPU.Cores > 0 && specs.CPU.Cores < release.MinCores:
+		return refuse(fmt.Errorf("%w: needs %d CPU cores, has %d", ErrHardwareTooOld, release.MinCores, specs.CPU.Cores))
+	case release.MinAppleGeneration > 0 && (specs.CPU.Family != AppleA || specs.CPU.Generation < release.MinAppleGeneration):
+		return refuse(fmt.Errorf("%w: needs an A%d CPU or newer, has %s", ErrHardwareTooOld, release.MinAppleGeneration, specs.CPU))
+	}
+	return target, nil
+}
@@ -0,0 +1,142 @@
+package factorymethod
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidVersion is returned for a version that cannot be parsed
+var ErrInvalidVersion = errors.New("invalid version")
+
+// Version is a semantic platform version: major.minor.patch with an
+// optional pre-release such as "beta.2"
+type Version struct {
+	Major, Minor, Patch int
+	PreRelease          string
+}
+
+var identifierPattern = regexp.MustCompile(`^[0-9A-Za-z-]+$`)
+
+// ParseVersion parses semantic versions such as "17.0.3" or "18.0.0-beta.2".
+// Platform versions often leave out the patch or minor part, as in "13" or
+// "17.1"; missing parts are zero. Build metadata after a "+" is ignored
+func ParseVersion(s string) (Version, error) {
+	invalid := func(reason string) (Version, error) {
+		return Version{}, fmt.Errorf("%w: %q: %s", ErrInvalidVersion, s, reason)
+	}
+
+	core, build, hasBuild := strings.Cut(strings.TrimSpace(s), "+")
+	if hasBuild && !validIdentifiers(build) {
+		return invalid("malformed build metadata")
+	}
+	core, pre, hasPre := strings.Cut(core, "-")
+	if hasPre && !validIdentifiers(pre) {
+		return invalid("malformed pre-release")
+	}
+	if hasPre {
+		for _, id := range strings.Split(pre, ".") {
+			if isNumeric(id) && len(id) > 1 && id[0] == '0' {
+				return invalid("numeric pre-release identifier with leading zero")
+			}
+		}
+	}
+
+	parts := strings.Split(core, ".")
+	if len(parts) > 3 {
+		return invalid("more than three numbers")
+	}
+	var numbers [3]int
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 || part != strconv.Itoa(n) {
+			return invalid("not a number: " + strconv.Quote(part))
+		}
+		numbers[i] = n
+	}
+	return Version{Major: numbers[0], Minor: numbers[1], Patch: numbers[2], PreRelease: pre}, nil
+}
+
+// validIdentifiers reports whether s is a dot-separated list of non-empty
+// alphanumeric identifiers
+func validIdentifiers(s string) bool {
+	for _, id := range strings.Split(s, ".") {
+		if !identifierPattern.MatchString(id) {
+			return false
+		}
+	}
+	return true
+}
+
+func isNumeric(id string) bool {
+	for _, r := range id {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return id != ""
+}
+
+// Compare returns -1, 0 or +1 as v is older than, the same as or newer
+// than other, following semantic versioning: a pre-release is older than
+// its release, and pre-releases compare identifier by identifier, numbers
+// numerically and below words
+func (v Version) Compare(other Version) int {
+	for _, d := range []int{v.Major - other.Major, v.Minor - other.Minor, v.Patch - other.Patch} {
+		if d != 0 {
+			return sign(d)
+		}
+	}
+	switch {
+	case v.PreRelease == other.PreRelease:
+		return 0
+	case v.PreRelease == "":
+		return 1
+	case other.PreRelease == "":
+		return -1
+	}
+
+	a, b := strings.Split(v.PreRelease, "."), strings.Split(other.PreRelease, ".")
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] == b[i] {
+			continue
+		}
+		aNum, bNum := isNumeric(a[i]), isNumeric(b[i])
+		switch {
+		case aNum && bNum:
+			x, _ := strconv.Atoi(a[i])
+			y, _ := strconv.Atoi(b[i])
+			return sign(x - y)
+		case aNum:
+			return -1
+		case bNum:
+			return 1
+		}
+		return strings.Compare(a[i], b[i])
+	}
+	return sign(len(a) - len(b))
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
+
+// String formats the version as major.minor, adding the patch if it is set
+// or the version is a pre-release
+func (v Version) String() string {
+	if v.PreRelease != "" {
+		return fmt.Sprintf("%d.%d.%d-%s", v.Major, v.Minor, v.Patch, v.PreRelease)
+	}
+	if v.Patch != 0 {
+		return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	}
+	return fmt.Sprintf("%d.%d", v.Major, v.Minor)
+}
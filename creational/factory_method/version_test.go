@@ -0,0 +1,214 @@
+package factorymethod_test
+
+import (
+	"errors"
+	"testing"
+
+	fm "go-design-patterns/creational/factory_method"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    fm.Version
+		wantErr bool
+	}{
+		{in: "13", want: fm.Version{Major: 13}},
+		{in: "17.1", want: fm.Version{Major: 17, Minor: 1}},
+		{in: "17.0.3", want: fm.Version{Major: 17, Patch: 3}},
+		{in: "18.0.0-beta.2", want: fm.Version{Major: 18, PreRelease: "beta.2"}},
+		{in: "1.0.0-x-y.0", want: fm.Version{Major: 1, PreRelease: "x-y.0"}},
+		{in: "14.0.0+build.5", want: fm.Version{Major: 14}},
+		{in: "1.2.3.4", wantErr: true},
+		{in: "01.2", wantErr: true},
+		{in: "-1", wantErr: true},
+		{in: "1.2.", wantErr: true},
+		{in: "1.0.0-", wantErr: true},
+		{in: "1.0.0-beta..1", wantErr: true},
+		{in: "1.0.0-01", wantErr: true},
+		{in: "1.0.0+", wantErr: true},
+		{in: "banana", wantErr: true},
+		{in: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := fm.ParseVersion(tt.in)
+		if tt.wantErr {
+			if !errors.Is(err, fm.ErrInvalidVersion) {
+				t.Errorf("ParseVersion(%q) = %+v, %v; want ErrInvalidVersion", tt.in, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ParseVersion(%q) = %+v, %v; want %+v", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestVersionCompare(t *testing.T) {
+	// Each version is older than the next, as listed by semver 2.0
+	ordered := []string{
+		"1.0.0-alpha",
+		"1.0.0-alpha.1",
+		"1.0.0-alpha.beta",
+		"1.0.0-beta",
+		"1.0.0-beta.2",
+		"1.0.0-beta.11",
+		"1.0.0-rc.1",
+		"1.0.0",
+		"1.0.1",
+		"1.1.0",
+		"2.0.0",
+		"13.0.0",
+		"13.0.10",
+		"13.1",
+	}
+	parse := func(s string) fm.Version {
+		t.Helper()
+		v, err := fm.ParseVersion(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	for i := range ordered {
+		for j := range ordered {
+			want := 0
+			switch {
+			case i < j:
+				want = -1
+			case i > j:
+				want = 1
+			}
+			if got := parse(ordered[i]).Compare(parse(ordered[j])); got != want {
+				t.Errorf("%s.Compare(%s) = %d, want %d", ordered[i], ordered[j], got, want)
+			}
+		}
+	}
+
+	equal := [][2]string{
+		{"13", "13.0.0"},
+		{"17.1", "17.1.0"},
+		{"14.0.0+build.1", "14.0.0+build.2"},
+		{"14.0.0-rc.1+exp", "14.0.0-rc.1"},
+	}
+	for _, pair := range equal {
+		if got := parse(pair[0]).Compare(parse(pair[1])); got != 0 {
+			t.Errorf("%s.Compare(%s) = %d, want 0", pair[0], pair[1], got)
+		}
+	}
+}
+
+func TestVersionString(t *testing.T) {
+	tests := map[fm.Version]string{
+		{Major: 13}:                             "13.0",
+		{Major: 17, Minor: 1}:                   "17.1",
+		{Major: 17, Patch: 3}:                   "17.0.3",
+		{Major: 18, PreRelease: "beta.2"}:       "18.0.0-beta.2",
+		{Major: 15, Patch: 1, PreRelease: "rc"}: "15.0.1-rc",
+	}
+	for v, want := range tests {
+		if got := v.String(); got != want {
+			t.Errorf("%+v.String() = %q, want %q", v, got, want)
+		}
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	android := func(ram fm.ByteSize, cores, major int) fm.MobileDevice {
+		return fm.NewAndroidDevice(fm.DeviceSpecs{
+			RAM:      ram,
+			Storage:  128 * fm.GB,
+			CPU:      fm.CPU{Family: fm.GenericCPU, Cores: cores},
+			Platform: fm.Platform{Name: "Android", Version: fm.Version{Major: major}},
+		})
+	}
+	iphone := func(ram fm.ByteSize, generation, major int) fm.MobileDevice {
+		d := fm.NewIosDevice(fm.DeviceSpecs{
+			RAM:      ram,
+			Storage:  128 * fm.GB,
+			CPU:      fm.CPU{Family: fm.AppleA, Generation: generation},
+			Platform: fm.Platform{Name: "iOS", Version: fm.Version{Major: major}},
+		})
+		d.SetAppleID("tester@example.com")
+		return d
+	}
+	unlisted := fm.NewAndroidDevice(fm.DeviceSpecs{
+		RAM:      1 * fm.GB,
+		Storage:  8 * fm.GB,
+		CPU:      fm.CPU{Family: fm.GenericCPU, Cores: 2},
+		Platform: fm.Platform{Name: "Android Go", Version: fm.Version{Major: 12}},
+	})
+
+	tests := []struct {
+		name    string
+		device  fm.MobileDevice
+		to      string
+		want    string
+		wantErr error
+	}{
+		{name: "next release", device: android(8*fm.GB, 8, 13), to: "14", want: "Android 14.0"},
+		{name: "patch of a release", device: android(8*fm.GB, 8, 13), to: "14.0.2", want: "Android 14.0.2"},
+		{name: "pre-release of a release", device: android(8*fm.GB, 8, 14), to: "15.0.0-beta.1", want: "Android 15.0.0-beta.1"},
+		{name: "iOS release", device: iphone(6*fm.GB, 15, 16), to: "17.1", want: "iOS 17.1"},
+		{name: "platform without upgrade paths", device: unlisted, to: "99", want: "Android Go 99.0"},
+		{name: "invalid version", device: android(8*fm.GB, 8, 13), to: "banana", wantErr: fm.ErrInvalidVersion},
+		{name: "same version", device: android(8*fm.GB, 8, 13), to: "13.0.0", wantErr: fm.ErrAlreadyInstalled},
+		{name: "downgrade", device: android(8*fm.GB, 8, 13), to: "12", wantErr: fm.ErrDowngrade},
+		{name: "pre-release of the installed version", device: android(8*fm.GB, 8, 13), to: "13.0.0-rc.1", wantErr: fm.ErrDowngrade},
+		{name: "unlisted release", device: android(8*fm.GB, 8, 13), to: "16", wantErr: fm.ErrUnsupportedVersion},
+		{name: "Android below MinFrom", device: android(8*fm.GB, 8, 12), to: "15", wantErr: fm.ErrNoUpgradePath},
+		{name: "iOS below MinFrom", device: iphone(6*fm.GB, 15, 15), to: "18", wantErr: fm.ErrNoUpgradePath},
+		{name: "too little RAM", device: android(2*fm.GB, 8, 13), to: "14", wantErr: fm.ErrHardwareTooOld},
+		{name: "too few cores", device: android(8*fm.GB, 4, 13), to: "14", wantErr: fm.ErrHardwareTooOld},
+		{name: "Apple CPU too old", device: iphone(6*fm.GB, 11, 16), to: "17", wantErr: fm.ErrHardwareTooOld},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := tt.device.GetPlatform()
+			err := tt.device.Update(tt.to)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) || !errors.Is(err, fm.ErrUpdateNotAllowed) {
+					t.Fatalf("Update(%q) = %v, want %v and ErrUpdateNotAllowed", tt.to, err, tt.wantErr)
+				}
+				var updateErr *fm.UpdateError
+				if !errors.As(err, &updateErr) || updateErr.To != tt.to {
+					t.Errorf("Update(%q) = %v, want an UpdateError to %q", tt.to, err, tt.to)
+				}
+				if got := tt.device.GetPlatform(); got != before {
+					t.Errorf("refused update changed the platform from %s to %s", before, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Update(%q) = %v", tt.to, err)
+			}
+			if got := tt.device.GetPlatform(); got != tt.want {
+				t.Errorf("platform %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateWithCustomPaths(t *testing.T) {
+	device := fm.NewAndroidDevice(fm.DeviceSpecs{
+		RAM:      8 * fm.GB,
+		Storage:  128 * fm.GB,
+		CPU:      fm.CPU{Family: fm.GenericCPU, Cores: 8},
+		Platform: fm.Platform{Name: "Android", Version: fm.Version{Major: 13}},
+	})
+	device.SetUpgradePaths(fm.UpgradePaths{"Android": {{Version: fm.Version{Major: 14, Minor: 1}}}})
+	if err := device.Update("14"); !errors.Is(err, fm.ErrUnsupportedVersion) {
+		t.Errorf("Update to a version below the listed minor = %v, want ErrUnsupportedVersion", err)
+	}
+	if err := device.Update("14.2"); err != nil {
+		t.Errorf("Update to a later minor of a listed release = %v", err)
+	}
+
+	device.SetUpgradePaths(nil)
+	if err := device.Update("20"); err != nil {
+		t.Errorf("Update without upgrade paths = %v", err)
+	}
+	if err := device.Update("19"); !errors.Is(err, fm.ErrDowngrade) {
+		t.Errorf("downgrade without upgrade paths = %v, want ErrDowngrade", err)
+	}
+}
@@ -0,0 +1,270 @@
+package facade
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrInvalidCredentials is returned for an unknown account or a wrong PIN.
+	ErrInvalidCredentials = fmt.Errorf("%w: invalid account details", ErrAuthFailed)
+	// ErrAccountLocked is returned once an account exceeded its failed attempts.
+	ErrAccountLocked = fmt.Errorf("%w: account locked", ErrAuthFailed)
+	// ErrInvalidSession is returned for a token that was never issued or was revoked.
+	ErrInvalidSession = fmt.Errorf("%w: invalid session token", ErrNotLoggedIn)
+	// ErrSessionExpired is returned for a token past its expiry time.
+	ErrSessionExpired = fmt.Errorf("%w: session expired", ErrNotLoggedIn)
+)
+
+const (
+	defaultMaxAttempts = 3
+	defaultLockout     = 15 * time.Minute
+	defaultTokenTTL    = 15 * time.Minute
+)
+
+// Session is an authenticated login issued by an Authenticator.
+type Session struct {
+	Token     string
+	Bank      string
+	AccountNo string
+	ExpiresAt time.Time
+}
+
+// AuthOption configures an Authenticator.
+type AuthOption func(*Authenticator)
+
+// WithMaxAttempts sets how many consecutive failed logins lock an account.
+func WithMaxAttempts(n int) AuthOption {
+	return func(a *Authenticator) {
+		a.maxAttempts = n
+	}
+}
+
+// WithLockout sets how long an account stays locked after too many failed
+// logins. Zero keeps it locked until Unlock.
+func WithLockout(d time.Duration) AuthOption {
+	return func(a *Authenticator) {
+		a.lockout = d
+	}
+}
+
+// WithPinHashIterations sets the PBKDF2 cost of the PIN hashes Enroll
+// writes. Logins rehash credentials of a lower cost. Lowering it below the
+// default is only meant for tests that log in many times.
+func WithPinHashIterations(n int) AuthOption {
+	return func(a *Authenticator) {
+		a.pinHashIterations = n
+	}
+}
+
+// WithTokenTTL sets how long issued session tokens stay valid.
+func WithTokenTTL(ttl time.Duration) AuthOption {
+	return func(a *Authenticator) {
+		a.tokenTTL = ttl
+	}
+}
+
+// WithAuthClock replaces time.Now, mainly to control token expiry in tests.
+func WithAuthClock(now func() time.Time) AuthOption {
+	return func(a *Authenticator) {
+		a.now = now
+	}
+}
+
+// Authenticator verifies PINs against a CredentialStore, locks accounts for
+// a while after too many failures and issues random, expiring session
+// tokens.
+type Authenticator struct {
+	store             CredentialStore
+	maxAttempts       int
+	lockout           time.Duration
+	pinHashIterations int
+	tokenTTL          time.Duration
+	now               func() time.Time
+
+	mu       sync.Mutex
+	sessions map[string]Session
+}
+
+// NewAuthenticator creates an Authenticator backed by store.
+func NewAuthenticator(store CredentialStore, opts ...AuthOption) *Authenticator {
+	a := &Authenticator{
+		store:             store,
+		maxAttempts:       defaultMaxAttempts,
+		lockout:           defaultLockout,
+		pinHashIterations: defaultPinHashIterations,
+		tokenTTL:          defaultTokenTTL,
+		now:               time.Now,
+		sessions:          make(map[string]Session),
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+// Enroll stores a new credential for the account, replacing any existing one.
+func (a *Authenticator) Enroll(bank, accountNo, pin string) error {
+	cred, err := newCredential(bank, accountNo, pin, a.pinHashIterations)
+	if err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.store.Put(cred)
+}
+
+// Login checks the PIN and returns a new session on success. A lockout that
+// has ended is lifted before the PIN is checked.
+func (a *Authenticator) Login(bank, accountNo, pin string) (Session, error) {
+	cred, err := a.credential(bank, accountNo)
+	if err != nil {
+		return Session{}, err
+	}
+	if a.locked(cred) {
+		return Session{}, ErrAccountLocked
+	}
+	// Hash outside the lock: the KDF is slow on purpose, and holding the
+	// lock would serialise the logins of every account behind it
+	matches := cred.Matches(pin)
+
+	// Serialise the bookkeeping so concurrent failures are all counted
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	current, err := a.credential(bank, accountNo)
+	if err != nil {
+		return Session{}, err
+	}
+	if current.Salt != cred.Salt || current.PinHash != cred.PinHash {
+		// Re-enrolled or rehashed meanwhile
+		matches = current.Matches(pin)
+	}
+	cred = current
+	if a.locked(cred) {
+		return Session{}, ErrAccountLocked
+	}
+	changed := false
+	if cred.Locked {
+		cred.Locked, cred.LockedUntil, cred.FailedAttempts = false, time.Time{}, 0
+		changed = true
+	}
+
+	if !matches {
+		cred.FailedAttempts++
+		if a.maxAttempts > 0 && cred.FailedAttempts >= a.maxAttempts {
+			cred.Locked = true
+			if a.lockout > 0 {
+				cred.LockedUntil = a.now().Add(a.lockout)
+			}
+		}
+		if err := a.store.Put(cred); err != nil {
+			return Session{}, fmt.Errorf("record failed attempt: %w", err)
+		}
+		if cred.Locked {
+			return Session{}, ErrAccountLocked
+		}
+		return Session{}, ErrInvalidCredentials
+	}
+
+	if cred.FailedAttempts > 0 {
+		cred.FailedAttempts = 0
+		changed = true
+	}
+	if cred.Iterations < a.pinHashIterations {
+		rehashed, err := newCredential(bank, accountNo, pin, a.pinHashIterations)
+		if err != nil {
+			return Session{}, err
+		}
+		cred.Salt, cred.PinHash, cred.Iterations = rehashed.Salt, rehashed.PinHash, rehashed.Iterations
+		changed = true
+	}
+	if changed {
+		if err := a.store.Put(cred); err != nil {
+			return Session{}, fmt.Errorf("update credential: %w", err)
+		}
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return Session{}, err
+	}
+	session := Session{
+		Token:     token,
+		Bank:      bank,
+		AccountNo: accountNo,
+		ExpiresAt: a.now().Add(a.tokenTTL),
+	}
+	a.sessions[token] = session
+	return session, nil
+}
+
+// credential loads the credential of an account.
+func (a *Authenticator) credential(bank, accountNo string) (Credential, error) {
+	cred, err := a.store.Get(bank, accountNo)
+	if errors.Is(err, ErrCredentialNotFound) {
+		return Credential{}, ErrInvalidCredentials
+	}
+	if err != nil {
+		return Credential{}, fmt.Errorf("load credential: %w", err)
+	}
+	return cred, nil
+}
+
+// locked reports whether the lockout of cred is still in force.
+func (a *Authenticator) locked(cred Credential) bool {
+	return cred.Locked && (cred.LockedUntil.IsZero() || a.now().Before(cred.LockedUntil))
+}
+
+// Validate returns the session for token if it is known and not expired.
+func (a *Authenticator) Validate(token string) (Session, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	session, exists := a.sessions[token]
+	if !exists {
+		return Session{}, ErrInvalidSession
+	}
+	if !a.now().Before(session.ExpiresAt) {
+		delete(a.sessions, token)
+		return Session{}, ErrSessionExpired
+	}
+	return session, nil
+}
+
+// Logout revokes token. Unknown tokens are ignored.
+func (a *Authenticator) Logout(token string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	delete(a.sessions, token)
+}
+
+// Unlock clears the lockout and failed-attempt counter of an account.
+func (a *Authenticator) Unlock(bank, accountNo string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	cred, err := a.store.Get(bank, accountNo)
+	if err != nil {
+		return err
+	}
+	cred.Locked = false
+	cred.LockedUntil = time.Time{}
+	cred.FailedAttempts = 0
+	return a.store.Put(cred)
+}
+
+func newToken() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate token: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
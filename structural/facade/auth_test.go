@@ -0,0 +1,179 @@
+package facade_test
+
+import (
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"go-design-patterns/structural/facade"
+)
+
+// testClock is a settable clock for Authenticator options.
+type testClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newTestClock() *testClock {
+	return &testClock{now: time.Date(2024, time.March, 1, 9, 0, 0, 0, time.UTC)}
+}
+
+func (c *testClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *testClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func newTestAuthenticator(t *testing.T, store facade.CredentialStore, opts ...facade.AuthOption) *facade.Authenticator {
+	t.Helper()
+	auth := facade.NewAuthenticator(store, append([]facade.AuthOption{facade.WithPinHashIterations(1000)}, opts...)...)
+	if err := auth.Enroll("KBank", "0123456789", "1234"); err != nil {
+		t.Fatal(err)
+	}
+	return auth
+}
+
+func TestLoginLockoutExpires(t *testing.T) {
+	clock := newTestClock()
+	auth := newTestAuthenticator(t, facade.NewMemoryCredentialStore(),
+		facade.WithAuthClock(clock.Now), facade.WithMaxAttempts(3), facade.WithLockout(10*time.Minute))
+
+	for i := 1; i <= 2; i++ {
+		if _, err := auth.Login("KBank", "0123456789", "0000"); !errors.Is(err, facade.ErrInvalidCredentials) {
+			t.Fatalf("failed login %d = %v, want ErrInvalidCredentials", i, err)
+		}
+	}
+	if _, err := auth.Login("KBank", "0123456789", "0000"); !errors.Is(err, facade.ErrAccountLocked) {
+		t.Fatalf("third failed login = %v, want ErrAccountLocked", err)
+	}
+	if _, err := auth.Login("KBank", "0123456789", "1234"); !errors.Is(err, facade.ErrAccountLocked) {
+		t.Fatalf("correct PIN while locked = %v, want ErrAccountLocked", err)
+	}
+	if !errors.Is(facade.ErrAccountLocked, facade.ErrAuthFailed) {
+		t.Error("ErrAccountLocked does not match ErrAuthFailed")
+	}
+
+	clock.Advance(10*time.Minute - time.Second)
+	if _, err := auth.Login("KBank", "0123456789", "1234"); !errors.Is(err, facade.ErrAccountLocked) {
+		t.Fatalf("correct PIN just before the lockout ends = %v, want ErrAccountLocked", err)
+	}
+	clock.Advance(time.Second)
+	if _, err := auth.Login("KBank", "0123456789", "1234"); err != nil {
+		t.Fatalf("correct PIN after the lockout ended = %v", err)
+	}
+
+	// The failed attempts were reset along with the lockout
+	for i := 1; i <= 2; i++ {
+		if _, err := auth.Login("KBank", "0123456789", "0000"); !errors.Is(err, facade.ErrInvalidCredentials) {
+			t.Fatalf("failed login %d after the lockout = %v, want ErrInvalidCredentials", i, err)
+		}
+	}
+}
+
+func TestLoginLockoutUntilUnlock(t *testing.T) {
+	clock := newTestClock()
+	auth := newTestAuthenticator(t, facade.NewMemoryCredentialStore(),
+		facade.WithAuthClock(clock.Now), facade.WithMaxAttempts(1), facade.WithLockout(0))
+
+	if _, err := auth.Login("KBank", "0123456789", "0000"); !errors.Is(err, facade.ErrAccountLocked) {
+		t.Fatalf("failed login = %v, want ErrAccountLocked", err)
+	}
+	clock.Advance(365 * 24 * time.Hour)
+	if _, err := auth.Login("KBank", "0123456789", "1234"); !errors.Is(err, facade.ErrAccountLocked) {
+		t.Fatalf("login a year later = %v, want ErrAccountLocked", err)
+	}
+	if err := auth.Unlock("KBank", "0123456789"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := auth.Login("KBank", "0123456789", "1234"); err != nil {
+		t.Errorf("login after Unlock = %v", err)
+	}
+}
+
+func TestLoginLockoutIsPersisted(t *testing.T) {
+	clock := newTestClock()
+	path := filepath.Join(t.TempDir(), "credentials.json")
+	store, err := facade.NewFileCredentialStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth := newTestAuthenticator(t, store, facade.WithAuthClock(clock.Now), facade.WithMaxAttempts(1))
+	if _, err := auth.Login("KBank", "0123456789", "0000"); !errors.Is(err, facade.ErrAccountLocked) {
+		t.Fatalf("failed login = %v, want ErrAccountLocked", err)
+	}
+
+	reopened, err := facade.NewFileCredentialStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cred, err := reopened.Get("KBank", "0123456789")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cred.Locked || !cred.LockedUntil.Equal(clock.Now().Add(15*time.Minute)) {
+		t.Errorf("stored lockout %t until %s, want the default 15 minutes", cred.Locked, cred.LockedUntil)
+	}
+	if cred.PinHash == "" || cred.Iterations != 1000 {
+		t.Errorf("stored hash %q with %d iterations", cred.PinHash, cred.Iterations)
+	}
+}
+
+func TestSessionTokenTTL(t *testing.T) {
+	clock := newTestClock()
+	auth := newTestAuthenticator(t, facade.NewMemoryCredentialStore(),
+		facade.WithAuthClock(clock.Now), facade.WithTokenTTL(time.Minute))
+
+	session, err := auth.Login("KBank", "0123456789", "1234")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := clock.Now().Add(time.Minute); !session.ExpiresAt.Equal(want) {
+		t.Errorf("session expires %s, want %s", session.ExpiresAt, want)
+	}
+
+	clock.Advance(59 * time.Second)
+	if _, err := auth.Validate(session.Token); err != nil {
+		t.Fatalf("Validate before expiry = %v", err)
+	}
+	clock.Advance(time.Second)
+	if _, err := auth.Validate(session.Token); !errors.Is(err, facade.ErrSessionExpired) {
+		t.Fatalf("Validate at expiry = %v, want ErrSessionExpired", err)
+	}
+	if _, err := auth.Validate(session.Token); !errors.Is(err, facade.ErrInvalidSession) {
+		t.Errorf("Validate after the expired token was dropped = %v, want ErrInvalidSession", err)
+	}
+
+	session, err = auth.Login("KBank", "0123456789", "1234")
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth.Logout(session.Token)
+	if _, err := auth.Validate(session.Token); !errors.Is(err, facade.ErrNotLoggedIn) {
+		t.Errorf("Validate after Logout = %v, want ErrNotLoggedIn", err)
+	}
+}
+
+func TestConcurrentFailedLoginsAreCounted(t *testing.T) {
+	auth := newTestAuthenticator(t, facade.NewMemoryCredentialStore(), facade.WithMaxAttempts(5))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			auth.Login("KBank", "0123456789", "0000")
+		}()
+	}
+	wg.Wait()
+	if _, err := auth.Login("KBank", "0123456789", "1234"); !errors.Is(err, facade.ErrAccountLocked) {
+		t.Errorf("login after 5 concurrent failures = %v, want ErrAccountLocked", err)
+	}
+}
@@ -0,0 +1,111 @@
+package facade
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Backend bundles the simulated core-banking services shared by the
+// in-process bank APIs.
+type Backend struct {
+	Auth   *Authenticator
+	Ledger *Ledger
+
+	mu      sync.RWMutex
+	latency map[string]time.Duration
+}
+
+// NewBackend creates a backend with an empty ledger that authenticates
+// against store.
+func NewBackend(store CredentialStore, opts ...AuthOption) *Backend {
+	return &Backend{
+		Auth:   NewAuthenticator(store, opts...),
+		Ledger: NewLedger(),
+	}
+}
+
+// SetLatency makes every call to the bank's in-process API take d, to
+// simulate a slow bank. A zero duration removes the delay.
+func (b *Backend) SetLatency(bank string, d time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.latency == nil {
+		b.latency = make(map[string]time.Duration)
+	}
+	b.latency[bank] = d
+}
+
+// wait blocks for the bank's simulated latency, returning early with
+// ctx.Err() if the context ends first.
+func (b *Backend) wait(ctx context.Context, bank string) error {
+	b.mu.RLock()
+	d := b.latency[bank]
+	b.mu.RUnlock()
+
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// OpenAccount creates the credential and ledger account of a customer and
+// funds it with an opening balance. A zero balance leaves the account empty.
+func (b *Backend) OpenAccount(bank, accountNo, pin string, opening Money) error {
+	if err := b.Auth.Enroll(bank, accountNo, pin); err != nil {
+		return fmt.Errorf("enroll credential: %w", err)
+	}
+
+	id := AccountID{Bank: bank, AccountNo: accountNo}
+	if err := b.Ledger.OpenAccount(id, opening.Currency()); err != nil {
+		return err
+	}
+	if opening.IsPositive() {
+		if _, err := b.Ledger.Deposit(id, opening); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NewDemoBackend creates a backend with the demo accounts used by Example:
+// account 0123456789 with PIN 1234 and 10,000 THB at both SiamBank and
+// KBank, and an empty account 9876543210 with PIN 4321 at KBank. opts
+// configure its authenticator.
+func NewDemoBackend(opts ...AuthOption) (*Backend, error) {
+	backend := NewBackend(NewMemoryCredentialStore(), opts...)
+
+	opening, err := ParseMoney("10000.00", "THB")
+	if err != nil {
+		return nil, err
+	}
+	empty, err := NewMoney(0, "THB")
+	if err != nil {
+		return nil, err
+	}
+
+	accounts := []struct {
+		bank, accountNo, pin string
+		opening              Money
+	}{
+		{"SiamBank", "0123456789", "1234", opening},
+		{"KBank", "0123456789", "1234", opening},
+		{"KBank", "9876543210", "4321", empty},
+	}
+	for _, acc := range accounts {
+		if err := backend.OpenAccount(acc.bank, acc.accountNo, acc.pin, acc.opening); err != nil {
+			return nil, fmt.Errorf("open demo account %s/%s: %w", acc.bank, acc.accountNo, err)
+		}
+	}
+	return backend, nil
+}
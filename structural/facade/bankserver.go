@@ -0,0 +1,614 @@
+package facade
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// httpErrorCodes maps bank errors to HTTP statuses and wire codes. The
+// client uses the same table to turn codes back into sentinel errors, so
+// specific errors come before the classes they belong to.
+var httpErrorCodes = []struct {
+	err    error
+	status int
+	code   string
+}{
+	{ErrInvalidCredentials, http.StatusUnauthorized, "invalid_credentials"},
+	{ErrAccountLocked, http.StatusLocked, "account_locked"},
+	{ErrAuthFailed, http.StatusUnauthorized, "auth_failed"},
+	{ErrInvalidSession, http.StatusUnauthorized, "invalid_session"},
+	{ErrSessionExpired, http.StatusUnauthorized, "session_expired"},
+	{ErrNotLoggedIn, http.StatusUnauthorized, "not_logged_in"},
+	{ErrInvalidConfirmation, http.StatusConflict, "invalid_confirmation"},
+	{ErrDuplicateCredit, http.StatusConflict, "duplicate_credit"},
+	{ErrUnknownCredit, http.StatusNotFound, "unknown_credit"},
+	{ErrInsufficientFunds, http.StatusUnprocessableEntity, "insufficient_funds"},
+	{ErrAccountNotFound, http.StatusNotFound, "account_not_found"},
+	{ErrCurrencyMismatch, http.StatusBadRequest, "currency_mismatch"},
+	{ErrNonPositiveAmount, http.StatusBadRequest, "invalid_amount"},
+	{ErrInvalidAmount, http.StatusBadRequest, "invalid_amount"},
+	{context.DeadlineExceeded, http.StatusGatewayTimeout, "timeout"},
+	{context.Canceled, http.StatusGatewayTimeout, "timeout"},
+}
+
+// Wire codes that are produced by the server itself rather than a bank error.
+const (
+	codeBadRequest  = "bad_request"
+	codeRateLimited = "rate_limited"
+	codeForbidden   = "forbidden"
+	codeUnavailable = "unavailable"
+	codeInternal    = "internal"
+)
+
+type errorBody struct {
+	Code    string `json:"code"`
+	Message string `json:"error"`
+}
+
+type loginRequest struct {
+	AccountNo string `json:"account_no"`
+	Pin       string `json:"pin"`
+}
+
+type loginResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type transferRequest struct {
+	ToAccount string `json:"to_account"`
+	ToBank    string `json:"to_bank"`
+	Amount    Money  `json:"amount"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+type confirmationRequest struct {
+	ConfirmationToken string `json:"confirmation_token,omitempty"`
+	RequestID         string `json:"request_id,omitempty"`
+}
+
+type balanceResponse struct {
+	Balance Money `json:"balance"`
+}
+
+type feeRequest struct {
+	Ref    string `json:"ref"`
+	Amount Money  `json:"amount"`
+}
+
+type creditRequest struct {
+	AccountNo string `json:"account_no"`
+	Ref       string `json:"ref"`
+	Amount    *Money `json:"amount,omitempty"`
+}
+
+// defaultServerSessionTTL bounds sessions whose bank does not say when
+// they end.
+const defaultServerSessionTTL = 30 * time.Minute
+
+// ServerOption configures a BankServer.
+type ServerOption func(*BankServer) error
+
+// WithServerLatency delays every request by d before it is handled.
+func WithServerLatency(d time.Duration) ServerOption {
+	return func(s *BankServer) error {
+		if d < 0 {
+			return errors.New("server latency must not be negative")
+		}
+		s.latency = d
+		return nil
+	}
+}
+
+// WithRateLimit allows at most requests calls per window and answers the
+// rest with 429 Too Many Requests.
+func WithRateLimit(requests int, window time.Duration) ServerOption {
+	return func(s *BankServer) error {
+		if requests < 1 || window <= 0 {
+			return errors.New("rate limit needs at least one request per positive window")
+		}
+		s.limit = requests
+		s.window = window
+		return nil
+	}
+}
+
+// WithServerSessionTTL ends sessions d after login, or earlier if the bank
+// expires them first. Expired sessions are purged from the server.
+func WithServerSessionTTL(d time.Duration) ServerOption {
+	return func(s *BankServer) error {
+		if d <= 0 {
+			return errors.New("server session TTL must be positive")
+		}
+		s.sessionTTL = d
+		return nil
+	}
+}
+
+// WithServerClock replaces the clock used for rate limits and session
+// expiry, e.g. in tests.
+func WithServerClock(now func() time.Time) ServerOption {
+	return func(s *BankServer) error {
+		if now == nil {
+			return errors.New("server clock must not be nil")
+		}
+		s.now = now
+		return nil
+	}
+}
+
+// WithClearingAccount lets only sessions of accountNo call /credit and
+// /reverse, so customers cannot pay themselves out of settlement.
+func WithClearingAccount(accountNo string) ServerOption {
+	return func(s *BankServer) error {
+		if accountNo == "" {
+			return errors.New("clearing account must not be empty")
+		}
+		s.clearingAccount = accountNo
+		return nil
+	}
+}
+
+// serverSession is a client logged in to a BankServer.
+type serverSession struct {
+	api       BankApi
+	accountNo string
+	expires   time.Time
+	// transfers maps the request IDs of the session's transfers to their
+	// holds, so a client that lost an answer can still cancel the hold.
+	transfers map[string]*requestedTransfer
+}
+
+// requestedTransfer is the hold placed for a transfer request ID.
+type requestedTransfer struct {
+	confToken string
+	cancelled bool
+}
+
+// transfer returns the entry of requestID, adding it on first use. The
+// server's mutex must be held.
+func (s *serverSession) transfer(requestID string) *requestedTransfer {
+	requested, exists := s.transfers[requestID]
+	if !exists {
+		if s.transfers == nil {
+			s.transfers = make(map[string]*requestedTransfer)
+		}
+		requested = &requestedTransfer{}
+		s.transfers[requestID] = requested
+	}
+	return requested
+}
+
+// BankServer exposes an in-process bank over HTTP so the facade can be
+// exercised against real network behaviour. It is an http.Handler and
+// plugs straight into httptest.NewServer.
+//
+// Endpoints (JSON bodies):
+//
+//	POST /login     {account_no, pin}            -> {token, expires_at}
+//	POST /logout
+//	POST /transfer  {to_account, to_bank, amount, request_id} -> {confirmation_token}
+//	POST /confirm   {confirmation_token}
+//	POST /cancel    {confirmation_token} or {request_id}
+//	POST /fee       {ref, amount}
+//	GET  /balance                                 -> {balance}
+//	POST /credit    {account_no, ref, amount}
+//	POST /reverse   {account_no, ref}
+//
+// All endpoints but /login need an "Authorization: Bearer <token>" header.
+// Sessions end on /logout, when the bank expires them or after the server
+// session TTL. A transfer may carry a client-chosen request ID; cancelling
+// by that ID releases the hold even if the client never saw its token.
+type BankServer struct {
+	bank    string
+	factory BankFactory
+	mux     *http.ServeMux
+
+	mu              sync.Mutex
+	sessions        map[string]*serverSession
+	receivers       map[string]Receiver
+	latency         time.Duration
+	faults          map[string][]int
+	limit           int
+	window          time.Duration
+	windowStart     time.Time
+	windowCount     int
+	sessionTTL      time.Duration
+	clearingAccount string
+	now             func() time.Time
+}
+
+// NewBankServer serves the bank created by factory under the name bank.
+func NewBankServer(bank string, factory BankFactory, opts ...ServerOption) (*BankServer, error) {
+	if factory == nil {
+		return nil, errors.New("bank server needs a bank factory")
+	}
+	s := &BankServer{
+		bank:       bank,
+		factory:    factory,
+		mux:        http.NewServeMux(),
+		sessions:   make(map[string]*serverSession),
+		receivers:  make(map[string]Receiver),
+		faults:     make(map[string][]int),
+		sessionTTL: defaultServerSessionTTL,
+		now:        time.Now,
+	}
+	for _, opt := range opts {
+		if err := opt(s); err != nil {
+			return nil, err
+		}
+	}
+
+	s.mux.HandleFunc("POST /login", s.handleLogin)
+	s.mux.HandleFunc("POST /logout", s.withSession(s.handleLogout))
+	s.mux.HandleFunc("POST /transfer", s.withSession(s.handleTransfer))
+	s.mux.HandleFunc("POST /confirm", s.withSession(s.handleConfirm))
+	s.mux.HandleFunc("POST /cancel", s.withSession(s.handleCancel))
+	s.mux.HandleFunc("POST /fee", s.withSession(s.handleFee))
+	s.mux.HandleFunc("GET /balance", s.withSession(s.handleBalance))
+	s.mux.HandleFunc("POST /credit", s.withSession(s.handleCredit))
+	s.mux.HandleFunc("POST /reverse", s.withSession(s.handleReverse))
+	return s, nil
+}
+
+// SetLatency changes the delay added to every request.
+func (s *BankServer) SetLatency(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.latency = d
+}
+
+// InjectFault makes the next times calls to endpoint (e.g. "/transfer")
+// fail with status before reaching the bank.
+func (s *BankServer) InjectFault(endpoint string, status, times int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := 0; i < times; i++ {
+		s.faults[endpoint] = append(s.faults[endpoint], status)
+	}
+}
+
+func (s *BankServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	latency, fault, limited := s.admit(r.URL.Path)
+	if limited {
+		w.Header().Set("Retry-After", strconv.Itoa(int(s.window.Seconds()+0.5)))
+		writeError(w, http.StatusTooManyRequests, codeRateLimited, "rate limit exceeded")
+		return
+	}
+
+	if latency > 0 {
+		timer := time.NewTimer(latency)
+		defer timer.Stop()
+		select {
+		case <-r.Context().Done():
+			return
+		case <-timer.C:
+		}
+	}
+
+	if fault != 0 {
+		writeError(w, fault, codeUnavailable, "injected fault")
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// admit applies the rate limit and pops a pending fault for the endpoint.
+func (s *BankServer) admit(endpoint string) (time.Duration, int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.limit > 0 {
+		now := s.now()
+		if now.Sub(s.windowStart) >= s.window {
+			s.windowStart = now
+			s.windowCount = 0
+		}
+		if s.windowCount >= s.limit {
+			return 0, 0, true
+		}
+		s.windowCount++
+	}
+
+	fault := 0
+	if pending := s.faults[endpoint]; len(pending) > 0 {
+		fault = pending[0]
+		s.faults[endpoint] = pending[1:]
+	}
+	return s.latency, fault, false
+}
+
+func (s *BankServer) handleLogin(w http.ResponseWriter, r *http.Request) {
+	var req loginRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	api := s.factory(req.AccountNo, s.bank, req.Pin)
+	if err := api.Login(r.Context()); err != nil {
+		writeBankError(w, err)
+		return
+	}
+	token, err := newToken()
+	if err != nil {
+		writeBankError(w, err)
+		return
+	}
+
+	s.mu.Lock()
+	now := s.now()
+	s.purgeSessions(now)
+	session := &serverSession{api: api, accountNo: req.AccountNo, expires: now.Add(s.sessionTTL)}
+	if expirer, ok := api.(sessionExpirer); ok {
+		if expires := expirer.SessionExpiry(); !expires.IsZero() && expires.Before(session.expires) {
+			session.expires = expires
+		}
+	}
+	s.sessions[token] = session
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, loginResponse{Token: token, ExpiresAt: session.expires})
+}
+
+// purgeSessions forgets the sessions that expired by now. s.mu must be held.
+func (s *BankServer) purgeSessions(now time.Time) {
+	for token, session := range s.sessions {
+		if !now.Before(session.expires) {
+			delete(s.sessions, token)
+		}
+	}
+}
+
+func (s *BankServer) handleLogout(w http.ResponseWriter, r *http.Request, session *serverSession) {
+	if logouter, ok := session.api.(sessionLogouter); ok {
+		if err := logouter.Logout(r.Context()); err != nil {
+			writeBankError(w, err)
+			return
+		}
+	}
+
+	s.mu.Lock()
+	delete(s.sessions, bearerToken(r))
+	s.mu.Unlock()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func bearerToken(r *http.Request) string {
+	return strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+}
+
+// withSession resolves the bearer token to its session and forgets
+// sessions that have expired on the server or the bank.
+func (s *BankServer) withSession(next func(http.ResponseWriter, *http.Request, *serverSession)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := bearerToken(r)
+
+		s.mu.Lock()
+		session, exists := s.sessions[token]
+		expired := exists && !s.now().Before(session.expires)
+		s.mu.Unlock()
+
+		if !exists {
+			writeBankError(w, ErrNotLoggedIn)
+			return
+		}
+		if expired || !session.api.IsLoggedIn() {
+			s.mu.Lock()
+			delete(s.sessions, token)
+			s.mu.Unlock()
+			writeBankError(w, ErrSessionExpired)
+			return
+		}
+		next(w, r, session)
+	}
+}
+
+func (s *BankServer) handleTransfer(w http.ResponseWriter, r *http.Request, session *serverSession) {
+	var req transferRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	// A request ID is claimed before the hold is placed, so a cancel that
+	// overtakes the transfer is not lost
+	var requested *requestedTransfer
+	if req.RequestID != "" {
+		s.mu.Lock()
+		requested = session.transfer(req.RequestID)
+		cancelled, confToken := requested.cancelled, requested.confToken
+		s.mu.Unlock()
+
+		switch {
+		case cancelled:
+			writeBankError(w, fmt.Errorf("%w: transfer %s was cancelled", ErrInvalidConfirmation, req.RequestID))
+			return
+		case confToken != "":
+			writeJSON(w, http.StatusOK, confirmationRequest{ConfirmationToken: confToken})
+			return
+		}
+	}
+
+	confToken, err := session.api.Transfer(r.Context(), req.ToAccount, req.ToBank, req.Amount)
+	if err != nil {
+		writeBankError(w, err)
+		return
+	}
+	if requested != nil {
+		s.mu.Lock()
+		cancelled := requested.cancelled
+		requested.confToken = confToken
+		s.mu.Unlock()
+
+		if cancelled {
+			err := session.api.Cancel(context.WithoutCancel(r.Context()), confToken)
+			writeBankError(w, errors.Join(fmt.Errorf("%w: transfer %s was cancelled", ErrInvalidConfirmation, req.RequestID), err))
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, confirmationRequest{ConfirmationToken: confToken})
+}
+
+func (s *BankServer) handleConfirm(w http.ResponseWriter, r *http.Request, session *serverSession) {
+	var req confirmationRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+	if err := session.api.Confirm(r.Context(), req.ConfirmationToken); err != nil {
+		writeBankError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleCancel releases a hold by its token or by the request ID of the
+// transfer that placed it. Cancelling an ID whose transfer has not arrived
+// or not finished makes that transfer release its own hold.
+func (s *BankServer) handleCancel(w http.ResponseWriter, r *http.Request, session *serverSession) {
+	var req confirmationRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	confToken := req.ConfirmationToken
+	if confToken == "" && req.RequestID != "" {
+		s.mu.Lock()
+		requested := session.transfer(req.RequestID)
+		requested.cancelled = true
+		confToken = requested.confToken
+		s.mu.Unlock()
+
+		if confToken == "" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+	if err := session.api.Cancel(r.Context(), confToken); err != nil {
+		writeBankError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *BankServer) handleFee(w http.ResponseWriter, r *http.Request, session *serverSession) {
+	var req feeRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+	collector, ok := session.api.(FeeCollector)
+	if !ok {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if err := collector.CollectFee(r.Context(), req.Ref, req.Amount); err != nil {
+		writeBankError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *BankServer) handleBalance(w http.ResponseWriter, r *http.Request, session *serverSession) {
+	balance, err := session.api.Balance(r.Context())
+	if err != nil {
+		writeBankError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, balanceResponse{Balance: balance})
+}
+
+// clearing reports whether session may credit and reverse, answering
+// 403 Forbidden if not.
+func (s *BankServer) clearing(w http.ResponseWriter, session *serverSession) bool {
+	if s.clearingAccount != "" && session.accountNo != s.clearingAccount {
+		writeError(w, http.StatusForbidden, codeForbidden, "only the clearing account may credit and reverse")
+		return false
+	}
+	return true
+}
+
+func (s *BankServer) handleCredit(w http.ResponseWriter, r *http.Request, session *serverSession) {
+	var req creditRequest
+	if !readJSON(w, r, &req) || !s.clearing(w, session) {
+		return
+	}
+	if req.Amount == nil {
+		writeError(w, http.StatusBadRequest, codeBadRequest, "amount is required")
+		return
+	}
+	receiver, ok := s.factory(req.AccountNo, s.bank, "").(Receiver)
+	if !ok {
+		writeError(w, http.StatusNotImplemented, codeInternal, ErrCannotReceive.Error())
+		return
+	}
+	if err := receiver.Credit(r.Context(), req.Ref, *req.Amount); err != nil {
+		writeBankError(w, err)
+		return
+	}
+
+	s.mu.Lock()
+	s.receivers[req.AccountNo+"\x00"+req.Ref] = receiver
+	s.mu.Unlock()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *BankServer) handleReverse(w http.ResponseWriter, r *http.Request, session *serverSession) {
+	var req creditRequest
+	if !readJSON(w, r, &req) || !s.clearing(w, session) {
+		return
+	}
+	key := req.AccountNo + "\x00" + req.Ref
+
+	s.mu.Lock()
+	receiver, exists := s.receivers[key]
+	s.mu.Unlock()
+
+	if !exists {
+		writeBankError(w, fmt.Errorf("%w: %s", ErrUnknownCredit, req.Ref))
+		return
+	}
+	if err := receiver.Reverse(r.Context(), req.Ref); err != nil {
+		writeBankError(w, err)
+		return
+	}
+
+	s.mu.Lock()
+	delete(s.receivers, key)
+	s.mu.Unlock()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func readJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, codeBadRequest, err.Error())
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, errorBody{Code: code, Message: message})
+}
+
+// writeBankError maps err through httpErrorCodes.
+func writeBankError(w http.ResponseWriter, err error) {
+	for _, entry := range httpErrorCodes {
+		if errors.Is(err, entry.err) {
+			writeError(w, entry.status, entry.code, err.Error())
+			return
+		}
+	}
+	writeError(w, http.StatusInternalServerError, codeInternal, err.Error())
+}
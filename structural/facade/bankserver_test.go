@@ -0,0 +1,218 @@
+package facade_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-design-patterns/structural/facade"
+)
+
+// newTestBankServer serves KBank with the accounts of the demo backend and
+// the clearing account 9000 with PIN 9999.
+func newTestBankServer(t *testing.T, opts ...facade.ServerOption) (*facade.BankServer, *facade.Backend) {
+	t.Helper()
+	backend := facade.NewBackend(facade.NewMemoryCredentialStore(), facade.WithPinHashIterations(1000))
+	for _, acc := range []struct{ accountNo, pin, opening string }{
+		{"0123456789", "1234", "10000.00"},
+		{"9876543210", "4321", "0"},
+		{"9000", "9999", "0"},
+	} {
+		if err := backend.OpenAccount("KBank", acc.accountNo, acc.pin, mustMoney(t, acc.opening, "THB")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	server, err := facade.NewBankServer("KBank", func(accountNo, bank, pin string) facade.BankApi {
+		return facade.NewKBankApi(accountNo, bank, pin, backend)
+	}, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server, backend
+}
+
+// post sends body to the handler and returns the status and error code.
+func post(t *testing.T, handler http.Handler, path, token string, body any) (int, string, []byte) {
+	t.Helper()
+	data, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(data))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	var errBody struct {
+		Code string `json:"code"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &errBody)
+	return rec.Code, errBody.Code, rec.Body.Bytes()
+}
+
+func login(t *testing.T, handler http.Handler, accountNo, pin string) string {
+	t.Helper()
+	status, code, body := post(t, handler, "/login", "", map[string]string{"account_no": accountNo, "pin": pin})
+	if status != http.StatusOK {
+		t.Fatalf("login of %s = %d %s", accountNo, status, code)
+	}
+	var resp struct {
+		Token string `json:"token"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp.Token
+}
+
+func TestBankServerOptions(t *testing.T) {
+	factory := func(accountNo, bank, pin string) facade.BankApi { return nil }
+	for name, opt := range map[string]facade.ServerOption{
+		"zero window":        facade.WithRateLimit(10, 0),
+		"negative window":    facade.WithRateLimit(10, -time.Second),
+		"no requests":        facade.WithRateLimit(0, time.Second),
+		"negative latency":   facade.WithServerLatency(-time.Second),
+		"zero session TTL":   facade.WithServerSessionTTL(0),
+		"nil clock":          facade.WithServerClock(nil),
+		"no clearing number": facade.WithClearingAccount(""),
+	} {
+		if _, err := facade.NewBankServer("KBank", factory, opt); err == nil {
+			t.Errorf("%s was accepted", name)
+		}
+	}
+	if _, err := facade.NewBankServer("KBank", nil); err == nil {
+		t.Error("nil factory was accepted")
+	}
+}
+
+func TestBankServerCreditNeedsClearingSession(t *testing.T) {
+	server, backend := newTestBankServer(t, facade.WithClearingAccount("9000"))
+	credit := map[string]any{"account_no": "9876543210", "ref": "tx-1", "amount": mustMoney(t, "50.00", "THB")}
+	reverse := map[string]any{"account_no": "9876543210", "ref": "tx-1"}
+
+	for _, path := range []string{"/credit", "/reverse"} {
+		if status, code, _ := post(t, server, path, "", credit); status != http.StatusUnauthorized || code != "not_logged_in" {
+			t.Errorf("%s without a session = %d %s, want 401 not_logged_in", path, status, code)
+		}
+	}
+	customer := login(t, server, "0123456789", "1234")
+	for _, path := range []string{"/credit", "/reverse"} {
+		if status, code, _ := post(t, server, path, customer, credit); status != http.StatusForbidden || code != "forbidden" {
+			t.Errorf("%s in a customer session = %d %s, want 403 forbidden", path, status, code)
+		}
+	}
+	wantBalance(t, backend.Ledger, facade.AccountID{Bank: "KBank", AccountNo: "9876543210"}, "0.00")
+
+	clearing := login(t, server, "9000", "9999")
+	if status, code, _ := post(t, server, "/credit", clearing, credit); status != http.StatusNoContent {
+		t.Fatalf("/credit in the clearing session = %d %s", status, code)
+	}
+	wantBalance(t, backend.Ledger, facade.AccountID{Bank: "KBank", AccountNo: "9876543210"}, "50.00")
+	if status, code, _ := post(t, server, "/reverse", clearing, reverse); status != http.StatusNoContent {
+		t.Fatalf("/reverse in the clearing session = %d %s", status, code)
+	}
+	wantBalance(t, backend.Ledger, facade.AccountID{Bank: "KBank", AccountNo: "9876543210"}, "0.00")
+}
+
+func TestBankServerPurgesExpiredSessions(t *testing.T) {
+	clock := newTestClock()
+	server, _ := newTestBankServer(t, facade.WithServerClock(clock.Now), facade.WithServerSessionTTL(time.Minute))
+
+	first := login(t, server, "0123456789", "1234")
+	clock.Advance(59 * time.Second)
+	if status, code, _ := post(t, server, "/fee", first, map[string]any{}); status == http.StatusUnauthorized {
+		t.Fatalf("session used before its TTL = %d %s", status, code)
+	}
+
+	clock.Advance(time.Second)
+	if status, code, _ := post(t, server, "/logout", first, nil); code != "session_expired" {
+		t.Errorf("session used at its TTL = %d %s, want session_expired", status, code)
+	}
+
+	// An expired session nobody uses again is dropped at the next login
+	second := login(t, server, "0123456789", "1234")
+	clock.Advance(time.Minute)
+	login(t, server, "9876543210", "4321")
+	if status, code, _ := post(t, server, "/logout", second, nil); code != "not_logged_in" {
+		t.Errorf("purged session = %d %s, want not_logged_in", status, code)
+	}
+}
+
+// lostAnswers serves /transfer on a context that outlives the client's
+// request and holds back the answer until the client gives up, so the hold
+// is placed but the client never learns its token. done is closed when the
+// transfer handler has finished.
+func lostAnswers(server http.Handler, delay time.Duration, done chan<- struct{}) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/transfer" {
+			server.ServeHTTP(w, r)
+			return
+		}
+		defer close(done)
+		time.Sleep(delay)
+		server.ServeHTTP(httptest.NewRecorder(), r.WithContext(context.WithoutCancel(r.Context())))
+		<-r.Context().Done()
+	})
+}
+
+func TestHTTPTransferTimeoutCancelsHold(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		delay time.Duration
+	}{
+		{name: "hold placed before the cancel", delay: 0},
+		{name: "cancel overtakes the hold", delay: 100 * time.Millisecond},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			bankServer, backend := newTestBankServer(t)
+			done := make(chan struct{})
+			server := httptest.NewServer(lostAnswers(bankServer, tt.delay, done))
+			defer server.Close()
+
+			api := facade.NewHTTPBankApi(server.URL, server.Client(), "0123456789", "KBank", "1234", "THB")
+			if err := api.Login(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			_, err := api.Transfer(ctx, "9876543210", "KBank", mustMoney(t, "100.00", "THB"))
+			if !errors.Is(err, context.DeadlineExceeded) || !facade.IsTransient(err) {
+				t.Errorf("transfer = %v, want a retryable timeout", err)
+			}
+
+			<-done
+			wantBalance(t, backend.Ledger, facade.AccountID{Bank: "KBank", AccountNo: "0123456789"}, "10000.00")
+			if err := backend.Ledger.Verify(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestHTTPTransferTimeoutWithFailedCancelIsUncertain(t *testing.T) {
+	bankServer, backend := newTestBankServer(t)
+	done := make(chan struct{})
+	server := httptest.NewServer(lostAnswers(bankServer, 0, done))
+	defer server.Close()
+
+	api := facade.NewHTTPBankApi(server.URL, server.Client(), "0123456789", "KBank", "1234", "THB")
+	if err := api.Login(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	bankServer.InjectFault("/cancel", http.StatusServiceUnavailable, 1)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := api.Transfer(ctx, "9876543210", "KBank", mustMoney(t, "100.00", "THB"))
+	if !errors.Is(err, facade.ErrTransferUncertain) || facade.IsTransient(err) || errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("transfer = %v, want a non-retryable ErrTransferUncertain", err)
+	}
+
+	<-done
+	wantBalance(t, backend.Ledger, facade.SystemAccount("KBank", "suspense"), "100.00")
+}
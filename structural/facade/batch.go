@@ -0,0 +1,253 @@
+package facade
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// defaultBatchConcurrency bounds the transfers of a batch in flight.
+const defaultBatchConcurrency = 4
+
+// BatchItem is one transfer of a batch.
+type BatchItem struct {
+	// ID identifies the item in the report; it defaults to its position.
+	ID          string
+	FromBank    string
+	FromAccount string
+	Pin         string
+	ToBank      string
+	ToAccount   string
+	Amount      Money
+	// IdempotencyKey, if set, makes re-running the batch safe for this item.
+	IdempotencyKey string
+}
+
+// BatchStatus is the outcome of a batch item.
+type BatchStatus string
+
+const (
+	// BatchSucceeded means the transfer was committed. Its Reason reports
+	// a fee or history that could not be booked after the money moved.
+	BatchSucceeded BatchStatus = "succeeded"
+	// BatchFailed means the transfer was attempted or rejected and did not move money.
+	BatchFailed BatchStatus = "failed"
+	// BatchIncomplete means the transfer failed part way and could not be
+	// undone: a hold or a credit is still in place and needs reconciling.
+	BatchIncomplete BatchStatus = "incomplete"
+	// BatchSkipped means the transfer was never attempted.
+	BatchSkipped BatchStatus = "skipped"
+)
+
+// BatchResult is the outcome of one batch item.
+type BatchResult struct {
+	Index   int         `json:"index"`
+	ID      string      `json:"id"`
+	Status  BatchStatus `json:"status"`
+	Reason  string      `json:"reason,omitempty"`
+	Receipt *Receipt    `json:"receipt,omitempty"`
+	Err     error       `json:"-"`
+}
+
+// BatchSummary totals the outcomes of a batch.
+type BatchSummary struct {
+	Total      int `json:"total"`
+	Succeeded  int `json:"succeeded"`
+	Failed     int `json:"failed"`
+	Incomplete int `json:"incomplete"`
+	Skipped    int `json:"skipped"`
+	// Transferred sums the committed amounts per currency.
+	Transferred []Money       `json:"transferred"`
+	Duration    time.Duration `json:"duration"`
+}
+
+// BatchReport lists the result of every item, in item order, and a summary.
+type BatchReport struct {
+	Results []BatchResult `json:"results"`
+	Summary BatchSummary  `json:"summary"`
+}
+
+// TransferBatch is a set of transfers executed together, such as a payroll run.
+type TransferBatch struct {
+	Items []BatchItem
+	// Concurrency bounds the transfers in flight. Zero means 4.
+	Concurrency int
+}
+
+// batchSession is the shared logged-in API of one source account.
+type batchSession struct {
+	once sync.Once
+	api  BankApi
+	err  error
+}
+
+// batchSessions logs each source account of a batch in once.
+type batchSessions struct {
+	mu       sync.Mutex
+	sessions map[[3]string]*batchSession
+}
+
+func (s *batchSessions) get(ctx context.Context, f *BankTransferFacade, item BatchItem) (BankApi, error) {
+	key := [3]string{item.FromBank, item.FromAccount, item.Pin}
+
+	s.mu.Lock()
+	session, exists := s.sessions[key]
+	if !exists {
+		session = &batchSession{}
+		s.sessions[key] = session
+	}
+	s.mu.Unlock()
+
+	session.once.Do(func() {
+		loginCtx, cancel := f.bankContext(ctx, item.FromBank)
+		defer cancel()
+		if f.sessions != nil {
+			session.api, session.err = f.sessions.Session(loginCtx, item.FromBank, item.FromAccount, item.Pin)
+			if session.err != nil {
+				session.err = newTransferError("", "login", item.FromBank, item.ToBank, StateFailed, session.err)
+			}
+			return
+		}
+
+		factory, err := f.bankFactory(item.FromBank)
+		if err != nil {
+			session.err = err
+			return
+		}
+		api := factory(item.FromAccount, item.FromBank, item.Pin)
+		if err := f.login(loginCtx, api, item.FromBank, item.FromAccount); err != nil {
+			session.err = newTransferError("", "login", item.FromBank, item.ToBank, StateFailed, err)
+			return
+		}
+		session.api = api
+	})
+	return session.api, session.err
+}
+
+// ExecuteBatch runs the transfers of batch with bounded concurrency. Each
+// source account logs in once and its session is shared by all its items;
+// items whose source cannot log in are skipped, as are duplicate item IDs
+// and items not started before ctx ends. A failed item does not stop the others.
+func (f *BankTransferFacade) ExecuteBatch(ctx context.Context, batch TransferBatch) BatchReport {
+	started := f.now()
+	concurrency := batch.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultBatchConcurrency
+	}
+
+	results := make([]BatchResult, len(batch.Items))
+	jobs := make(chan int, len(batch.Items))
+	seen := make(map[string]bool, len(batch.Items))
+	for i, item := range batch.Items {
+		id := item.ID
+		if id == "" {
+			id = fmt.Sprint(i + 1)
+		}
+		results[i] = BatchResult{Index: i, ID: id}
+		if seen[id] {
+			results[i].Status = BatchSkipped
+			results[i].Reason = "duplicate item id"
+			continue
+		}
+		seen[id] = true
+		jobs <- i
+	}
+	close(jobs)
+
+	sessions := &batchSessions{sessions: make(map[[3]string]*batchSession)}
+	var wg sync.WaitGroup
+	for w := 0; w < min(concurrency, len(batch.Items)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = f.executeBatchItem(ctx, sessions, batch.Items[i], results[i])
+			}
+		}()
+	}
+	wg.Wait()
+
+	return BatchReport{Results: results, Summary: summarizeBatch(results, f.now().Sub(started))}
+}
+
+func (f *BankTransferFacade) executeBatchItem(ctx context.Context, sessions *batchSessions, item BatchItem, result BatchResult) BatchResult {
+	if err := ctx.Err(); err != nil {
+		result.Status = BatchSkipped
+		result.Reason = "batch cancelled"
+		result.Err = err
+		return result
+	}
+
+	source, err := sessions.get(ctx, f, item)
+	if err != nil {
+		result.Status = BatchSkipped
+		result.Reason = err.Error()
+		result.Err = err
+		return result
+	}
+
+	opts := []TransferOption{func(o *transferOptions) { o.source = source }}
+	if item.IdempotencyKey != "" {
+		opts = append(opts, WithIdempotencyKey(item.IdempotencyKey))
+	}
+	receipt, err := f.Transfer(ctx, item.FromAccount, item.Pin, item.FromBank, item.ToAccount, item.ToBank, item.Amount, opts...)
+	if receipt.TransactionID != "" {
+		result.Receipt = &receipt
+	}
+	result.Status = batchStatus(receipt, err)
+	if err != nil {
+		result.Reason = err.Error()
+		result.Err = err
+	}
+	return result
+}
+
+// batchStatus classifies a transfer by the state it ended in rather than by
+// its error: a transfer committed before its fee or history failed still
+// moved the money, and one that failed after the hold was placed may not
+// have been undone.
+func batchStatus(receipt Receipt, err error) BatchStatus {
+	if err == nil || receipt.Status == StateCommitted {
+		return BatchSucceeded
+	}
+	var transferErr *TransferError
+	if !errors.As(err, &transferErr) || transferErr.State == "" || transferErr.undone() {
+		return BatchFailed
+	}
+	if transferErr.State == StateCommitted {
+		return BatchSucceeded
+	}
+	return BatchIncomplete
+}
+
+func summarizeBatch(results []BatchResult, elapsed time.Duration) BatchSummary {
+	summary := BatchSummary{Total: len(results), Transferred: []Money{}, Duration: elapsed}
+	transferred := make(map[string]Money)
+	for _, result := range results {
+		switch result.Status {
+		case BatchSucceeded:
+			summary.Succeeded++
+			amount := result.Receipt.Amount
+			if sum, exists := transferred[amount.Currency()]; exists {
+				amount, _ = sum.Add(amount)
+			}
+			transferred[amount.Currency()] = amount
+		case BatchFailed:
+			summary.Failed++
+		case BatchIncomplete:
+			summary.Incomplete++
+		case BatchSkipped:
+			summary.Skipped++
+		}
+	}
+	for _, amount := range transferred {
+		summary.Transferred = append(summary.Transferred, amount)
+	}
+	sort.Slice(summary.Transferred, func(i, j int) bool {
+		return summary.Transferred[i].Currency() < summary.Transferred[j].Currency()
+	})
+	return summary
+}
@@ -0,0 +1,117 @@
+package facade_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go-design-patterns/structural/facade"
+	"go-design-patterns/structural/facade/facadetest"
+)
+
+// failingHistory is a history repository that cannot store receipts.
+type failingHistory struct{}
+
+var errHistoryDown = errors.New("history unavailable")
+
+func (failingHistory) Add(receipt facade.Receipt) error { return errHistoryDown }
+
+func (failingHistory) Query(filter facade.HistoryFilter) ([]facade.Receipt, error) {
+	return nil, errHistoryDown
+}
+
+func TestBatchClassifiesByTransferState(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        []facade.Option
+		alpha, beta []facadetest.Fault
+		want        facade.BatchStatus
+		wantReason  bool
+		wantSource  string
+	}{
+		{
+			name:       "committed",
+			want:       facade.BatchSucceeded,
+			wantSource: "990.00",
+		},
+		{
+			name:       "committed but history not saved",
+			opts:       []facade.Option{facade.WithHistoryRepository(failingHistory{})},
+			want:       facade.BatchSucceeded,
+			wantReason: true,
+			wantSource: "990.00",
+		},
+		{
+			name:       "hold refused",
+			alpha:      []facadetest.Fault{{Op: facade.OpTransfer}},
+			want:       facade.BatchFailed,
+			wantReason: true,
+			wantSource: "1000.00",
+		},
+		{
+			name:       "credit refused and compensated",
+			beta:       []facadetest.Fault{{Op: facade.OpCredit}},
+			want:       facade.BatchFailed,
+			wantReason: true,
+			wantSource: "1000.00",
+		},
+		{
+			name:       "hold cannot be released",
+			alpha:      []facadetest.Fault{{Op: facade.OpCancel}},
+			beta:       []facadetest.Fault{{Op: facade.OpCredit}},
+			want:       facade.BatchIncomplete,
+			wantReason: true,
+			wantSource: "990.00",
+		},
+		{
+			name:       "credit cannot be reversed",
+			alpha:      []facadetest.Fault{{Op: facade.OpConfirm}},
+			beta:       []facadetest.Fault{{Op: facade.OpReverse}},
+			want:       facade.BatchIncomplete,
+			wantReason: true,
+			wantSource: "1000.00",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, err := facadetest.NewHarness(facadetest.StandardBanks(), tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			h.Bank("Alpha").Inject(tt.alpha...)
+			h.Bank("Beta").Inject(tt.beta...)
+
+			from, to := facadetest.AlphaMain, facadetest.BetaMain
+			report := h.Facade.ExecuteBatch(context.Background(), facade.TransferBatch{Items: []facade.BatchItem{{
+				FromBank: from.Bank, FromAccount: from.AccountNo, Pin: from.Pin,
+				ToBank: to.Bank, ToAccount: to.AccountNo, Amount: mustMoney(t, "10.00", "THB"),
+			}}})
+			result := report.Results[0]
+			if result.Status != tt.want {
+				t.Errorf("status %s, want %s (%s)", result.Status, tt.want, result.Reason)
+			}
+			if (result.Reason != "") != tt.wantReason {
+				t.Errorf("reason %q, want one %t", result.Reason, tt.wantReason)
+			}
+
+			summary := report.Summary
+			counts := map[facade.BatchStatus]int{
+				facade.BatchSucceeded:  summary.Succeeded,
+				facade.BatchFailed:     summary.Failed,
+				facade.BatchIncomplete: summary.Incomplete,
+				facade.BatchSkipped:    summary.Skipped,
+			}
+			for status, n := range counts {
+				if want := map[bool]int{true: 1}[status == tt.want]; n != want {
+					t.Errorf("summary counts %d %s, want %d", n, status, want)
+				}
+			}
+			if tt.want == facade.BatchSucceeded && (len(summary.Transferred) != 1 || summary.Transferred[0].Decimal() != "10.00") {
+				t.Errorf("summary transferred %v, want 10.00 THB", summary.Transferred)
+			}
+			if balance, _ := h.Balance(from.Bank, from.AccountNo); balance.Decimal() != tt.wantSource {
+				t.Errorf("source balance %s, want %s", balance.Decimal(), tt.wantSource)
+			}
+		})
+	}
+}
@@ -0,0 +1,257 @@
+package facade
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrCredentialNotFound is returned when no credential exists for an account.
+var ErrCredentialNotFound = errors.New("credential not found")
+
+// defaultPinHashIterations is the PBKDF2 cost of new PIN hashes. A PIN has
+// only 10^4 values, so each guess has to be expensive to slow down an
+// offline search of a leaked credential store.
+const defaultPinHashIterations = 600_000
+
+// Credential is the stored authentication record of a single bank account.
+// The PIN itself is never stored, only a salted PBKDF2-HMAC-SHA256 hash of
+// it.
+type Credential struct {
+	Bank      string `json:"bank"`
+	AccountNo string `json:"account_no"`
+	Salt      string `json:"salt"`
+	PinHash   string `json:"pin_hash"`
+	// Iterations is the PBKDF2 cost of PinHash. Zero marks a hash written
+	// as a single round of SHA-256, which Login replaces on the next
+	// successful login.
+	Iterations     int  `json:"iterations,omitempty"`
+	FailedAttempts int  `json:"failed_attempts"`
+	Locked         bool `json:"locked"`
+	// LockedUntil is when a lockout ends; zero keeps the account locked
+	// until Unlock.
+	LockedUntil time.Time `json:"locked_until,omitempty"`
+}
+
+// NewCredential creates a credential for the account with a fresh random salt.
+func NewCredential(bank, accountNo, pin string) (Credential, error) {
+	return newCredential(bank, accountNo, pin, defaultPinHashIterations)
+}
+
+func newCredential(bank, accountNo, pin string, iterations int) (Credential, error) {
+	if iterations < 1 {
+		return Credential{}, fmt.Errorf("PIN hash needs at least one iteration, got %d", iterations)
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return Credential{}, fmt.Errorf("generate salt: %w", err)
+	}
+	saltHex := hex.EncodeToString(salt)
+	return Credential{
+		Bank:       bank,
+		AccountNo:  accountNo,
+		Salt:       saltHex,
+		PinHash:    hashPin(saltHex, pin, iterations),
+		Iterations: iterations,
+	}, nil
+}
+
+// Matches reports whether pin hashes to the stored PIN hash.
+func (c Credential) Matches(pin string) bool {
+	return subtle.ConstantTimeCompare([]byte(hashPin(c.Salt, pin, c.Iterations)), []byte(c.PinHash)) == 1
+}
+
+// hashPin hashes pin with PBKDF2, or with the single SHA-256 round of
+// credentials written before PBKDF2 when iterations is zero.
+func hashPin(salt, pin string, iterations int) string {
+	if iterations == 0 {
+		sum := sha256.Sum256([]byte(salt + ":" + pin))
+		return hex.EncodeToString(sum[:])
+	}
+	return hex.EncodeToString(pbkdf2SHA256([]byte(pin), []byte(salt), iterations, sha256.Size))
+}
+
+// pbkdf2SHA256 derives keyLen bytes from password and salt with PBKDF2
+// (RFC 8018) using HMAC-SHA256 as the pseudorandom function.
+func pbkdf2SHA256(password, salt []byte, iterations, keyLen int) []byte {
+	prf := hmac.New(sha256.New, password)
+	key := make([]byte, 0, keyLen+sha256.Size)
+	u := make([]byte, 0, sha256.Size)
+	block := make([]byte, sha256.Size)
+	for i := uint32(1); len(key) < keyLen; i++ {
+		prf.Reset()
+		prf.Write(salt)
+		prf.Write(binary.BigEndian.AppendUint32(nil, i))
+		u = prf.Sum(u[:0])
+		copy(block, u)
+		for n := 1; n < iterations; n++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range block {
+				block[j] ^= u[j]
+			}
+		}
+		key = append(key, block...)
+	}
+	return key[:keyLen]
+}
+
+// CredentialStore persists credentials keyed by bank and account number.
+type CredentialStore interface {
+	Get(bank, accountNo string) (Credential, error)
+	Put(cred Credential) error
+}
+
+type credentialKey struct {
+	bank      string
+	accountNo string
+}
+
+// MemoryCredentialStore keeps credentials in memory.
+type MemoryCredentialStore struct {
+	mu    sync.RWMutex
+	creds map[credentialKey]Credential
+}
+
+// NewMemoryCredentialStore creates an empty in-memory credential store.
+func NewMemoryCredentialStore() *MemoryCredentialStore {
+	return &MemoryCredentialStore{creds: make(map[credentialKey]Credential)}
+}
+
+func (s *MemoryCredentialStore) Get(bank, accountNo string) (Credential, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	cred, exists := s.creds[credentialKey{bank, accountNo}]
+	if !exists {
+		return Credential{}, ErrCredentialNotFound
+	}
+	return cred, nil
+}
+
+func (s *MemoryCredentialStore) Put(cred Credential) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.creds[credentialKey{cred.Bank, cred.AccountNo}] = cred
+	return nil
+}
+
+// FileCredentialStore keeps credentials in a JSON file. Every Put rewrites
+// the whole file through a temporary file so a crash never leaves it
+// half-written.
+type FileCredentialStore struct {
+	mu    sync.RWMutex
+	path  string
+	creds map[credentialKey]Credential
+}
+
+// NewFileCredentialStore opens the store at path, loading existing
+// credentials if the file is present.
+func NewFileCredentialStore(path string) (*FileCredentialStore, error) {
+	s := &FileCredentialStore{path: path, creds: make(map[credentialKey]Credential)}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read credential file: %w", err)
+	}
+
+	var list []Credential
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("decode credential file: %w", err)
+	}
+	for _, cred := range list {
+		s.creds[credentialKey{cred.Bank, cred.AccountNo}] = cred
+	}
+	return s, nil
+}
+
+func (s *FileCredentialStore) Get(bank, accountNo string) (Credential, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	cred, exists := s.creds[credentialKey{bank, accountNo}]
+	if !exists {
+		return Credential{}, ErrCredentialNotFound
+	}
+	return cred, nil
+}
+
+func (s *FileCredentialStore) Put(cred Credential) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := credentialKey{cred.Bank, cred.AccountNo}
+	previous, existed := s.creds[key]
+	s.creds[key] = cred
+
+	if err := s.flush(); err != nil {
+		// Keep memory consistent with what is on disk
+		if existed {
+			s.creds[key] = previous
+		} else {
+			delete(s.creds, key)
+		}
+		return err
+	}
+	return nil
+}
+
+// flush writes all credentials to disk. Callers must hold s.mu.
+func (s *FileCredentialStore) flush() error {
+	list := make([]Credential, 0, len(s.creds))
+	for _, cred := range s.creds {
+		list = append(list, cred)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Bank != list[j].Bank {
+			return list[i].Bank < list[j].Bank
+		}
+		return list[i].AccountNo < list[j].AccountNo
+	})
+
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode credential file: %w", err)
+	}
+	return writeFileAtomic(s.path, data)
+}
+
+// writeFileAtomic replaces path with data via a temporary file and rename.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write temp file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close temp file: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0o600); err != nil {
+		return fmt.Errorf("chmod temp file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("replace %s: %w", path, err)
+	}
+	return nil
+}
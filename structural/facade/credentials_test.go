@@ -0,0 +1,47 @@
+package facade
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestPBKDF2SHA256(t *testing.T) {
+	// Test vectors of RFC 7914, section 11
+	tests := []struct {
+		password, salt string
+		iterations     int
+		want           string
+	}{
+		{"passwd", "salt", 1, "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc49ca9cccf179b645991664b39d77ef317c71b845b1e30bd509112041d3a19783"},
+		{"Password", "NaCl", 80000, "4ddcd8f60b98be21830cee5ef22701f9641a4418d04c0414aeff08876b34ab56a1d425a1225833549adb841b51c9b3176a272bdebba1d078478f62b397f33c8d"},
+	}
+	for _, tt := range tests {
+		got := hex.EncodeToString(pbkdf2SHA256([]byte(tt.password), []byte(tt.salt), tt.iterations, 64))
+		if got != tt.want {
+			t.Errorf("PBKDF2(%q, %q, %d) = %s, want %s", tt.password, tt.salt, tt.iterations, got, tt.want)
+		}
+	}
+}
+
+func TestLoginRehashesLegacyCredential(t *testing.T) {
+	store := NewMemoryCredentialStore()
+	legacy := Credential{Bank: "KBank", AccountNo: "0123456789", Salt: "5a17", PinHash: hashPin("5a17", "1234", 0)}
+	if err := store.Put(legacy); err != nil {
+		t.Fatal(err)
+	}
+
+	auth := NewAuthenticator(store, WithPinHashIterations(1000))
+	if _, err := auth.Login("KBank", "0123456789", "1234"); err != nil {
+		t.Fatalf("login with a legacy hash = %v", err)
+	}
+	cred, err := store.Get("KBank", "0123456789")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cred.Iterations != 1000 || cred.PinHash == legacy.PinHash || !cred.Matches("1234") || cred.Matches("4321") {
+		t.Errorf("credential not rehashed: %+v", cred)
+	}
+	if _, err := auth.Login("KBank", "0123456789", "1234"); err != nil {
+		t.Errorf("login after rehash = %v", err)
+	}
+}
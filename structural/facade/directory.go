@@ -0,0 +1,235 @@
+package facade
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	// ErrInvalidAccount is returned for an account number its bank cannot have.
+	ErrInvalidAccount = errors.New("invalid account number")
+	// ErrBankNotListed is returned when the directory has no entry for a bank.
+	ErrBankNotListed = errors.New("bank not in directory")
+)
+
+// AccountError describes why an account number was refused.
+type AccountError struct {
+	Bank      string
+	AccountNo string
+	Reason    string
+}
+
+func (e *AccountError) Error() string {
+	return fmt.Sprintf("%v %s/%s: %s", ErrInvalidAccount, e.Bank, e.AccountNo, e.Reason)
+}
+
+func (e *AccountError) Unwrap() error {
+	return ErrInvalidAccount
+}
+
+// CheckDigit verifies the check digit of an account number made of digits.
+type CheckDigit interface {
+	Valid(digits string) bool
+}
+
+// Mod11 is a weighted modulo 11 check: the last digit must make the
+// weighted sum of all digits a multiple of 11. Weights apply to the digits
+// before the check digit, left to right. Numbers needing a check digit of
+// 10 cannot be issued.
+type Mod11 struct {
+	Weights []int
+}
+
+func (m Mod11) Valid(digits string) bool {
+	if len(digits) != len(m.Weights)+1 {
+		return false
+	}
+	sum := 0
+	for i, weight := range m.Weights {
+		sum += int(digits[i]-'0') * weight
+	}
+	check := (11 - sum%11) % 11
+	return check < 10 && int(digits[len(digits)-1]-'0') == check
+}
+
+// Luhn is the modulo 10 check used by payment card numbers.
+type Luhn struct{}
+
+func (Luhn) Valid(digits string) bool {
+	if digits == "" {
+		return false
+	}
+	sum := 0
+	for i := len(digits) - 1; i >= 0; i-- {
+		digit := int(digits[i] - '0')
+		if (len(digits)-i)%2 == 0 {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+	}
+	return sum%10 == 0
+}
+
+// tenDigitMod11 is the check digit scheme of the built-in banks' 10-digit
+// account numbers.
+var tenDigitMod11 = Mod11{Weights: []int{10, 9, 8, 7, 6, 5, 4, 3, 2}}
+
+// BankInfo is the directory entry of a bank.
+type BankInfo struct {
+	// Name is the name the bank is registered under with the facade.
+	Name string
+	// Code is the bank's clearing code.
+	Code     string
+	FullName string
+	// AccountLength is the number of digits of an account number; zero
+	// allows any length.
+	AccountLength int
+	// CheckDigit, if set, verifies the account numbers of the bank.
+	CheckDigit CheckDigit
+}
+
+// ValidateAccount normalizes accountNo by dropping spaces and dashes and
+// checks it against the format of the bank.
+func (b BankInfo) ValidateAccount(accountNo string) (string, error) {
+	normalized := strings.NewReplacer(" ", "", "-", "").Replace(accountNo)
+	if normalized == "" {
+		return "", &AccountError{Bank: b.Name, AccountNo: accountNo, Reason: "account number is empty"}
+	}
+	for _, r := range normalized {
+		if r < '0' || r > '9' {
+			return "", &AccountError{Bank: b.Name, AccountNo: accountNo, Reason: "account number must contain digits only"}
+		}
+	}
+	if b.AccountLength > 0 && len(normalized) != b.AccountLength {
+		return "", &AccountError{Bank: b.Name, AccountNo: accountNo, Reason: fmt.Sprintf("account number must have %d digits", b.AccountLength)}
+	}
+	if b.CheckDigit != nil && !b.CheckDigit.Valid(normalized) {
+		return "", &AccountError{Bank: b.Name, AccountNo: accountNo, Reason: "check digit mismatch"}
+	}
+	return normalized, nil
+}
+
+// BankDirectory lists known banks by name and clearing code. It is safe for
+// concurrent use.
+type BankDirectory struct {
+	mu     sync.RWMutex
+	byName map[string]BankInfo
+	byCode map[string]string
+}
+
+// NewBankDirectory creates a directory of banks.
+func NewBankDirectory(banks ...BankInfo) (*BankDirectory, error) {
+	d := &BankDirectory{byName: make(map[string]BankInfo), byCode: make(map[string]string)}
+	for _, bank := range banks {
+		if err := d.Add(bank); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+// DefaultBankDirectory lists the built-in SiamBank and KBank.
+func DefaultBankDirectory() *BankDirectory {
+	d, _ := NewBankDirectory(
+		BankInfo{Name: "KBank", Code: "004", FullName: "Kasikornbank", AccountLength: 10, CheckDigit: tenDigitMod11},
+		BankInfo{Name: "SiamBank", Code: "014", FullName: "Siam Commercial Bank", AccountLength: 10, CheckDigit: tenDigitMod11},
+	)
+	return d
+}
+
+// Add lists a bank. Names and codes must be unique.
+func (d *BankDirectory) Add(bank BankInfo) error {
+	if bank.Name == "" || bank.Code == "" {
+		return fmt.Errorf("directory entry needs a name and a code: %+v", bank)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, exists := d.byName[bank.Name]; exists {
+		return fmt.Errorf("bank %q already in directory", bank.Name)
+	}
+	if name, exists := d.byCode[bank.Code]; exists {
+		return fmt.Errorf("bank code %s already used by %s", bank.Code, name)
+	}
+	d.byName[bank.Name] = bank
+	d.byCode[bank.Code] = bank.Name
+	return nil
+}
+
+// Lookup finds a bank by name or clearing code.
+func (d *BankDirectory) Lookup(nameOrCode string) (BankInfo, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if bank, exists := d.byName[nameOrCode]; exists {
+		return bank, nil
+	}
+	if name, exists := d.byCode[nameOrCode]; exists {
+		return d.byName[name], nil
+	}
+	return BankInfo{}, fmt.Errorf("%w: %s", ErrBankNotListed, nameOrCode)
+}
+
+// List returns every bank ordered by code.
+func (d *BankDirectory) List() []BankInfo {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	list := make([]BankInfo, 0, len(d.byName))
+	for _, bank := range d.byName {
+		list = append(list, bank)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Code < list[j].Code
+	})
+	return list
+}
+
+// ValidateAccount checks accountNo against the format of bank and returns
+// it normalized. Banks missing from the directory accept account numbers
+// of digits of any length, with no check digit.
+func (d *BankDirectory) ValidateAccount(bank, accountNo string) (string, error) {
+	d.mu.RLock()
+	info, exists := d.byName[bank]
+	d.mu.RUnlock()
+
+	if !exists {
+		info = BankInfo{Name: bank}
+	}
+	return info.ValidateAccount(accountNo)
+}
+
+// WithBankDirectory validates destination accounts against directory
+// instead of the built-in one.
+func WithBankDirectory(directory *BankDirectory) Option {
+	return func(f *BankTransferFacade) error {
+		if directory == nil {
+			return errors.New("bank directory must not be nil")
+		}
+		f.directory = directory
+		return nil
+	}
+}
+
+// Directory returns the bank directory of the facade.
+func (f *BankTransferFacade) Directory() *BankDirectory {
+	return f.directory
+}
+
+// LookupBank finds a bank of the directory by name or clearing code.
+func (f *BankTransferFacade) LookupBank(nameOrCode string) (BankInfo, error) {
+	return f.directory.Lookup(nameOrCode)
+}
+
+// ValidateAccount checks that accountNo can be an account of bank and
+// returns it normalized, as Transfer does for destination accounts.
+func (f *BankTransferFacade) ValidateAccount(bank, accountNo string) (string, error) {
+	return f.directory.ValidateAccount(bank, accountNo)
+}
@@ -0,0 +1,47 @@
+package facade_test
+
+import (
+	"errors"
+	"testing"
+
+	"go-design-patterns/structural/facade"
+)
+
+func TestValidateAccount(t *testing.T) {
+	directory, err := facade.NewBankDirectory(facade.BankInfo{
+		Name: "KBank", Code: "004", AccountLength: 10, CheckDigit: facade.Luhn{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name      string
+		bank      string
+		accountNo string
+		want      string
+		wantErr   bool
+	}{
+		{name: "listed bank", bank: "KBank", accountNo: "000-000001-8", want: "0000000018"},
+		{name: "listed bank, wrong length", bank: "KBank", accountNo: "18", wantErr: true},
+		{name: "listed bank, check digit mismatch", bank: "KBank", accountNo: "0000000019", wantErr: true},
+		{name: "unlisted bank, any length", bank: "Remote", accountNo: "10 01", want: "1001"},
+		{name: "unlisted bank, no check digit", bank: "Remote", accountNo: "0000000019", want: "0000000019"},
+		{name: "unlisted bank, letters", bank: "Remote", accountNo: "ACC-1001", wantErr: true},
+		{name: "unlisted bank, empty", bank: "Remote", accountNo: " - ", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := directory.ValidateAccount(tt.bank, tt.accountNo)
+			if tt.wantErr {
+				var accountErr *facade.AccountError
+				if !errors.As(err, &accountErr) || !errors.Is(err, facade.ErrInvalidAccount) || accountErr.Bank != tt.bank {
+					t.Errorf("ValidateAccount = %q, %v; want an AccountError of %s", got, err, tt.bank)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("ValidateAccount = %q, %v; want %q", got, err, tt.want)
+			}
+		})
+	}
+}
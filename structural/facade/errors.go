@@ -0,0 +1,69 @@
+package facade
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Error classes shared by all banks. The more specific sentinels of the
+// package wrap them, so errors.Is(err, ErrAuthFailed) also matches
+// ErrInvalidCredentials and ErrAccountLocked.
+var (
+	// ErrUnsupportedBank is returned for a bank the facade cannot talk to.
+	ErrUnsupportedBank = errors.New("unsupported bank")
+	// ErrAuthFailed is returned when a bank refuses to log an account in.
+	ErrAuthFailed = errors.New("authentication failed")
+	// ErrNotLoggedIn is returned for calls that need a session the API does not have.
+	ErrNotLoggedIn = errors.New("please login first")
+	// ErrInvalidConfirmation is returned for an unknown or already used confirmation token.
+	ErrInvalidConfirmation = errors.New("invalid confirmation token")
+	// ErrCannotReceive is returned when the destination bank does not accept transfers.
+	ErrCannotReceive = errors.New("bank cannot receive transfers")
+	// ErrDuplicateCredit is returned when a credit reference is used twice.
+	ErrDuplicateCredit = errors.New("duplicate credit reference")
+	// ErrUnknownCredit is returned when reversing a credit that was never made.
+	ErrUnknownCredit = errors.New("unknown credit reference")
+)
+
+// BankError is a failed call to a bank.
+type BankError struct {
+	Bank string
+	Op   BankOperation
+	// Retryable reports whether repeating the call may succeed, e.g. after
+	// a timeout or while the bank is unavailable.
+	Retryable bool
+	Err       error
+}
+
+func (e *BankError) Error() string {
+	return fmt.Sprintf("bank %s: %v", e.Bank, e.Err)
+}
+
+func (e *BankError) Unwrap() error {
+	return e.Err
+}
+
+// Temporary reports Retryable, so IsTransient and the retry decorator
+// recognise retryable bank errors.
+func (e *BankError) Temporary() bool {
+	return e.Retryable
+}
+
+// newBankError attributes err to a call of bank. Errors already carrying a
+// BankError are returned unchanged.
+func newBankError(bank string, op BankOperation, err error) error {
+	if err == nil {
+		return nil
+	}
+	var bankErr *BankError
+	if errors.As(err, &bankErr) {
+		return err
+	}
+	return &BankError{Bank: bank, Op: op, Retryable: retryable(err), Err: err}
+}
+
+// retryable reports whether err is a failure that may pass on its own.
+func retryable(err error) bool {
+	return IsTransient(err) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, ErrCircuitOpen)
+}
@@ -0,0 +1,50 @@
+package facade_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go-design-patterns/structural/facade"
+	"go-design-patterns/structural/facade/facadetest"
+)
+
+func TestLoginFailuresAreStepErrors(t *testing.T) {
+	ctx := context.Background()
+	h, err := facadetest.NewHarness(facadetest.StandardBanks())
+	if err != nil {
+		t.Fatal(err)
+	}
+	from, to := facadetest.AlphaMain, facadetest.BetaMain
+
+	wantLoginStep := func(t *testing.T, err error) {
+		t.Helper()
+		var transferErr *facade.TransferError
+		if !errors.As(err, &transferErr) {
+			t.Fatalf("error %v is not a *TransferError", err)
+		}
+		if transferErr.Step != "login" || transferErr.Bank != from.Bank {
+			t.Errorf("step %q at bank %q, want login at %s", transferErr.Step, transferErr.Bank, from.Bank)
+		}
+		if !errors.Is(err, facade.ErrAuthFailed) {
+			t.Errorf("error %v does not match ErrAuthFailed", err)
+		}
+	}
+
+	t.Run("balance", func(t *testing.T) {
+		_, err := h.Facade.Balance(ctx, from.AccountNo, "0000", from.Bank)
+		wantLoginStep(t, err)
+	})
+	t.Run("batch", func(t *testing.T) {
+		amount, _ := facade.ParseMoney("10.00", "THB")
+		report := h.Facade.ExecuteBatch(ctx, facade.TransferBatch{Items: []facade.BatchItem{{
+			FromBank: from.Bank, FromAccount: from.AccountNo, Pin: "0000",
+			ToBank: to.Bank, ToAccount: to.AccountNo, Amount: amount,
+		}}})
+		result := report.Results[0]
+		if result.Status != facade.BatchSkipped {
+			t.Fatalf("status %s, want %s", result.Status, facade.BatchSkipped)
+		}
+		wantLoginStep(t, result.Err)
+	})
+}
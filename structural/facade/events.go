@@ -0,0 +1,273 @@
+package facade
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrAuditTampered is returned when an audit log does not match its hash chain.
+var ErrAuditTampered = errors.New("audit log tampered")
+
+// EventType identifies what happened in an Event.
+type EventType string
+
+const (
+	// EventLoginAttempted is emitted after every login to a source bank;
+	// Error is set if it failed.
+	EventLoginAttempted EventType = "login_attempted"
+	// EventTransferInitiated is emitted once the source bank holds the funds.
+	EventTransferInitiated EventType = "transfer_initiated"
+	// EventTransferConfirmed is emitted once a transfer is committed.
+	EventTransferConfirmed EventType = "transfer_confirmed"
+	// EventTransferFailed is emitted when a started transfer ends without
+	// committing; State tells whether it was compensated.
+	EventTransferFailed EventType = "transfer_failed"
+)
+
+// Event is something that happened while the facade worked with the banks.
+type Event struct {
+	Type       EventType     `json:"type"`
+	Time       time.Time     `json:"time"`
+	TransferID string        `json:"transfer_id,omitempty"`
+	Bank       string        `json:"bank"`
+	AccountNo  string        `json:"account_no"`
+	ToBank     string        `json:"to_bank,omitempty"`
+	ToAccount  string        `json:"to_account,omitempty"`
+	Amount     *Money        `json:"amount,omitempty"`
+	Fee        *Money        `json:"fee,omitempty"`
+	Step       string        `json:"step,omitempty"`
+	State      TransferState `json:"state,omitempty"`
+	Error      string        `json:"error,omitempty"`
+}
+
+// EventSink receives events. Sinks are called synchronously from the
+// goroutine making the transfer, so they must be quick and safe for
+// concurrent use.
+type EventSink interface {
+	Emit(event Event)
+}
+
+// EventSinkFunc adapts a function to an EventSink.
+type EventSinkFunc func(Event)
+
+func (fn EventSinkFunc) Emit(event Event) {
+	fn(event)
+}
+
+// WithEventSink sends the facade's events to sink. It can be used several
+// times; sinks receive events in the order they were added.
+func WithEventSink(sink EventSink) Option {
+	return func(f *BankTransferFacade) error {
+		if sink == nil {
+			return errors.New("event sink must not be nil")
+		}
+		f.sinks = append(f.sinks, sink)
+		return nil
+	}
+}
+
+// emit stamps event and hands it to every sink.
+func (f *BankTransferFacade) emit(event Event) {
+	if len(f.sinks) == 0 {
+		return
+	}
+	event.Time = f.now()
+	for _, sink := range f.sinks {
+		sink.Emit(event)
+	}
+}
+
+// login logs api in within the timeout of bank and reports the attempt.
+// Managed sources are not reported: the session manager reports the logins
+// it actually makes and cached sessions need none.
+func (f *BankTransferFacade) login(ctx context.Context, api BankApi, bank, accountNo string) error {
+	loginCtx, cancel := f.bankContext(ctx, bank)
+	defer cancel()
+
+	err := api.Login(loginCtx)
+	if _, managed := api.(*managedBankApi); managed {
+		return err
+	}
+	event := Event{Type: EventLoginAttempted, Bank: bank, AccountNo: accountNo}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	f.emit(event)
+	return err
+}
+
+// transferEvent describes the transfer of record.
+func transferEvent(eventType EventType, record TransferRecord) Event {
+	amount, fee := record.Amount, record.Fee
+	return Event{
+		Type:       eventType,
+		TransferID: record.ID,
+		Bank:       record.FromBank,
+		AccountNo:  record.FromAccount,
+		ToBank:     record.ToBank,
+		ToAccount:  record.ToAccount,
+		Amount:     &amount,
+		Fee:        &fee,
+		State:      record.State,
+	}
+}
+
+// EventRecorder keeps every event in memory, e.g. to assert on them.
+type EventRecorder struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (r *EventRecorder) Emit(event Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.events = append(r.events, event)
+}
+
+// Events returns the recorded events, oldest first.
+func (r *EventRecorder) Events() []Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]Event(nil), r.events...)
+}
+
+// AuditEntry is one line of an audit log. Hash covers the entry and the
+// hash of the entry before it, so changing, removing or reordering lines
+// breaks the chain from that point on.
+type AuditEntry struct {
+	Seq      uint64 `json:"seq"`
+	Event    Event  `json:"event"`
+	PrevHash string `json:"prev_hash"`
+	Hash     string `json:"hash"`
+}
+
+// computeHash hashes the entry with its Hash field left out.
+func (e AuditEntry) computeHash() (string, error) {
+	e.Hash = ""
+	data, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// AuditLog is an EventSink appending hash-chained JSON lines to a writer.
+// Write errors cannot be returned from Emit; the first one is kept and
+// reported by Err, and nothing more is written after it.
+type AuditLog struct {
+	mu       sync.Mutex
+	w        io.Writer
+	closer   io.Closer
+	seq      uint64
+	lastHash string
+	err      error
+}
+
+// NewAuditLog starts a new audit log on w.
+func NewAuditLog(w io.Writer) *AuditLog {
+	return &AuditLog{w: w}
+}
+
+// OpenAuditLog opens the audit log file at path for appending, creating it
+// if needed. An existing file is verified first so the chain continues
+// from its last entry.
+func OpenAuditLog(path string) (*AuditLog, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("open audit log: %w", err)
+	}
+	last, err := VerifyAuditLog(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &AuditLog{w: file, closer: file, seq: last.Seq, lastHash: last.Hash}, nil
+}
+
+func (a *AuditLog) Emit(event Event) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.err != nil {
+		return
+	}
+	entry := AuditEntry{Seq: a.seq + 1, Event: event, PrevHash: a.lastHash}
+	hash, err := entry.computeHash()
+	if err != nil {
+		a.err = err
+		return
+	}
+	entry.Hash = hash
+	line, err := json.Marshal(entry)
+	if err != nil {
+		a.err = err
+		return
+	}
+	if _, err := a.w.Write(append(line, '\n')); err != nil {
+		a.err = fmt.Errorf("write audit log: %w", err)
+		return
+	}
+	a.seq = entry.Seq
+	a.lastHash = entry.Hash
+}
+
+// Err returns the first error that stopped the log.
+func (a *AuditLog) Err() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.err
+}
+
+// Close closes the file of a log opened with OpenAuditLog.
+func (a *AuditLog) Close() error {
+	if a.closer == nil {
+		return nil
+	}
+	return a.closer.Close()
+}
+
+// VerifyAuditLog checks the hash chain of the audit log read from r and
+// returns its last entry, which is zero for an empty log.
+func VerifyAuditLog(r io.Reader) (AuditEntry, error) {
+	var last AuditEntry
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var entry AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return AuditEntry{}, fmt.Errorf("%w: line %d: %v", ErrAuditTampered, line, err)
+		}
+		if entry.Seq != last.Seq+1 || entry.PrevHash != last.Hash {
+			return AuditEntry{}, fmt.Errorf("%w: line %d does not follow entry %d", ErrAuditTampered, line, last.Seq)
+		}
+		hash, err := entry.computeHash()
+		if err != nil {
+			return AuditEntry{}, err
+		}
+		if hash != entry.Hash {
+			return AuditEntry{}, fmt.Errorf("%w: line %d hash mismatch", ErrAuditTampered, line)
+		}
+		last = entry
+	}
+	if err := scanner.Err(); err != nil {
+		return AuditEntry{}, fmt.Errorf("read audit log: %w", err)
+	}
+	return last, nil
+}
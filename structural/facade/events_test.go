@@ -0,0 +1,145 @@
+package facade_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go-design-patterns/structural/facade"
+)
+
+// auditLines writes n events to a new audit log and returns its lines.
+func auditLines(t *testing.T, n int) []string {
+	t.Helper()
+	var buf bytes.Buffer
+	log := facade.NewAuditLog(&buf)
+	start := time.Date(2024, time.March, 1, 9, 0, 0, 0, time.UTC)
+	for i := 0; i < n; i++ {
+		log.Emit(facade.Event{Type: facade.EventLoginAttempted, Time: start.Add(time.Duration(i) * time.Second), Bank: "KBank", AccountNo: "0123456789"})
+	}
+	if err := log.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+}
+
+// forgedFirst changes the account of the first entry of a log and gives it
+// a valid hash, as someone who knows the scheme would.
+func forgedFirst(t *testing.T, line string) string {
+	t.Helper()
+	var entry facade.AuditEntry
+	if err := json.Unmarshal([]byte(line), &entry); err != nil {
+		t.Fatal(err)
+	}
+	entry.Event.AccountNo = "9876543210"
+	var buf bytes.Buffer
+	facade.NewAuditLog(&buf).Emit(entry.Event)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+func TestVerifyAuditLogDetectsTampering(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(lines []string) []string
+	}{
+		{"edited event", func(l []string) []string {
+			l[1] = strings.Replace(l[1], "0123456789", "9876543210", 1)
+			return l
+		}},
+		{"edited entry with a valid hash", func(l []string) []string {
+			l[0] = forgedFirst(t, l[0])
+			return l
+		}},
+		{"removed entry", func(l []string) []string { return append(l[:1], l[2:]...) }},
+		{"reordered entries", func(l []string) []string {
+			l[1], l[2] = l[2], l[1]
+			return l
+		}},
+		{"duplicated entry", func(l []string) []string { return append(l[:2], l[1:]...) }},
+		{"garbage line", func(l []string) []string { return append(l, "{not json") }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := tt.tamper(auditLines(t, 3))
+			_, err := facade.VerifyAuditLog(strings.NewReader(strings.Join(lines, "\n")))
+			if !errors.Is(err, facade.ErrAuditTampered) {
+				t.Errorf("VerifyAuditLog = %v, want ErrAuditTampered", err)
+			}
+		})
+	}
+}
+
+func TestVerifyAuditLogAcceptsIntactLog(t *testing.T) {
+	lines := auditLines(t, 3)
+	last, err := facade.VerifyAuditLog(strings.NewReader(strings.Join(lines, "\n") + "\n\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last.Seq != 3 || last.Hash == "" {
+		t.Errorf("last entry %d with hash %q, want entry 3", last.Seq, last.Hash)
+	}
+	if last, err := facade.VerifyAuditLog(strings.NewReader("")); err != nil || last.Seq != 0 {
+		t.Errorf("empty log = %d, %v", last.Seq, err)
+	}
+}
+
+func TestOpenAuditLogContinuesChain(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	for i := 0; i < 2; i++ {
+		log, err := facade.OpenAuditLog(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		log.Emit(facade.Event{Type: facade.EventLoginAttempted, Bank: "KBank"})
+		if err := errors.Join(log.Err(), log.Close()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if last, err := facade.VerifyAuditLog(file); err != nil || last.Seq != 2 {
+		t.Fatalf("reopened log ends at entry %d, %v; want 2", last.Seq, err)
+	}
+
+	data, _ := os.ReadFile(path)
+	tampered := bytes.Replace(data, []byte(`"KBank"`), []byte(`"SiamBank"`), 1)
+	if err := os.WriteFile(path, tampered, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := facade.OpenAuditLog(path); !errors.Is(err, facade.ErrAuditTampered) {
+		t.Errorf("OpenAuditLog of a tampered file = %v, want ErrAuditTampered", err)
+	}
+}
+
+// failingWriter fails every write after the first.
+type failingWriter struct{ writes int }
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	if w.writes > 1 {
+		return 0, errors.New("disk full")
+	}
+	return len(p), nil
+}
+
+func TestAuditLogStopsAtFirstWriteError(t *testing.T) {
+	w := &failingWriter{}
+	log := facade.NewAuditLog(w)
+	for i := 0; i < 3; i++ {
+		log.Emit(facade.Event{Type: facade.EventLoginAttempted})
+	}
+	if err := log.Err(); err == nil || !strings.Contains(err.Error(), "disk full") {
+		t.Errorf("Err = %v, want the write error", err)
+	}
+	if w.writes != 2 {
+		t.Errorf("%d writes, want none after the failed one", w.writes)
+	}
+}
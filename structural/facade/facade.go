@@ -1,88 +1,535 @@
 package facade
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"time"
 )
 
-// BankApi defines the interface for different bank APIs.
+// BankApi defines the interface for different bank APIs. Every call takes a
+// context so a slow bank can be abandoned; an implementation must return an
+// error wrapping ctx.Err() without side effects when the context ends before
+// it acts. Failures are reported as *BankError.
 type BankApi interface {
-	Login() error
-	Transfer(toAcc, toBank string, amount float32) (string, error)
-	Confirm(confToken string) error
+	Login(ctx context.Context) error
+	Transfer(ctx context.Context, toAcc, toBank string, amount Money) (string, error)
+	Confirm(ctx context.Context, confToken string) error
+	Cancel(ctx context.Context, confToken string) error
+	Balance(ctx context.Context) (Money, error)
 	IsLoggedIn() bool
 }
 
+// Receiver is implemented by bank APIs that can accept incoming transfers.
+// Receiving money needs no login, so the facade calls it on a destination
+// API created without a PIN.
+type Receiver interface {
+	AccountCurrency() string
+	Credit(ctx context.Context, ref string, amount Money) error
+	Reverse(ctx context.Context, ref string) error
+}
+
+// FeeCollector is implemented by bank APIs that keep the books of the fees
+// their customers pay. Once a transfer is committed the facade asks the
+// source API to move the fee out of settlement into its bank's fee income;
+// the fees of banks without it stay in settlement.
+type FeeCollector interface {
+	CollectFee(ctx context.Context, ref string, fee Money) error
+}
+
 // BaseBankApi provides common functionality for bank APIs.
 type BaseBankApi struct {
 	AccountNo string
 	Bank      string
 	Pin       string
 	Token     string
+	Currency  string
+	// TokenExpiry is when the session of Token ends.
+	TokenExpiry time.Time
+
+	backend *Backend
+	mu      sync.Mutex
+	held    map[string]Money
+	credits map[string]Money
+}
+
+// settlementAccount is the clearing account that balances holds on the
+// source bank against credits on the destination bank.
+func settlementAccount(currency string) AccountID {
+	return SystemAccount("clearing", "settlement-"+currency)
+}
+
+func (b *BaseBankApi) account() AccountID {
+	return AccountID{Bank: b.Bank, AccountNo: b.AccountNo}
+}
+
+// Login verifies account details against the backend and stores a session token.
+func (b *BaseBankApi) Login(ctx context.Context) error {
+	if err := b.backend.wait(ctx, b.Bank); err != nil {
+		return newBankError(b.Bank, OpLogin, err)
+	}
+	session, err := b.backend.Auth.Login(b.Bank, b.AccountNo, b.Pin)
+	if err != nil {
+		return newBankError(b.Bank, OpLogin, err)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.Token = session.Token
+	b.TokenExpiry = session.ExpiresAt
+	return nil
+}
+
+// SessionExpiry returns when the current session ends.
+func (b *BaseBankApi) SessionExpiry() time.Time {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.TokenExpiry
+}
+
+// Logout ends the current session.
+func (b *BaseBankApi) Logout(ctx context.Context) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.Token != "" {
+		b.backend.Auth.Logout(b.Token)
+	}
+	b.Token = ""
+	b.TokenExpiry = time.Time{}
+	return nil
 }
 
-// Login verifies account details and generates a token.
-func (b *BaseBankApi) Login(validAccount, validPin, validBank string) error {
-	if b.AccountNo == validAccount && b.Pin == validPin && b.Bank == validBank {
-		b.Token = "valid-token"
-		return nil
+// CheckAmount rejects non-positive amounts and amounts not in the account currency.
+func (b *BaseBankApi) CheckAmount(amount Money) error {
+	if err := amount.Validate(); err != nil {
+		return err
+	}
+	if !amount.IsPositive() {
+		return fmt.Errorf("%w: %s", ErrNonPositiveAmount, amount)
 	}
-	return fmt.Errorf("invalid account details for bank: %s", b.Bank)
+	if amount.Currency() != b.Currency {
+		return fmt.Errorf("%w: bank %s holds %s, got %s", ErrCurrencyMismatch, b.Bank, b.Currency, amount.Currency())
+	}
+	return nil
+}
+
+// ready waits for the bank and checks the session before op.
+func (b *BaseBankApi) ready(ctx context.Context, op BankOperation) error {
+	if err := b.backend.wait(ctx, b.Bank); err != nil {
+		return newBankError(b.Bank, op, err)
+	}
+	if !b.IsLoggedIn() {
+		return newBankError(b.Bank, op, ErrNotLoggedIn)
+	}
+	return nil
+}
+
+// Balance returns the balance of the logged-in account.
+func (b *BaseBankApi) Balance(ctx context.Context) (Money, error) {
+	if err := b.ready(ctx, OpBalance); err != nil {
+		return Money{}, err
+	}
+	balance, err := b.backend.Ledger.Balance(b.account())
+	return balance, newBankError(b.Bank, OpBalance, err)
+}
+
+// hold moves amount from the logged-in account into the bank's suspense
+// account and returns the confirmation token that commits or cancels it.
+func (b *BaseBankApi) hold(toAcc, toBank string, amount Money) (string, error) {
+	confToken, err := newToken()
+	if err != nil {
+		return "", err
+	}
+
+	suspense := SystemAccount(b.Bank, "suspense")
+	if err := b.backend.Ledger.EnsureAccount(suspense, b.Currency); err != nil {
+		return "", err
+	}
+	_, err = b.backend.Ledger.Move(confToken, fmt.Sprintf("hold for %s/%s", toBank, toAcc), b.account(), suspense, amount)
+	if err != nil {
+		return "", err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.held == nil {
+		b.held = make(map[string]Money)
+	}
+	b.held[confToken] = amount
+	return confToken, nil
+}
+
+// settle commits the funds held under confToken to the settlement account.
+func (b *BaseBankApi) settle(confToken string) error {
+	return b.clearHold(confToken, "commit", settlementAccount(b.Currency))
+}
+
+// release returns the funds held under confToken to the account.
+func (b *BaseBankApi) release(confToken string) error {
+	return b.clearHold(confToken, "release", b.account())
+}
+
+func (b *BaseBankApi) clearHold(confToken, memo string, to AccountID) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	amount, exists := b.held[confToken]
+	if !exists {
+		return ErrInvalidConfirmation
+	}
+	if err := b.backend.Ledger.EnsureAccount(to, amount.Currency()); err != nil {
+		return err
+	}
+	_, err := b.backend.Ledger.Move(confToken, memo, SystemAccount(b.Bank, "suspense"), to, amount)
+	if err != nil {
+		return err
+	}
+	delete(b.held, confToken)
+	return nil
+}
+
+// AccountCurrency returns the currency the account is held in.
+func (b *BaseBankApi) AccountCurrency() string {
+	return b.Currency
+}
+
+// Credit pays amount from the settlement account into this account.
+func (b *BaseBankApi) Credit(ctx context.Context, ref string, amount Money) error {
+	if err := b.backend.wait(ctx, b.Bank); err != nil {
+		return newBankError(b.Bank, OpCredit, err)
+	}
+	return newBankError(b.Bank, OpCredit, b.credit(ref, amount))
+}
+
+func (b *BaseBankApi) credit(ref string, amount Money) error {
+	if err := b.CheckAmount(amount); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, exists := b.credits[ref]; exists {
+		return fmt.Errorf("%w: %s", ErrDuplicateCredit, ref)
+	}
+	settlement := settlementAccount(amount.Currency())
+	if err := b.backend.Ledger.EnsureAccount(settlement, amount.Currency()); err != nil {
+		return err
+	}
+	_, err := b.backend.Ledger.Move(ref, "credit", settlement, b.account(), amount)
+	if err != nil {
+		return err
+	}
+	if b.credits == nil {
+		b.credits = make(map[string]Money)
+	}
+	b.credits[ref] = amount
+	return nil
+}
+
+// Reverse takes back a credit made under ref.
+func (b *BaseBankApi) Reverse(ctx context.Context, ref string) error {
+	if err := b.backend.wait(ctx, b.Bank); err != nil {
+		return newBankError(b.Bank, OpReverse, err)
+	}
+	return newBankError(b.Bank, OpReverse, b.reverse(ref))
+}
+
+func (b *BaseBankApi) reverse(ref string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	amount, exists := b.credits[ref]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrUnknownCredit, ref)
+	}
+	_, err := b.backend.Ledger.Move(ref, "reverse credit", b.account(), settlementAccount(amount.Currency()), amount)
+	if err != nil {
+		return err
+	}
+	delete(b.credits, ref)
+	return nil
+}
+
+// CollectFee books the fee of the committed transfer ref into this bank's
+// fee income.
+func (b *BaseBankApi) CollectFee(ctx context.Context, ref string, fee Money) error {
+	if err := b.backend.wait(ctx, b.Bank); err != nil {
+		return newBankError(b.Bank, OpFee, err)
+	}
+	return newBankError(b.Bank, OpFee, collectFee(b.backend.Ledger, b.Bank, ref, fee))
 }
 
 // IsLoggedIn checks if the token is valid.
 func (b *BaseBankApi) IsLoggedIn() bool {
-	return b.Token == "valid-token"
+	b.mu.Lock()
+	token := b.Token
+	b.mu.Unlock()
+
+	if token == "" {
+		return false
+	}
+	_, err := b.backend.Auth.Validate(token)
+	return err == nil
 }
 
 // BankTransferFacade encapsulates the complexity of bank transfers
 type BankTransferFacade struct {
-	banks map[string]func(accountNo, bank, pin string) BankApi
+	mu               sync.RWMutex
+	banks            map[string]BankFactory
+	registerDefaults bool
+	backend          *Backend
+	transfers        TransferStore
+	idempotency      IdempotencyStore
+	history          HistoryRepository
+	fees             FeePolicy
+	rates            RateProvider
+	timeouts         map[string]time.Duration
+	defaultTimeout   time.Duration
+	retry            *RetryPolicy
+	breakerConfig    *BreakerConfig
+	breakers         map[string]*CircuitBreaker
+	decorators       []BankDecorator
+	sessions         *SessionManager
+	rules            *RuleChain
+	sinks            []EventSink
+	directory        *BankDirectory
+	now              func() time.Time
 }
 
-// NewBankTransferFacade creates a new facade instance with registered banks
-func NewBankTransferFacade() *BankTransferFacade {
+// NewBankTransferFacade creates a new facade instance with registered banks.
+// Options are applied in order; the built-in banks are registered afterwards
+// for every name the options left free, so WithBank can replace a default.
+func NewBankTransferFacade(opts ...Option) (*BankTransferFacade, error) {
 	facade := &BankTransferFacade{
-		banks: make(map[string]func(accountNo, bank, pin string) BankApi),
+		banks:            make(map[string]BankFactory),
+		registerDefaults: true,
+		transfers:        NewMemoryTransferStore(),
+		idempotency:      NewMemoryIdempotencyStore(defaultIdempotencyTTL),
+		history:          NewMemoryHistoryRepository(),
+		rates:            NewStaticRates(),
+		timeouts:         make(map[string]time.Duration),
+		defaultTimeout:   defaultBankTimeout,
+		breakers:         make(map[string]*CircuitBreaker),
+		rules:            NewRuleChain(),
+		directory:        DefaultBankDirectory(),
+		now:              time.Now,
+	}
+
+	for _, opt := range opts {
+		if err := opt(facade); err != nil {
+			return nil, err
+		}
+	}
+
+	if facade.backend == nil {
+		backend, err := NewDemoBackend()
+		if err != nil {
+			return nil, err
+		}
+		facade.backend = backend
 	}
 
 	// Register supported banks
-	facade.banks["SiamBank"] = func(accountNo, bank, pin string) BankApi {
-		return NewSiamBankApi(accountNo, bank, pin)
+	if facade.registerDefaults {
+		for name, factory := range facade.defaultBanks() {
+			if _, exists := facade.banks[name]; !exists {
+				facade.banks[name] = factory
+			}
+		}
 	}
-	facade.banks["KBank"] = func(accountNo, bank, pin string) BankApi {
-		return NewKBankApi(accountNo, bank, pin)
+
+	return facade, nil
+}
+
+// defaultBanks returns the factories for the banks shipped with the package.
+func (f *BankTransferFacade) defaultBanks() map[string]BankFactory {
+	return map[string]BankFactory{
+		"SiamBank": func(accountNo, bank, pin string) BankApi {
+			return NewSiamBankApi(accountNo, bank, pin, f.backend)
+		},
+		"KBank": func(accountNo, bank, pin string) BankApi {
+			return NewKBankApi(accountNo, bank, pin, f.backend)
+		},
 	}
+}
 
-	return facade
+// Backend returns the backend shared by the built-in banks, so custom bank
+// factories can authenticate and post against the same accounts.
+func (f *BankTransferFacade) Backend() *Backend {
+	return f.backend
 }
 
-// Transfer provides a simplified interface for transferring funds
-func (f *BankTransferFacade) Transfer(accountNo, pin, fromBank, toAcc, toBank string, amount float32) error {
-	// Get bank factory function
-	bankFactory, exists := f.banks[fromBank]
-	if !exists {
-		return fmt.Errorf("unsupported bank: %s", fromBank)
+// TransferOption configures a single call to Transfer.
+type TransferOption func(*transferOptions)
+
+type transferOptions struct {
+	idempotencyKey string
+	// source is a logged-in API of the source account to use instead of
+	// creating and logging in a new one.
+	source BankApi
+}
+
+// Transfer provides a simplified interface for transferring funds. It holds
+// the amount on the source bank, credits the destination bank and then
+// commits the hold; if a later step fails the earlier ones are undone.
+//
+// The receipt describes the outcome and is also returned for transfers that
+// failed after they started, alongside the error.
+func (f *BankTransferFacade) Transfer(ctx context.Context, accountNo, pin, fromBank, toAcc, toBank string, amount Money, opts ...TransferOption) (Receipt, error) {
+	var options transferOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	toAcc, err := f.ValidateAccount(toBank, toAcc)
+	if err != nil {
+		return Receipt{}, err
+	}
+
+	if options.idempotencyKey == "" {
+		return f.transfer(ctx, options.source, accountNo, pin, fromBank, toAcc, toBank, amount)
+	}
+	return f.transferOnce(ctx, options, accountNo, pin, fromBank, toAcc, toBank, amount)
+}
+
+// transferOnce runs a transfer at most once per idempotency key.
+func (f *BankTransferFacade) transferOnce(ctx context.Context, options transferOptions, accountNo, pin, fromBank, toAcc, toBank string, amount Money) (Receipt, error) {
+	key := options.idempotencyKey
+	fingerprint := transferFingerprint(accountNo, fromBank, toAcc, toBank, amount)
+	record, claimed, err := f.idempotency.Claim(IdempotencyRecord{Key: key, Fingerprint: fingerprint})
+	if err != nil {
+		return Receipt{}, fmt.Errorf("claim idempotency key: %w", err)
+	}
+	if !claimed {
+		if record.Fingerprint != fingerprint {
+			return Receipt{}, fmt.Errorf("%w: %q", ErrIdempotencyKeyConflict, key)
+		}
+		return record.replay()
+	}
+
+	receipt, err := f.transfer(ctx, options.source, accountNo, pin, fromBank, toAcc, toBank, amount)
+	if receipt.TransactionID == "" || IsTransient(err) {
+		// Rejected before any money moved, or undone after a failure that
+		// may pass; only final outcomes are kept, so a retry runs again
+		if releaseErr := f.idempotency.Release(key); releaseErr != nil {
+			return receipt, errors.Join(err, fmt.Errorf("release idempotency key: %w", releaseErr))
+		}
+		return receipt, err
+	}
+
+	record.TransferID = receipt.TransactionID
+	record.State = receipt.Status
+	record.Receipt = &receipt
+	if err != nil {
+		record.Error = err.Error()
+		var transferErr *TransferError
+		if errors.As(err, &transferErr) {
+			record.Error = transferErr.Err.Error()
+			record.FailedStep = transferErr.Step
+		}
+	}
+	if completeErr := f.idempotency.Complete(record); completeErr != nil {
+		return receipt, errors.Join(err, fmt.Errorf("record idempotency key: %w", completeErr))
+	}
+	return receipt, err
+}
+
+// transfer validates the request, runs the transfer saga and records its
+// receipt. The receipt is empty if the request was rejected before a saga
+// started. A nil source is created from the source bank's factory.
+func (f *BankTransferFacade) transfer(ctx context.Context, source BankApi, accountNo, pin, fromBank, toAcc, toBank string, amount Money) (Receipt, error) {
+	if err := amount.Validate(); err != nil {
+		return Receipt{}, fmt.Errorf("invalid amount: %w", err)
+	}
+	if !amount.IsPositive() {
+		return Receipt{}, fmt.Errorf("invalid amount: %w: %s", ErrNonPositiveAmount, amount)
+	}
+
+	// Get bank factory functions
+	sourceFactory, err := f.bankFactory(fromBank)
+	if err != nil {
+		return Receipt{}, err
+	}
+	destinationFactory, err := f.bankFactory(toBank)
+	if err != nil {
+		return Receipt{}, err
+	}
+
+	// Let the pre-transfer rules refuse the transfer before any bank is called
+	req := TransferRequest{
+		FromBank:    fromBank,
+		FromAccount: accountNo,
+		ToBank:      toBank,
+		ToAccount:   toAcc,
+		Amount:      amount,
+		Time:        f.now(),
+		Rates:       f.rates,
+	}
+	if err := f.rules.Check(ctx, req); err != nil {
+		return Receipt{}, err
 	}
+	var receipt Receipt
+	defer func() {
+		f.rules.Record(req, receipt.Status == StateCommitted)
+	}()
 
-	// Create bank API instance
-	bankApi := bankFactory(accountNo, fromBank, pin)
+	// Create bank API instances; the destination needs no login to receive
+	if source == nil {
+		source = f.sourceApi(sourceFactory, accountNo, fromBank, pin)
+	}
+	receiver, ok := destinationFactory(toAcc, toBank, "").(Receiver)
+	if !ok {
+		return Receipt{}, &BankError{Bank: toBank, Op: OpCredit, Err: ErrCannotReceive}
+	}
+
+	// Work out fees and the amount the destination receives
+	q, err := f.price(fromBank, toBank, amount, receiver.AccountCurrency())
+	if err != nil {
+		return Receipt{}, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return Receipt{}, err
+	}
 
 	// Execute transfer workflow
-	if err := bankApi.Login(); err != nil {
-		return fmt.Errorf("login failed: %w", err)
+	s, err := f.newSaga(accountNo, fromBank, toAcc, toBank, amount, q)
+	if err != nil {
+		return Receipt{}, err
+	}
+	err = s.run(ctx, source, receiver)
+
+	receipt = newReceipt(s.record)
+	if historyErr := f.history.Add(receipt); historyErr != nil {
+		historyErr = newTransferError(receipt.TransactionID, "history", fromBank, toBank, receipt.Status, historyErr)
+		return receipt, errors.Join(err, historyErr)
 	}
+	return receipt, err
+}
 
-	confToken, err := bankApi.Transfer(toAcc, toBank, amount)
+// Balance logs in to bank and returns the balance of the account.
+func (f *BankTransferFacade) Balance(ctx context.Context, accountNo, pin, bank string) (Money, error) {
+	bankFactory, err := f.bankFactory(bank)
 	if err != nil {
-		return fmt.Errorf("transfer failed: %w", err)
+		return Money{}, err
 	}
 
-	if err := bankApi.Confirm(confToken); err != nil {
-		return fmt.Errorf("confirmation failed: %w", err)
+	bankApi := f.sourceApi(bankFactory, accountNo, bank, pin)
+	if err := f.login(ctx, bankApi, bank, accountNo); err != nil {
+		return Money{}, newTransferError("", "login", bank, "", "", err)
 	}
 
-	return nil
+	balanceCtx, cancel := f.bankContext(ctx, bank)
+	defer cancel()
+	return bankApi.Balance(balanceCtx)
 }
 
 // SiamBankApi implements BankApi for SiamBank
@@ -90,30 +537,41 @@ type SiamBankApi struct {
 	BaseBankApi
 }
 
-func NewSiamBankApi(accountNo, bank, pin string) *SiamBankApi {
-	return &SiamBankApi{BaseBankApi{AccountNo: accountNo, Bank: bank, Pin: pin}}
+func NewSiamBankApi(accountNo, bank, pin string, backend *Backend) *SiamBankApi {
+	return &SiamBankApi{BaseBankApi{AccountNo: accountNo, Bank: bank, Pin: pin, Currency: "THB", backend: backend}}
 }
 
-func (s *SiamBankApi) Login() error {
-	return s.BaseBankApi.Login("0123456789", "1234", "SiamBank")
+func (s *SiamBankApi) Transfer(ctx context.Context, toAcc, toBank string, amount Money) (string, error) {
+	if err := s.ready(ctx, OpTransfer); err != nil {
+		return "", err
+	}
+	if err := s.CheckAmount(amount); err != nil {
+		return "", newBankError(s.Bank, OpTransfer, err)
+	}
+	confToken, err := s.hold(toAcc, toBank, amount)
+	if err != nil {
+		return "", newBankError(s.Bank, OpTransfer, err)
+	}
+	return confToken, nil
 }
 
-func (s *SiamBankApi) Transfer(toAcc, toBank string, amount float32) (string, error) {
-	if !s.IsLoggedIn() {
-		return "", errors.New("please login first")
+func (s *SiamBankApi) Confirm(ctx context.Context, confToken string) error {
+	if err := s.ready(ctx, OpConfirm); err != nil {
+		return err
 	}
-	fmt.Printf("Transferring %.2f from SiamBank to %s at %s\n", amount, toAcc, toBank)
-	return "valid-confirm-token", nil
+	if err := s.settle(confToken); err != nil {
+		return newBankError(s.Bank, OpConfirm, err)
+	}
+	return nil
 }
 
-func (s *SiamBankApi) Confirm(confToken string) error {
-	if !s.IsLoggedIn() {
-		return errors.New("please login first")
+func (s *SiamBankApi) Cancel(ctx context.Context, confToken string) error {
+	if err := s.ready(ctx, OpCancel); err != nil {
+		return err
 	}
-	if confToken != "valid-confirm-token" {
-		return errors.New("invalid confirmation token")
+	if err := s.release(confToken); err != nil {
+		return newBankError(s.Bank, OpCancel, err)
 	}
-	fmt.Println("Transfer confirmed via SiamBank")
 	return nil
 }
 
@@ -122,41 +580,274 @@ type KBankApi struct {
 	BaseBankApi
 }
 
-func NewKBankApi(accountNo, bank, pin string) *KBankApi {
-	return &KBankApi{BaseBankApi{AccountNo: accountNo, Bank: bank, Pin: pin}}
+func NewKBankApi(accountNo, bank, pin string, backend *Backend) *KBankApi {
+	return &KBankApi{BaseBankApi{AccountNo: accountNo, Bank: bank, Pin: pin, Currency: "THB", backend: backend}}
 }
 
-func (k *KBankApi) Login() error {
-	return k.BaseBankApi.Login("0123456789", "1234", "KBank")
+func (k *KBankApi) Transfer(ctx context.Context, toAcc, toBank string, amount Money) (string, error) {
+	if err := k.ready(ctx, OpTransfer); err != nil {
+		return "", err
+	}
+	if err := k.CheckAmount(amount); err != nil {
+		return "", newBankError(k.Bank, OpTransfer, err)
+	}
+	confToken, err := k.hold(toAcc, toBank, amount)
+	if err != nil {
+		return "", newBankError(k.Bank, OpTransfer, err)
+	}
+	return confToken, nil
 }
 
-func (k *KBankApi) Transfer(toAcc, toBank string, amount float32) (string, error) {
-	if !k.IsLoggedIn() {
-		return "", errors.New("please login first")
+func (k *KBankApi) Confirm(ctx context.Context, confToken string) error {
+	if err := k.ready(ctx, OpConfirm); err != nil {
+		return err
+	}
+	if err := k.settle(confToken); err != nil {
+		return newBankError(k.Bank, OpConfirm, err)
 	}
-	fmt.Printf("Transferring %.2f from KBank to %s at %s\n", amount, toAcc, toBank)
-	return "valid-confirm-token", nil
+	return nil
 }
 
-func (k *KBankApi) Confirm(confToken string) error {
-	if !k.IsLoggedIn() {
-		return errors.New("please login first")
+func (k *KBankApi) Cancel(ctx context.Context, confToken string) error {
+	if err := k.ready(ctx, OpCancel); err != nil {
+		return err
 	}
-	if confToken != "valid-confirm-token" {
-		return errors.New("invalid confirmation token")
+	if err := k.release(confToken); err != nil {
+		return newBankError(k.Bank, OpCancel, err)
 	}
-	fmt.Println("Transfer confirmed via KBank")
 	return nil
 }
 
 // Example usage:
 func Example() {
-	facade := NewBankTransferFacade()
+	ctx := context.Background()
 
-	// Perform transfer using the facade
-	err := facade.Transfer("0123456789", "1234", "SiamBank", "9876543210", "KBank", 1000.00)
+	// A USD bank built on the same backend, reachable through an exchange rate
+	backend, err := NewDemoBackend()
+	if err != nil {
+		fmt.Printf("Setup failed: %v\n", err)
+		return
+	}
+	if err := backend.OpenAccount("GlobalBank", "5550001111", "0000", Money{currency: "USD"}); err != nil {
+		fmt.Printf("Setup failed: %v\n", err)
+		return
+	}
+	usdBank := func(accountNo, bank, pin string) BankApi {
+		api := NewKBankApi(accountNo, bank, pin, backend)
+		api.Currency = "USD"
+		return api
+	}
+	usdRate, _ := ParseRate("USD", "THB", "35.00")
+	interbankFee, _ := ParseMoney("25.00", "THB")
+	perTransferLimit, _ := ParseMoney("100000.00", "THB")
+	dailyLimit, _ := ParseMoney("60000.00", "THB")
+
+	// Every event goes to a hash-chained audit log and an in-memory recorder
+	var auditTrail bytes.Buffer
+	audit := NewAuditLog(&auditTrail)
+	events := &EventRecorder{}
+
+	facade, err := NewBankTransferFacade(
+		WithBackend(backend),
+		WithBank("GlobalBank", usdBank),
+		WithBankTimeout("KBank", 200*time.Millisecond),
+		WithFeePolicy(InterbankFee{Policy: FlatFee{Amount: interbankFee}}),
+		WithRateProvider(NewStaticRates(usdRate)),
+		WithRules(
+			SelfTransfer{},
+			MaxAmount{Limit: perTransferLimit},
+			NewDenylist(AccountID{Bank: "KBank", AccountNo: "6666666666"}),
+			NewDailyLimit(dailyLimit, nil),
+			NewVelocityLimit(10, time.Hour),
+		),
+		WithSessionReuse(WithRefreshBefore(time.Minute)),
+		WithEventSink(audit),
+		WithEventSink(events),
+		WithRetry(RetryPolicy{MaxAttempts: 3, BaseDelay: 10 * time.Millisecond, Jitter: 0.5}),
+		WithCircuitBreaker(BreakerConfig{
+			FailureThreshold: 3,
+			OpenTimeout:      time.Minute,
+			OnStateChange: func(change BreakerStateChange) {
+				fmt.Printf("Breaker %s: %s -> %s\n", change.Bank, change.From, change.To)
+			},
+		}),
+	)
+	if err != nil {
+		fmt.Printf("Setup failed: %v\n", err)
+		return
+	}
+	fmt.Printf("Registered banks: %v\n", facade.Banks())
+
+	// Perform transfer using the facade; the retry with the same key is a no-op
+	amount, err := ParseMoney("1000.00", "THB")
+	if err != nil {
+		fmt.Printf("Invalid amount: %v\n", err)
+		return
+	}
+	for attempt := 1; attempt <= 2; attempt++ {
+		receipt, err := facade.Transfer(ctx, "0123456789", "1234", "SiamBank", "9876543210", "KBank", amount,
+			WithIdempotencyKey("invoice-42"))
+		if err != nil {
+			fmt.Printf("Transfer failed: %v\n", err)
+			return
+		}
+		fmt.Printf("Receipt %s (%s): %s + fee %s %s\n", receipt.TransactionID, receipt.Reference, receipt.Amount, receipt.Fee, receipt.Status)
+	}
+
+	// A cross-currency transfer credits the destination in USD
+	receipt, err := facade.Transfer(ctx, "0123456789", "1234", "KBank", "5550001111", "GlobalBank", amount)
 	if err != nil {
 		fmt.Printf("Transfer failed: %v\n", err)
 		return
 	}
+	fmt.Printf("Credited %s at %s (fee %s)\n", receipt.Credited, receipt.Rate, receipt.Fee)
+
+	// KBank served over HTTP is registered like any other bank
+	bankServer, err := NewBankServer("KBank", func(accountNo, bank, pin string) BankApi {
+		return NewKBankApi(accountNo, bank, pin, backend)
+	}, WithServerLatency(10*time.Millisecond))
+	if err != nil {
+		fmt.Printf("Bank server failed: %v\n", err)
+		return
+	}
+	server := httptest.NewServer(bankServer)
+	defer server.Close()
+	if err := facade.RegisterBank("KBankOnline", HTTPBankFactory(server.URL, "THB", server.Client())); err != nil {
+		fmt.Printf("Register failed: %v\n", err)
+		return
+	}
+
+	// Two failed logins are retried; three in a row open the breaker
+	bankServer.InjectFault("/login", http.StatusServiceUnavailable, 2)
+	receipt, err = facade.Transfer(ctx, "0123456789", "1234", "KBankOnline", "0123456789", "SiamBank", amount)
+	if err != nil {
+		fmt.Printf("HTTP transfer failed: %v\n", err)
+		return
+	}
+	fmt.Printf("HTTP transfer %s %s\n", receipt.TransactionID, receipt.Status)
+	if err := facade.Sessions().Logout(ctx, "KBankOnline", "0123456789"); err != nil {
+		fmt.Printf("Logout failed: %v\n", err)
+	}
+	bankServer.InjectFault("/login", http.StatusServiceUnavailable, 3)
+	for attempt := 1; attempt <= 2; attempt++ {
+		_, err = facade.Transfer(ctx, "0123456789", "1234", "KBankOnline", "0123456789", "SiamBank", amount)
+		fmt.Printf("HTTP transfer failed: %v\n", err)
+	}
+
+	balance, err := facade.Balance(ctx, "9876543210", "4321", "KBank")
+	if err != nil {
+		fmt.Printf("Balance failed: %v\n", err)
+		return
+	}
+	fmt.Printf("KBank 9876543210 balance: %s\n", balance)
+
+	// Destination accounts are checked against the bank directory, which
+	// also resolves clearing codes
+	kbank, err := facade.LookupBank("004")
+	if err != nil {
+		fmt.Printf("Lookup failed: %v\n", err)
+		return
+	}
+	fmt.Printf("Bank %s is %s (%s)\n", kbank.Code, kbank.Name, kbank.FullName)
+	_, err = facade.Transfer(ctx, "0123456789", "1234", "SiamBank", "987-6-54321-1", kbank.Name, amount)
+	fmt.Printf("Mistyped account: %v\n", err)
+
+	// Rules refuse transfers before any bank is called
+	for _, toAcc := range []string{"0123456789", "6666666666"} {
+		_, err = facade.Transfer(ctx, "0123456789", "1234", "KBank", toAcc, "KBank", amount)
+		var rejection *RejectionError
+		if errors.As(err, &rejection) {
+			fmt.Printf("Rejected (%s): %v\n", rejection.Code, err)
+		}
+	}
+
+	// A monthly standing order from the 31st runs on the last day of shorter months
+	clock := time.Date(2024, time.January, 31, 9, 0, 0, 0, time.UTC)
+	scheduler := NewScheduler(facade, WithSchedulerClock(func() time.Time { return clock }))
+	rent, _ := ParseMoney("100.00", "THB")
+	_, err = scheduler.Schedule(Instruction{
+		FromBank: "KBank", FromAccount: "9876543210", Pin: "4321",
+		ToBank: "KBank", ToAccount: "0123456789",
+		Amount: rent, Frequency: Monthly, Start: clock,
+	})
+	if err != nil {
+		fmt.Printf("Schedule failed: %v\n", err)
+		return
+	}
+	for _, month := range []time.Month{time.January, time.February, time.March} {
+		clock = time.Date(2024, month+1, 0, 9, 0, 0, 0, time.UTC) // last day of month
+		for _, run := range scheduler.RunDue(ctx) {
+			fmt.Printf("Standing order ran on %s: %s\n", run.Due.Format(time.DateOnly), run.Receipt.Status)
+		}
+	}
+
+	// A payroll batch logs each source account in once and reports every item
+	salary, _ := ParseMoney("500.00", "THB")
+	report := facade.ExecuteBatch(ctx, TransferBatch{Concurrency: 2, Items: []BatchItem{
+		{ID: "alice", FromBank: "SiamBank", FromAccount: "0123456789", Pin: "1234", ToBank: "KBank", ToAccount: "9876543210", Amount: salary},
+		{ID: "bob", FromBank: "SiamBank", FromAccount: "0123456789", Pin: "1234", ToBank: "KBank", ToAccount: "1111111111", Amount: salary},
+		{ID: "carol", FromBank: "KBank", FromAccount: "9876543210", Pin: "0000", ToBank: "SiamBank", ToAccount: "0123456789", Amount: salary},
+	}})
+	for _, result := range report.Results {
+		fmt.Printf("Batch item %s %s %s\n", result.ID, result.Status, result.Reason)
+	}
+	fmt.Printf("Batch: %d succeeded, %d failed, %d skipped, transferred %v\n",
+		report.Summary.Succeeded, report.Summary.Failed, report.Summary.Skipped, report.Summary.Transferred)
+
+	// A transfer larger than the balance is rejected by the ledger
+	tooMuch, _ := ParseMoney("50000.00", "THB")
+	_, err = facade.Transfer(ctx, "0123456789", "1234", "SiamBank", "9876543210", "KBank", tooMuch)
+	fmt.Printf("Oversized transfer: %v\n", err)
+
+	// A transfer to an unknown account is compensated: the hold is released
+	_, err = facade.Transfer(ctx, "0123456789", "1234", "SiamBank", "1111111111", "KBank", amount)
+	var transferErr *TransferError
+	if errors.As(err, &transferErr) {
+		record, _ := facade.TransferStatus(transferErr.TransferID)
+		fmt.Printf("Transfer %s ended %s: %v\n", record.ID, record.State, err)
+	}
+
+	// A slow destination bank times out, the hold is released again and the
+	// error says the transfer can be retried
+	facade.Backend().SetLatency("KBank", time.Second)
+	_, err = facade.Transfer(ctx, "0123456789", "1234", "SiamBank", "9876543210", "KBank", amount)
+	if errors.As(err, &transferErr) {
+		fmt.Printf("Slow bank %s at %s (retryable %t): %v\n", transferErr.Step, transferErr.Bank, IsTransient(err), err)
+	}
+
+	// Sources kept their sessions between transfers; log them all out
+	for _, session := range facade.Sessions().Sessions() {
+		fmt.Printf("Session %s/%s expires %s\n", session.Bank, session.AccountNo, session.ExpiresAt.Sub(session.LoggedIn).Round(time.Minute))
+	}
+	if err := facade.Sessions().LogoutAll(ctx); err != nil {
+		fmt.Printf("Logout failed: %v\n", err)
+	}
+
+	counts := make(map[EventType]int)
+	for _, event := range events.Events() {
+		counts[event.Type]++
+	}
+	fmt.Printf("Events: %d logins, %d initiated, %d confirmed, %d failed\n", counts[EventLoginAttempted],
+		counts[EventTransferInitiated], counts[EventTransferConfirmed], counts[EventTransferFailed])
+
+	// The audit log verifies until a single line is changed
+	last, err := VerifyAuditLog(bytes.NewReader(auditTrail.Bytes()))
+	if err != nil || audit.Err() != nil {
+		fmt.Printf("Audit failed: %v\n", errors.Join(err, audit.Err()))
+		return
+	}
+	fmt.Printf("Audit log verified: %d events\n", last.Seq)
+	tampered := bytes.Replace(auditTrail.Bytes(), []byte(`"amount":"1000.00"`), []byte(`"amount":"10.00"`), 1)
+	_, err = VerifyAuditLog(bytes.NewReader(tampered))
+	fmt.Printf("Tampered audit log: %v\n", err)
+
+	// Export the statement of the source account
+	receipts, err := facade.History(HistoryFilter{Bank: "SiamBank", Account: "0123456789"})
+	if err != nil {
+		fmt.Printf("History failed: %v\n", err)
+		return
+	}
+	if err := WriteStatementCSV(os.Stdout, receipts); err != nil {
+		fmt.Printf("Export failed: %v\n", err)
+	}
 }
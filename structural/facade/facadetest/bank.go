@@ -0,0 +1,273 @@
+package facadetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go-design-patterns/structural/facade"
+)
+
+// ErrInjected is the error of a Fault that does not set its own.
+var ErrInjected = errors.New("injected fault")
+
+// Fault makes calls of one operation of a fake bank fail or stall. Faults
+// are checked before the call reaches the bank, so a failed call has no
+// side effects.
+type Fault struct {
+	Op facade.BankOperation
+	// Err is the cause of the failure; nil means ErrInjected unless only
+	// Delay is set.
+	Err error
+	// Retryable marks the failure as one that may pass on its own.
+	Retryable bool
+	// Delay stalls the call, failing it with the context's error if the
+	// context ends first.
+	Delay time.Duration
+	// Times limits the fault to the first calls; zero means every call.
+	Times int
+}
+
+// account is a customer account a fake bank opens.
+type account struct {
+	accountNo string
+	pin       string
+	opening   string
+}
+
+// BankBuilder describes a fake bank for a Harness. Its accounts live in the
+// harness backend, so the facade moves real ledger balances.
+type BankBuilder struct {
+	name        string
+	currency    string
+	noReceiving bool
+	accounts    []account
+	faults      []Fault
+}
+
+// NewBank starts a fake THB bank that can receive transfers.
+func NewBank(name string) *BankBuilder {
+	return &BankBuilder{name: name, currency: "THB"}
+}
+
+// Currency sets the currency of the bank's accounts.
+func (b *BankBuilder) Currency(code string) *BankBuilder {
+	b.currency = code
+	return b
+}
+
+// Account opens an account with an opening balance given as a decimal in
+// the bank's currency; an empty opening balance leaves it empty.
+func (b *BankBuilder) Account(accountNo, pin, opening string) *BankBuilder {
+	b.accounts = append(b.accounts, account{accountNo: accountNo, pin: pin, opening: opening})
+	return b
+}
+
+// Fail injects faults from the start.
+func (b *BankBuilder) Fail(faults ...Fault) *BankBuilder {
+	b.faults = append(b.faults, faults...)
+	return b
+}
+
+// WithoutReceiving makes the bank's APIs lack the Receiver methods.
+func (b *BankBuilder) WithoutReceiving() *BankBuilder {
+	b.noReceiving = true
+	return b
+}
+
+// build opens the accounts of the bank on backend.
+func (b *BankBuilder) build(backend *facade.Backend) (*Bank, error) {
+	for _, acc := range b.accounts {
+		opening, err := facade.NewMoney(0, b.currency)
+		if err != nil {
+			return nil, err
+		}
+		if acc.opening != "" {
+			if opening, err = facade.ParseMoney(acc.opening, b.currency); err != nil {
+				return nil, err
+			}
+		}
+		if err := backend.OpenAccount(b.name, acc.accountNo, acc.pin, opening); err != nil {
+			return nil, fmt.Errorf("open %s/%s: %w", b.name, acc.accountNo, err)
+		}
+	}
+	bank := &Bank{
+		name:        b.name,
+		currency:    b.currency,
+		noReceiving: b.noReceiving,
+		backend:     backend,
+		calls:       make(map[facade.BankOperation]int),
+	}
+	bank.Inject(b.faults...)
+	return bank, nil
+}
+
+// injectedFault is a Fault and the number of calls it has hit.
+type injectedFault struct {
+	Fault
+	hits int
+}
+
+// Bank is a fake bank of a Harness. Its APIs share faults and call counts,
+// and it is safe for concurrent use.
+type Bank struct {
+	name        string
+	currency    string
+	noReceiving bool
+	backend     *facade.Backend
+
+	mu     sync.Mutex
+	faults []*injectedFault
+	calls  map[facade.BankOperation]int
+}
+
+// Name returns the name the bank is registered under.
+func (b *Bank) Name() string {
+	return b.name
+}
+
+// Inject adds faults to the bank.
+func (b *Bank) Inject(faults ...Fault) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, fault := range faults {
+		b.faults = append(b.faults, &injectedFault{Fault: fault})
+	}
+}
+
+// Calls returns how often op was called on the bank's APIs, including
+// calls that failed.
+func (b *Bank) Calls(op facade.BankOperation) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.calls[op]
+}
+
+// Factory creates the bank's APIs.
+func (b *Bank) Factory() facade.BankFactory {
+	return func(accountNo, bank, pin string) facade.BankApi {
+		inner := facade.NewKBankApi(accountNo, bank, pin, b.backend)
+		inner.Currency = b.currency
+		api := &fakeApi{bank: b, inner: inner}
+		if b.noReceiving {
+			return api
+		}
+		return &fakeReceiverApi{fakeApi: api}
+	}
+}
+
+// intercept counts a call of op and applies the first matching fault.
+func (b *Bank) intercept(ctx context.Context, op facade.BankOperation) error {
+	b.mu.Lock()
+	b.calls[op]++
+	var f Fault
+	matched := false
+	for _, injected := range b.faults {
+		if injected.Op == op && (injected.Times == 0 || injected.hits < injected.Times) {
+			injected.hits++
+			f, matched = injected.Fault, true
+			break
+		}
+	}
+	b.mu.Unlock()
+
+	if !matched {
+		return nil
+	}
+	if f.Delay > 0 {
+		timer := time.NewTimer(f.Delay)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return &facade.BankError{Bank: b.name, Op: op, Retryable: true, Err: ctx.Err()}
+		case <-timer.C:
+		}
+		if f.Err == nil {
+			return nil
+		}
+	}
+	err := f.Err
+	if err == nil {
+		err = ErrInjected
+	}
+	return &facade.BankError{Bank: b.name, Op: op, Retryable: f.Retryable, Err: err}
+}
+
+// fakeApi is a bank API of a fake bank.
+type fakeApi struct {
+	bank  *Bank
+	inner *facade.KBankApi
+}
+
+func (a *fakeApi) Login(ctx context.Context) error {
+	if err := a.bank.intercept(ctx, facade.OpLogin); err != nil {
+		return err
+	}
+	return a.inner.Login(ctx)
+}
+
+func (a *fakeApi) IsLoggedIn() bool {
+	return a.inner.IsLoggedIn()
+}
+
+func (a *fakeApi) Transfer(ctx context.Context, toAcc, toBank string, amount facade.Money) (string, error) {
+	if err := a.bank.intercept(ctx, facade.OpTransfer); err != nil {
+		return "", err
+	}
+	return a.inner.Transfer(ctx, toAcc, toBank, amount)
+}
+
+func (a *fakeApi) Confirm(ctx context.Context, confToken string) error {
+	if err := a.bank.intercept(ctx, facade.OpConfirm); err != nil {
+		return err
+	}
+	return a.inner.Confirm(ctx, confToken)
+}
+
+func (a *fakeApi) Cancel(ctx context.Context, confToken string) error {
+	if err := a.bank.intercept(ctx, facade.OpCancel); err != nil {
+		return err
+	}
+	return a.inner.Cancel(ctx, confToken)
+}
+
+func (a *fakeApi) CollectFee(ctx context.Context, ref string, fee facade.Money) error {
+	if err := a.bank.intercept(ctx, facade.OpFee); err != nil {
+		return err
+	}
+	return a.inner.CollectFee(ctx, ref, fee)
+}
+
+func (a *fakeApi) Balance(ctx context.Context) (facade.Money, error) {
+	if err := a.bank.intercept(ctx, facade.OpBalance); err != nil {
+		return facade.Money{}, err
+	}
+	return a.inner.Balance(ctx)
+}
+
+// fakeReceiverApi is a bank API of a fake bank that receives transfers.
+type fakeReceiverApi struct {
+	*fakeApi
+}
+
+func (a *fakeReceiverApi) AccountCurrency() string {
+	return a.inner.AccountCurrency()
+}
+
+func (a *fakeReceiverApi) Credit(ctx context.Context, ref string, amount facade.Money) error {
+	if err := a.bank.intercept(ctx, facade.OpCredit); err != nil {
+		return err
+	}
+	return a.inner.Credit(ctx, ref, amount)
+}
+
+func (a *fakeReceiverApi) Reverse(ctx context.Context, ref string) error {
+	if err := a.bank.intercept(ctx, facade.OpReverse); err != nil {
+		return err
+	}
+	return a.inner.Reverse(ctx, ref)
+}
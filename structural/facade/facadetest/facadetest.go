@@ -0,0 +1,125 @@
+// Package facadetest provides a harness for checking the bank transfer
+// facade: fake banks with injectable faults, a table of the failure branches
+// of Transfer, ledger invariants such as money conservation, a randomized
+// property checker and a concurrent stress runner.
+//
+// The checks return errors instead of taking a *testing.T, so they can be
+// driven from tests, from Example or from a program built with -race.
+package facadetest
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go-design-patterns/structural/facade"
+)
+
+// Harness is a facade over fake banks sharing one backend.
+type Harness struct {
+	Backend *facade.Backend
+	Facade  *facade.BankTransferFacade
+
+	banks map[string]*Bank
+}
+
+// pinHashIterations keeps the PIN hashes of harness accounts cheap, since
+// the properties and stress runs log in thousands of times.
+const pinHashIterations = 1000
+
+// NewHarness builds banks on a fresh backend and creates a facade with only
+// those banks registered. opts are applied after the harness's own options,
+// so they can replace them.
+func NewHarness(banks []*BankBuilder, opts ...facade.Option) (*Harness, error) {
+	h := &Harness{
+		Backend: facade.NewBackend(facade.NewMemoryCredentialStore(), facade.WithPinHashIterations(pinHashIterations)),
+		banks:   make(map[string]*Bank),
+	}
+
+	facadeOpts := []facade.Option{facade.WithBackend(h.Backend), facade.WithoutDefaultBanks()}
+	for _, builder := range banks {
+		bank, err := builder.build(h.Backend)
+		if err != nil {
+			return nil, err
+		}
+		h.banks[bank.name] = bank
+		facadeOpts = append(facadeOpts, facade.WithBank(bank.name, bank.Factory()))
+	}
+
+	f, err := facade.NewBankTransferFacade(append(facadeOpts, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+	h.Facade = f
+	return h, nil
+}
+
+// Bank returns the fake bank registered under name, or nil.
+func (h *Harness) Bank(name string) *Bank {
+	return h.banks[name]
+}
+
+// Balance returns the ledger balance of a customer account.
+func (h *Harness) Balance(bank, accountNo string) (facade.Money, error) {
+	return h.Backend.Ledger.Balance(facade.AccountID{Bank: bank, AccountNo: accountNo})
+}
+
+// Standard accounts of StandardBanks.
+var (
+	AlphaMain  = Endpoint{Bank: "Alpha", AccountNo: "1001", Pin: "1111"}
+	AlphaSpare = Endpoint{Bank: "Alpha", AccountNo: "1002", Pin: "2222"}
+	BetaMain   = Endpoint{Bank: "Beta", AccountNo: "2001", Pin: "3333"}
+	BetaSpare  = Endpoint{Bank: "Beta", AccountNo: "2002", Pin: "4444"}
+	GammaMain  = Endpoint{Bank: "Gamma", AccountNo: "3001", Pin: "5555"}
+	SinkMain   = Endpoint{Bank: "Sink", AccountNo: "4001", Pin: "6666"}
+)
+
+// Endpoint is an account of a fake bank with its PIN.
+type Endpoint struct {
+	Bank      string
+	AccountNo string
+	Pin       string
+}
+
+// StandardBanks returns builders for the banks the scenarios, properties and
+// stress runs use: Alpha and Beta hold THB, Gamma holds USD and Sink cannot
+// receive transfers. The main accounts start with 1,000.00, the spare ones
+// empty.
+func StandardBanks() []*BankBuilder {
+	return []*BankBuilder{
+		NewBank("Alpha").Account(AlphaMain.AccountNo, AlphaMain.Pin, "1000.00").Account(AlphaSpare.AccountNo, AlphaSpare.Pin, ""),
+		NewBank("Beta").Account(BetaMain.AccountNo, BetaMain.Pin, "1000.00").Account(BetaSpare.AccountNo, BetaSpare.Pin, ""),
+		NewBank("Gamma").Currency("USD").Account(GammaMain.AccountNo, GammaMain.Pin, "1000.00"),
+		NewBank("Sink").WithoutReceiving().Account(SinkMain.AccountNo, SinkMain.Pin, "1000.00"),
+	}
+}
+
+// Example runs every check of the package once.
+func Example() {
+	ctx := context.Background()
+
+	failed := 0
+	scenarios := TransferScenarios()
+	for _, scenario := range scenarios {
+		if err := scenario.Run(ctx); err != nil {
+			failed++
+			fmt.Printf("Scenario %q: %v\n", scenario.Name, err)
+		}
+	}
+	fmt.Printf("Transfer scenarios: %d of %d passed\n", len(scenarios)-failed, len(scenarios))
+
+	props := PropertyConfig{Seed: 42, Transfers: 200}
+	if err := CheckProperties(ctx, props); err != nil {
+		fmt.Printf("Properties failed: %v\n", err)
+	} else {
+		fmt.Printf("Properties held for %d random transfers (seed %d)\n", props.Transfers, props.Seed)
+	}
+
+	report, err := Stress(ctx, StressConfig{Workers: 8, Transfers: 400, Seed: 7})
+	if err != nil {
+		fmt.Printf("Stress failed: %v\n", err)
+		return
+	}
+	fmt.Printf("Stress: %d committed, %d not committed by %d workers in %s\n",
+		report.Committed, report.NotCommitted, report.Workers, report.Duration.Round(time.Millisecond))
+}
@@ -0,0 +1,174 @@
+package facadetest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go-design-patterns/structural/facade"
+	"go-design-patterns/structural/facade/facadetest"
+)
+
+func TestTransferScenarios(t *testing.T) {
+	for _, scenario := range facadetest.TransferScenarios() {
+		t.Run(scenario.Name, func(t *testing.T) {
+			t.Parallel()
+			if err := scenario.Run(context.Background()); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestProperties(t *testing.T) {
+	transfers := 300
+	if testing.Short() {
+		transfers = 50
+	}
+	for _, seed := range []uint64{1, 42, 2024} {
+		config := facadetest.PropertyConfig{Seed: seed, Transfers: transfers}
+		if err := facadetest.CheckProperties(context.Background(), config); err != nil {
+			t.Errorf("seed %d: %v", seed, err)
+		}
+	}
+}
+
+func TestPropertiesWithSessionReuse(t *testing.T) {
+	config := facadetest.PropertyConfig{
+		Seed:      7,
+		Transfers: 100,
+		Options:   []facade.Option{facade.WithSessionReuse()},
+	}
+	if err := facadetest.CheckProperties(context.Background(), config); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestStress is most useful under go test -race.
+func TestStress(t *testing.T) {
+	config := facadetest.StressConfig{Workers: 8, Transfers: 400, Seed: 7}
+	if testing.Short() {
+		config.Transfers = 80
+	}
+	report, err := facadetest.Stress(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Committed == 0 {
+		t.Fatalf("no transfer committed: %+v", report)
+	}
+	if got := report.Committed + report.NotCommitted; got != config.Transfers {
+		t.Errorf("report covers %d transfers, want %d", got, config.Transfers)
+	}
+	t.Logf("%d committed, %d not committed in %s", report.Committed, report.NotCommitted, report.Duration)
+}
+
+// TestCheckTransferEffectDetectsViolations makes sure the invariant used by
+// the scenarios and properties is not vacuous.
+func TestCheckTransferEffectDetectsViolations(t *testing.T) {
+	h, err := facadetest.NewHarness(facadetest.StandardBanks())
+	if err != nil {
+		t.Fatal(err)
+	}
+	balances, err := facadetest.Snapshot(h.Backend.Ledger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	amount, _ := facade.NewMoney(10000, "THB")
+	claimed := facade.Receipt{
+		TransactionID: "tx-claimed",
+		FromBank:      facadetest.AlphaMain.Bank,
+		FromAccount:   facadetest.AlphaMain.AccountNo,
+		ToBank:        facadetest.BetaMain.Bank,
+		ToAccount:     facadetest.BetaMain.AccountNo,
+		Total:         amount,
+		Credited:      amount,
+		Status:        facade.StateCommitted,
+	}
+	if err := facadetest.CheckTransferEffect(balances, balances, claimed); !errors.Is(err, facadetest.ErrInvariant) {
+		t.Errorf("committed receipt without balance changes: %v, want ErrInvariant", err)
+	}
+	if err := facadetest.CheckTransferEffect(balances, balances, facade.Receipt{}); err != nil {
+		t.Errorf("rejected transfer without balance changes: %v", err)
+	}
+}
+
+func TestFaultsApplyInOrder(t *testing.T) {
+	h, err := facadetest.NewHarness(facadetest.StandardBanks())
+	if err != nil {
+		t.Fatal(err)
+	}
+	errClosed := errors.New("account closed")
+	bank := h.Bank("Alpha")
+	bank.Inject(
+		facadetest.Fault{Op: facade.OpBalance, Retryable: true, Times: 1},
+		facadetest.Fault{Op: facade.OpBalance, Err: errClosed, Times: 1},
+		facadetest.Fault{Op: facade.OpBalance, Delay: time.Millisecond, Times: 1},
+	)
+	from := facadetest.AlphaMain
+	api := bank.Factory()(from.AccountNo, from.Bank, from.Pin)
+	if err := api.Login(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	checks := []func(err error) bool{
+		func(err error) bool { return errors.Is(err, facadetest.ErrInjected) && facade.IsTransient(err) },
+		func(err error) bool { return errors.Is(err, errClosed) && !facade.IsTransient(err) },
+		func(err error) bool { return err == nil }, // only delayed
+		func(err error) bool { return err == nil }, // every fault used up
+	}
+	for i, check := range checks {
+		if _, err := api.Balance(context.Background()); !check(err) {
+			t.Errorf("call %d: unexpected error %v", i+1, err)
+		}
+	}
+	if calls := bank.Calls(facade.OpBalance); calls != len(checks) {
+		t.Errorf("%d balance calls counted, want %d", calls, len(checks))
+	}
+	if calls := h.Bank("Beta").Calls(facade.OpBalance); calls != 0 {
+		t.Errorf("faults of Alpha counted %d calls on Beta", calls)
+	}
+}
+
+func TestDelayFailsWithContext(t *testing.T) {
+	h, err := facadetest.NewHarness(facadetest.StandardBanks())
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Bank("Alpha").Inject(facadetest.Fault{Op: facade.OpBalance, Delay: time.Hour})
+	from := facadetest.AlphaMain
+	api := h.Bank("Alpha").Factory()(from.AccountNo, from.Bank, from.Pin)
+	if err := api.Login(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := api.Balance(ctx); !errors.Is(err, context.DeadlineExceeded) || !facade.IsTransient(err) {
+		t.Errorf("stalled call = %v, want a retryable context.DeadlineExceeded", err)
+	}
+}
+
+// TestCheckSettledDetectsHeldFunds makes sure a hold the source bank refuses
+// to release is reported, while the money is still conserved.
+func TestCheckSettledDetectsHeldFunds(t *testing.T) {
+	h, err := facadetest.NewHarness(facadetest.StandardBanks())
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Bank("Alpha").Inject(facadetest.Fault{Op: facade.OpCancel})
+	h.Bank("Beta").Inject(facadetest.Fault{Op: facade.OpCredit})
+
+	from, to := facadetest.AlphaMain, facadetest.BetaMain
+	amount, _ := facade.ParseMoney("10.00", "THB")
+	if _, err := h.Facade.Transfer(context.Background(), from.AccountNo, from.Pin, from.Bank, to.AccountNo, to.Bank, amount); err == nil {
+		t.Fatal("transfer with a refused credit succeeded")
+	}
+	if err := facadetest.CheckSettled(h.Backend.Ledger); !errors.Is(err, facadetest.ErrInvariant) {
+		t.Errorf("CheckSettled = %v, want ErrInvariant for the held funds", err)
+	}
+	if err := facadetest.CheckConservation(h.Backend.Ledger); err != nil {
+		t.Error(err)
+	}
+}
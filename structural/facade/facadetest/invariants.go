@@ -0,0 +1,123 @@
+package facadetest
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"go-design-patterns/structural/facade"
+)
+
+// ErrInvariant is wrapped by every invariant violation.
+var ErrInvariant = errors.New("invariant violated")
+
+// Balances is a snapshot of every ledger balance.
+type Balances map[facade.AccountID]facade.Money
+
+// Snapshot returns the balances of every account of ledger.
+func Snapshot(ledger *facade.Ledger) (Balances, error) {
+	balances := make(Balances)
+	for _, id := range ledger.Accounts() {
+		balance, err := ledger.Balance(id)
+		if err != nil {
+			return nil, err
+		}
+		balances[id] = balance
+	}
+	return balances, nil
+}
+
+// delta returns how much the balance of id changed from before to after,
+// in minor units.
+func (after Balances) delta(before Balances, id facade.AccountID) int64 {
+	return after[id].Minor() - before[id].Minor()
+}
+
+// CheckConservation checks that money is only ever moved: the postings of
+// ledger replay to its balances, the balances of every currency sum to zero
+// across all accounts (deposits come from the banks' cash accounts) and no
+// customer account is overdrawn.
+func CheckConservation(ledger *facade.Ledger) error {
+	if err := ledger.Verify(); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvariant, err)
+	}
+	balances, err := Snapshot(ledger)
+	if err != nil {
+		return err
+	}
+
+	sums := make(map[string]int64)
+	for id, balance := range balances {
+		sums[balance.Currency()] += balance.Minor()
+		if !id.IsSystem() && balance.IsNegative() {
+			return fmt.Errorf("%w: customer account %s is overdrawn: %s", ErrInvariant, id, balance)
+		}
+	}
+	for currency, sum := range sums {
+		if sum != 0 {
+			return fmt.Errorf("%w: %s balances sum to %d minor units, not 0", ErrInvariant, currency, sum)
+		}
+	}
+	return nil
+}
+
+// CheckSettled checks that no funds are left on hold, as must be the case
+// once every transfer has finished without needing manual attention.
+func CheckSettled(ledger *facade.Ledger) error {
+	balances, err := Snapshot(ledger)
+	if err != nil {
+		return err
+	}
+	var held []string
+	for id, balance := range balances {
+		if id.IsSystem() && strings.HasSuffix(id.AccountNo, ":suspense") && !balance.IsZero() {
+			held = append(held, fmt.Sprintf("%s holds %s", id, balance))
+		}
+	}
+	if len(held) > 0 {
+		sort.Strings(held)
+		return fmt.Errorf("%w: funds left on hold: %s", ErrInvariant, strings.Join(held, ", "))
+	}
+	return nil
+}
+
+// CheckTransferEffect checks the customer balances after a transfer
+// against those before it: a committed transfer takes its total from the
+// source and gives the credited amount to the destination, any other
+// outcome moves nothing, and no other customer account changes. A receipt
+// without a transaction ID stands for a transfer rejected before it started.
+func CheckTransferEffect(before, after Balances, receipt facade.Receipt) error {
+	source := facade.AccountID{Bank: receipt.FromBank, AccountNo: receipt.FromAccount}
+	destination := facade.AccountID{Bank: receipt.ToBank, AccountNo: receipt.ToAccount}
+	committed := receipt.TransactionID != "" && receipt.Status == facade.StateCommitted
+
+	for id := range union(before, after) {
+		if id.IsSystem() {
+			continue
+		}
+		var want int64
+		if committed && id == source {
+			want -= receipt.Total.Minor()
+		}
+		if committed && id == destination {
+			want += receipt.Credited.Minor()
+		}
+		if got := after.delta(before, id); got != want {
+			return fmt.Errorf("%w: %s changed by %d minor units, want %d after %s transfer %s",
+				ErrInvariant, id, got, want, receipt.Status, receipt.TransactionID)
+		}
+	}
+	return nil
+}
+
+func union(a, b Balances) map[facade.AccountID]bool {
+	ids := make(map[facade.AccountID]bool, len(a))
+	for id := range a {
+		ids[id] = true
+	}
+	for id := range b {
+		ids[id] = true
+	}
+	return ids
+}
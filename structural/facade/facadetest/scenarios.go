@@ -0,0 +1,339 @@
+package facadetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go-design-patterns/structural/facade"
+)
+
+// TransferCall is the arguments of a call to Transfer.
+type TransferCall struct {
+	From   Endpoint
+	To     Endpoint
+	Amount facade.Money
+	// Key, if set, is passed as the idempotency key.
+	Key string
+}
+
+func (c TransferCall) run(ctx context.Context, f *facade.BankTransferFacade) (facade.Receipt, error) {
+	var opts []facade.TransferOption
+	if c.Key != "" {
+		opts = append(opts, facade.WithIdempotencyKey(c.Key))
+	}
+	return f.Transfer(ctx, c.From.AccountNo, c.From.Pin, c.From.Bank, c.To.AccountNo, c.To.Bank, c.Amount, opts...)
+}
+
+// Scenario is one branch of Transfer: how to reach it on the standard banks
+// and the outcome it must have.
+type Scenario struct {
+	Name string
+	// Options configure the facade of the scenario's harness.
+	Options []facade.Option
+	// Faults are injected into the standard banks, by bank name.
+	Faults map[string][]Fault
+	// Before runs on the harness before the call, e.g. to use up an
+	// idempotency key.
+	Before func(ctx context.Context, h *Harness) error
+	// Cancelled makes the call with a cancelled context.
+	Cancelled bool
+	Call      TransferCall
+
+	// WantErr is matched with errors.Is; nil means the call must succeed.
+	WantErr error
+	// WantStep and WantState are the step and state of the TransferError
+	// of transfers that stopped after they started.
+	WantStep  string
+	WantState facade.TransferState
+	// WantReplay means the call returns the outcome of an earlier transfer
+	// and must not move money.
+	WantReplay bool
+}
+
+// Run executes the scenario on a fresh harness and checks its outcome, the
+// balances it leaves and the ledger invariants.
+func (s Scenario) Run(ctx context.Context) error {
+	h, err := NewHarness(StandardBanks(), s.Options...)
+	if err != nil {
+		return fmt.Errorf("harness: %w", err)
+	}
+	for bank, faults := range s.Faults {
+		h.Bank(bank).Inject(faults...)
+	}
+	if s.Before != nil {
+		if err := s.Before(ctx, h); err != nil {
+			return fmt.Errorf("before: %w", err)
+		}
+	}
+	before, err := Snapshot(h.Backend.Ledger)
+	if err != nil {
+		return err
+	}
+
+	callCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if s.Cancelled {
+		cancel()
+	}
+	receipt, err := s.Call.run(callCtx, h.Facade)
+
+	switch {
+	case s.WantErr == nil && err != nil:
+		return fmt.Errorf("unexpected error: %w", err)
+	case s.WantErr != nil && !errors.Is(err, s.WantErr):
+		return fmt.Errorf("error %v does not match %v", err, s.WantErr)
+	}
+	var transferErr *facade.TransferError
+	if errors.As(err, &transferErr) {
+		if transferErr.Step != s.WantStep || transferErr.State != s.WantState {
+			return fmt.Errorf("stopped at %s in state %s, want %s in state %s",
+				transferErr.Step, transferErr.State, s.WantStep, s.WantState)
+		}
+	} else if s.WantStep != "" {
+		return fmt.Errorf("error %v is not a TransferError at step %s", err, s.WantStep)
+	}
+	if s.WantState != "" && receipt.Status != s.WantState {
+		return fmt.Errorf("receipt status %s, want %s", receipt.Status, s.WantState)
+	}
+
+	if err := CheckConservation(h.Backend.Ledger); err != nil {
+		return err
+	}
+	// A transfer whose compensation failed leaves its hold for manual repair
+	if receipt.Status == facade.StateFailed && receipt.Reference != "" {
+		return nil
+	}
+	if err := CheckSettled(h.Backend.Ledger); err != nil {
+		return err
+	}
+	after, err := Snapshot(h.Backend.Ledger)
+	if err != nil {
+		return err
+	}
+	if s.WantReplay {
+		receipt = facade.Receipt{}
+	}
+	return CheckTransferEffect(before, after, receipt)
+}
+
+// brokenHistory is a history repository that cannot store receipts.
+type brokenHistory struct {
+	facade.HistoryRepository
+}
+
+func (brokenHistory) Add(facade.Receipt) error {
+	return ErrInjected
+}
+
+// brokenTransferStore is a transfer store that cannot save.
+type brokenTransferStore struct {
+	facade.TransferStore
+}
+
+func (brokenTransferStore) Save(facade.TransferRecord) error {
+	return ErrInjected
+}
+
+// thb returns a THB amount of the scenario table from whole baht. THB is
+// a known currency, so NewMoney cannot fail.
+func thb(baht int64) facade.Money {
+	money, _ := facade.NewMoney(baht*100, "THB")
+	return money
+}
+
+// TransferScenarios returns a scenario for every way Transfer can end,
+// from validation failures before any bank is called to compensations
+// that cannot be completed.
+func TransferScenarios() []Scenario {
+	call := TransferCall{From: AlphaMain, To: BetaMain, Amount: thb(100)}
+	with := func(change func(*TransferCall)) TransferCall {
+		c := call
+		change(&c)
+		return c
+	}
+	limit := thb(50)
+	usdRate, _ := facade.ParseRate("THB", "USD", "0.028")
+
+	return []Scenario{
+		{
+			Name:      "committed",
+			Call:      call,
+			WantState: facade.StateCommitted,
+		},
+		{
+			Name:      "committed across currencies",
+			Options:   []facade.Option{facade.WithRateProvider(facade.NewStaticRates(usdRate))},
+			Call:      with(func(c *TransferCall) { c.To = GammaMain }),
+			WantState: facade.StateCommitted,
+		},
+		{
+			Name:    "amount without currency",
+			Call:    with(func(c *TransferCall) { c.Amount = facade.Money{} }),
+			WantErr: facade.ErrUnknownCurrency,
+		},
+		{
+			Name:    "zero amount",
+			Call:    with(func(c *TransferCall) { c.Amount = thb(0) }),
+			WantErr: facade.ErrNonPositiveAmount,
+		},
+		{
+			Name:    "malformed destination account",
+			Call:    with(func(c *TransferCall) { c.To.AccountNo = "20O1" }),
+			WantErr: facade.ErrInvalidAccount,
+		},
+		{
+			Name:    "unknown source bank",
+			Call:    with(func(c *TransferCall) { c.From.Bank = "Nowhere" }),
+			WantErr: facade.ErrUnsupportedBank,
+		},
+		{
+			Name:    "unknown destination bank",
+			Call:    with(func(c *TransferCall) { c.To.Bank = "Nowhere" }),
+			WantErr: facade.ErrUnsupportedBank,
+		},
+		{
+			Name:    "rejected by a rule",
+			Options: []facade.Option{facade.WithRules(facade.MaxAmount{Limit: limit})},
+			Call:    call,
+			WantErr: facade.ErrTransferRejected,
+		},
+		{
+			Name:    "destination cannot receive",
+			Call:    with(func(c *TransferCall) { c.To = SinkMain }),
+			WantErr: facade.ErrCannotReceive,
+		},
+		{
+			Name:    "no exchange rate",
+			Call:    with(func(c *TransferCall) { c.To = GammaMain }),
+			WantErr: facade.ErrRateUnavailable,
+		},
+		{
+			Name:      "cancelled before starting",
+			Cancelled: true,
+			Call:      call,
+			WantErr:   context.Canceled,
+		},
+		{
+			Name:    "transfer store unavailable",
+			Options: []facade.Option{facade.WithTransferStore(brokenTransferStore{})},
+			Call:    call,
+			WantErr: ErrInjected,
+		},
+		{
+			Name:      "wrong PIN",
+			Call:      with(func(c *TransferCall) { c.From.Pin = "0000" }),
+			WantErr:   facade.ErrAuthFailed,
+			WantStep:  "login",
+			WantState: facade.StateFailed,
+		},
+		{
+			Name:      "login unavailable",
+			Faults:    map[string][]Fault{"Alpha": {{Op: facade.OpLogin, Retryable: true}}},
+			Call:      call,
+			WantErr:   ErrInjected,
+			WantStep:  "login",
+			WantState: facade.StateFailed,
+		},
+		{
+			Name:      "insufficient funds",
+			Call:      with(func(c *TransferCall) { c.Amount = thb(5000) }),
+			WantErr:   facade.ErrInsufficientFunds,
+			WantStep:  "transfer",
+			WantState: facade.StateFailed,
+		},
+		{
+			Name:      "hold refused",
+			Faults:    map[string][]Fault{"Alpha": {{Op: facade.OpTransfer}}},
+			Call:      call,
+			WantErr:   ErrInjected,
+			WantStep:  "transfer",
+			WantState: facade.StateFailed,
+		},
+		{
+			Name:      "destination account missing",
+			Call:      with(func(c *TransferCall) { c.To.AccountNo = "2999" }),
+			WantErr:   facade.ErrAccountNotFound,
+			WantStep:  "credit",
+			WantState: facade.StateCompensated,
+		},
+		{
+			Name:      "credit refused",
+			Faults:    map[string][]Fault{"Beta": {{Op: facade.OpCredit}}},
+			Call:      call,
+			WantErr:   ErrInjected,
+			WantStep:  "credit",
+			WantState: facade.StateCompensated,
+		},
+		{
+			Name:      "credit timed out",
+			Options:   []facade.Option{facade.WithBankTimeout("Beta", 20*time.Millisecond)},
+			Faults:    map[string][]Fault{"Beta": {{Op: facade.OpCredit, Delay: time.Second}}},
+			Call:      call,
+			WantErr:   context.DeadlineExceeded,
+			WantStep:  "credit",
+			WantState: facade.StateCompensated,
+		},
+		{
+			Name:      "confirmation refused",
+			Faults:    map[string][]Fault{"Alpha": {{Op: facade.OpConfirm}}},
+			Call:      call,
+			WantErr:   ErrInjected,
+			WantStep:  "confirmation",
+			WantState: facade.StateCompensated,
+		},
+		{
+			Name: "hold cannot be released",
+			Faults: map[string][]Fault{
+				"Alpha": {{Op: facade.OpCancel}},
+				"Beta":  {{Op: facade.OpCredit}},
+			},
+			Call:      call,
+			WantErr:   ErrInjected,
+			WantStep:  "credit",
+			WantState: facade.StateFailed,
+		},
+		{
+			Name: "credit cannot be reversed",
+			Faults: map[string][]Fault{
+				"Alpha": {{Op: facade.OpConfirm}},
+				"Beta":  {{Op: facade.OpReverse}},
+			},
+			Call:      call,
+			WantErr:   ErrInjected,
+			WantStep:  "confirmation",
+			WantState: facade.StateFailed,
+		},
+		{
+			Name: "idempotency key reused for another transfer",
+			Before: func(ctx context.Context, h *Harness) error {
+				_, err := with(func(c *TransferCall) { c.Key = "invoice-1" }).run(ctx, h.Facade)
+				return err
+			},
+			Call: with(func(c *TransferCall) {
+				c.Key = "invoice-1"
+				c.Amount = thb(200)
+			}),
+			WantErr: facade.ErrIdempotencyKeyConflict,
+		},
+		{
+			Name: "idempotent retry",
+			Before: func(ctx context.Context, h *Harness) error {
+				_, err := with(func(c *TransferCall) { c.Key = "invoice-1" }).run(ctx, h.Facade)
+				return err
+			},
+			Call:       with(func(c *TransferCall) { c.Key = "invoice-1" }),
+			WantState:  facade.StateCommitted,
+			WantReplay: true,
+		},
+		{
+			Name:      "history unavailable",
+			Options:   []facade.Option{facade.WithHistoryRepository(brokenHistory{})},
+			Call:      call,
+			WantErr:   ErrInjected,
+			WantStep:  "history",
+			WantState: facade.StateCommitted,
+		},
+	}
+}
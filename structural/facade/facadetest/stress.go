@@ -0,0 +1,211 @@
+package facadetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"go-design-patterns/structural/facade"
+)
+
+// randomEndpoints are the accounts random transfers are made between. The
+// last one does not exist, so transfers to it are compensated.
+var randomEndpoints = []Endpoint{
+	AlphaMain, AlphaSpare, BetaMain, BetaSpare, GammaMain, SinkMain,
+	{Bank: "Beta", AccountNo: "2999", Pin: "0000"},
+}
+
+// randomFaultOps are the operations random faults hit. Cancel and Reverse
+// are left out so every compensation completes and no funds stay on hold.
+var randomFaultOps = []facade.BankOperation{facade.OpLogin, facade.OpTransfer, facade.OpCredit, facade.OpConfirm}
+
+// randomHarness creates the harness of random runs: the standard banks
+// with a 0.5% interbank fee and exchange rates between THB and USD.
+func randomHarness(opts []facade.Option) (*Harness, error) {
+	toUSD, _ := facade.ParseRate("THB", "USD", "0.028")
+	toTHB, _ := facade.ParseRate("USD", "THB", "35.50")
+	base := []facade.Option{
+		facade.WithFeePolicy(facade.InterbankFee{Policy: facade.PercentageFee{BasisPoints: 50}}),
+		facade.WithRateProvider(facade.NewStaticRates(toUSD, toTHB)),
+	}
+	return NewHarness(StandardBanks(), append(base, opts...)...)
+}
+
+// randomTransfer picks a transfer between random endpoints of up to 300.00
+// in the source currency and, one time in four, a fault for it.
+func randomTransfer(rng *rand.Rand) (TransferCall, *Fault, string) {
+	from := randomEndpoints[rng.IntN(len(randomEndpoints)-1)]
+	to := randomEndpoints[rng.IntN(len(randomEndpoints))]
+	currency := "THB"
+	if from.Bank == "Gamma" {
+		currency = "USD"
+	}
+	amount, _ := facade.NewMoney(1+rng.Int64N(30000), currency)
+	call := TransferCall{From: from, To: to, Amount: amount}
+
+	if rng.IntN(4) > 0 {
+		return call, nil, ""
+	}
+	fault := &Fault{Op: randomFaultOps[rng.IntN(len(randomFaultOps))], Times: 1}
+	bank := from.Bank
+	if fault.Op == facade.OpCredit {
+		bank = to.Bank
+	}
+	return call, fault, bank
+}
+
+// PropertyConfig configures CheckProperties.
+type PropertyConfig struct {
+	// Seed makes the run reproducible.
+	Seed uint64
+	// Transfers is the number of random transfers; zero means 100.
+	Transfers int
+	// Options configure the facade, e.g. to add retries or session reuse.
+	Options []facade.Option
+}
+
+// CheckProperties makes random transfers one after another, some of them
+// with injected faults, and checks after each that money is conserved,
+// nothing is left on hold and only the transfer's own accounts changed as
+// its receipt says. The error names the seed and transfer to reproduce a
+// failure with.
+func CheckProperties(ctx context.Context, config PropertyConfig) error {
+	if config.Transfers <= 0 {
+		config.Transfers = 100
+	}
+	h, err := randomHarness(config.Options)
+	if err != nil {
+		return err
+	}
+	rng := rand.New(rand.NewPCG(config.Seed, config.Seed))
+
+	for i := 1; i <= config.Transfers; i++ {
+		call, fault, bank := randomTransfer(rng)
+		if fault != nil {
+			h.Bank(bank).Inject(*fault)
+		}
+		before, err := Snapshot(h.Backend.Ledger)
+		if err != nil {
+			return err
+		}
+		receipt, transferErr := call.run(ctx, h.Facade)
+		if errors.Is(transferErr, context.Canceled) {
+			return transferErr
+		}
+
+		after, err := Snapshot(h.Backend.Ledger)
+		if err == nil {
+			err = errors.Join(
+				CheckConservation(h.Backend.Ledger),
+				CheckSettled(h.Backend.Ledger),
+				CheckTransferEffect(before, after, receipt),
+			)
+		}
+		if err != nil {
+			return fmt.Errorf("seed %d, transfer %d (%s/%s -> %s/%s %s): %w",
+				config.Seed, i, call.From.Bank, call.From.AccountNo, call.To.Bank, call.To.AccountNo, call.Amount, err)
+		}
+	}
+	return nil
+}
+
+// StressConfig configures Stress.
+type StressConfig struct {
+	// Workers is the number of goroutines making transfers; zero means 8.
+	Workers int
+	// Transfers is the total number of transfers; zero means 500.
+	Transfers int
+	// Seed makes the transfers each worker picks reproducible; their
+	// interleaving is not.
+	Seed uint64
+	// Options configure the facade, e.g. to add retries or session reuse.
+	Options []facade.Option
+}
+
+// StressReport summarizes a stress run.
+type StressReport struct {
+	Workers      int
+	Committed    int
+	NotCommitted int
+	Duration     time.Duration
+}
+
+// Stress makes random transfers from many goroutines at once, some with
+// injected faults, and then checks that money was conserved, nothing was
+// left on hold and every customer balance changed by exactly the committed
+// transfers. Run it in a binary built with -race to also check the facade
+// for data races.
+func Stress(ctx context.Context, config StressConfig) (StressReport, error) {
+	if config.Workers <= 0 {
+		config.Workers = 8
+	}
+	if config.Transfers <= 0 {
+		config.Transfers = 500
+	}
+	h, err := randomHarness(config.Options)
+	if err != nil {
+		return StressReport{}, err
+	}
+	before, err := Snapshot(h.Backend.Ledger)
+	if err != nil {
+		return StressReport{}, err
+	}
+
+	started := time.Now()
+	receipts := make([][]facade.Receipt, config.Workers)
+	var wg sync.WaitGroup
+	for w := 0; w < config.Workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rng := rand.New(rand.NewPCG(config.Seed, uint64(w)))
+			for i := w; i < config.Transfers; i += config.Workers {
+				call, fault, bank := randomTransfer(rng)
+				if fault != nil {
+					h.Bank(bank).Inject(*fault)
+				}
+				receipt, _ := call.run(ctx, h.Facade)
+				receipts[w] = append(receipts[w], receipt)
+			}
+		}()
+	}
+	wg.Wait()
+
+	report := StressReport{Workers: config.Workers, Duration: time.Since(started)}
+	expected := make(map[facade.AccountID]int64)
+	for _, list := range receipts {
+		for _, receipt := range list {
+			if receipt.Status != facade.StateCommitted {
+				report.NotCommitted++
+				continue
+			}
+			report.Committed++
+			expected[facade.AccountID{Bank: receipt.FromBank, AccountNo: receipt.FromAccount}] -= receipt.Total.Minor()
+			expected[facade.AccountID{Bank: receipt.ToBank, AccountNo: receipt.ToAccount}] += receipt.Credited.Minor()
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return report, err
+	}
+
+	if err := errors.Join(CheckConservation(h.Backend.Ledger), CheckSettled(h.Backend.Ledger)); err != nil {
+		return report, err
+	}
+	after, err := Snapshot(h.Backend.Ledger)
+	if err != nil {
+		return report, err
+	}
+	for id := range union(before, after) {
+		if id.IsSystem() {
+			continue
+		}
+		if got := after.delta(before, id); got != expected[id] {
+			return report, fmt.Errorf("%w: %s changed by %d minor units, committed transfers account for %d",
+				ErrInvariant, id, got, expected[id])
+		}
+	}
+	return report, nil
+}
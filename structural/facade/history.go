@@ -0,0 +1,269 @@
+package facade
+
+import (
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Receipt is what a caller gets back for a transfer, successful or not.
+type Receipt struct {
+	TransactionID string        `json:"transaction_id"`
+	Reference     string        `json:"reference,omitempty"`
+	FromBank      string        `json:"from_bank"`
+	FromAccount   string        `json:"from_account"`
+	ToBank        string        `json:"to_bank"`
+	ToAccount     string        `json:"to_account"`
+	Amount        Money         `json:"amount"`
+	Fee           Money         `json:"fee"`
+	Total         Money         `json:"total"`
+	Credited      Money         `json:"credited"`
+	Rate          string        `json:"rate,omitempty"`
+	Status        TransferState `json:"status"`
+	Error         string        `json:"error,omitempty"`
+	CreatedAt     time.Time     `json:"created_at"`
+	CompletedAt   time.Time     `json:"completed_at"`
+}
+
+// newReceipt builds the receipt of a finished transfer saga. Credited is
+// the amount the destination still holds: zero in its currency unless the
+// credit was made and not reversed.
+func newReceipt(record TransferRecord) Receipt {
+	fee := record.Fee
+	if fee.Currency() == "" {
+		fee = Money{currency: record.Amount.Currency()}
+	}
+	credited := Money{currency: record.Credit.Currency()}
+	if credited.currency == "" {
+		credited.currency = record.Amount.Currency()
+	}
+	if record.Credited {
+		credited = record.Credit
+	}
+	total, _ := record.Amount.Add(fee)
+	return Receipt{
+		TransactionID: record.ID,
+		Reference:     record.Reference,
+		FromBank:      record.FromBank,
+		FromAccount:   record.FromAccount,
+		ToBank:        record.ToBank,
+		ToAccount:     record.ToAccount,
+		Amount:        record.Amount,
+		Fee:           fee,
+		Total:         total,
+		Credited:      credited,
+		Rate:          record.Rate,
+		Status:        record.State,
+		Error:         record.Error,
+		CreatedAt:     record.CreatedAt,
+		CompletedAt:   record.UpdatedAt,
+	}
+}
+
+// HistoryFilter selects receipts. Zero-valued fields match everything.
+type HistoryFilter struct {
+	// Account matches the source or destination account number.
+	Account string
+	// Bank matches the source or destination bank; combined with Account
+	// both must match on the same side.
+	Bank string
+	// From and To bound CreatedAt to the half-open range [From, To).
+	From time.Time
+	To   time.Time
+	// Status matches the final transfer state.
+	Status TransferState
+}
+
+// Matches reports whether r passes the filter.
+func (q HistoryFilter) Matches(r Receipt) bool {
+	side := func(bank, account string) bool {
+		return (q.Bank == "" || q.Bank == bank) && (q.Account == "" || q.Account == account)
+	}
+	if !side(r.FromBank, r.FromAccount) && !side(r.ToBank, r.ToAccount) {
+		return false
+	}
+	if !q.From.IsZero() && r.CreatedAt.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && !r.CreatedAt.Before(q.To) {
+		return false
+	}
+	return q.Status == "" || q.Status == r.Status
+}
+
+// HistoryRepository stores receipts of finished transfers.
+type HistoryRepository interface {
+	Add(receipt Receipt) error
+	// Query returns matching receipts ordered by creation time.
+	Query(filter HistoryFilter) ([]Receipt, error)
+}
+
+// MemoryHistoryRepository keeps receipts in memory.
+type MemoryHistoryRepository struct {
+	mu       sync.RWMutex
+	receipts []Receipt
+}
+
+// NewMemoryHistoryRepository creates an empty in-memory history.
+func NewMemoryHistoryRepository() *MemoryHistoryRepository {
+	return &MemoryHistoryRepository{}
+}
+
+func (h *MemoryHistoryRepository) Add(receipt Receipt) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.receipts = append(h.receipts, receipt)
+	return nil
+}
+
+func (h *MemoryHistoryRepository) Query(filter HistoryFilter) ([]Receipt, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return queryReceipts(h.receipts, filter), nil
+}
+
+// queryReceipts returns the receipts matching filter ordered by creation time.
+func queryReceipts(receipts []Receipt, filter HistoryFilter) []Receipt {
+	var result []Receipt
+	for _, r := range receipts {
+		if filter.Matches(r) {
+			result = append(result, r)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].CreatedAt.Before(result[j].CreatedAt)
+	})
+	return result
+}
+
+// FileHistoryRepository keeps receipts in a JSON file so statements survive
+// process restarts. Every Add rewrites the whole file.
+type FileHistoryRepository struct {
+	mu       sync.RWMutex
+	path     string
+	receipts []Receipt
+}
+
+// NewFileHistoryRepository opens the history at path, loading existing
+// receipts if the file is present.
+func NewFileHistoryRepository(path string) (*FileHistoryRepository, error) {
+	h := &FileHistoryRepository{path: path}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return h, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read history file: %w", err)
+	}
+	if err := json.Unmarshal(data, &h.receipts); err != nil {
+		return nil, fmt.Errorf("decode history file: %w", err)
+	}
+	return h, nil
+}
+
+func (h *FileHistoryRepository) Add(receipt Receipt) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.receipts = append(h.receipts, receipt)
+	data, err := json.MarshalIndent(h.receipts, "", "  ")
+	if err == nil {
+		err = writeFileAtomic(h.path, data)
+	}
+	if err != nil {
+		// Keep memory consistent with what is on disk
+		h.receipts = h.receipts[:len(h.receipts)-1]
+		return fmt.Errorf("write history file: %w", err)
+	}
+	return nil
+}
+
+func (h *FileHistoryRepository) Query(filter HistoryFilter) ([]Receipt, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return queryReceipts(h.receipts, filter), nil
+}
+
+// WithHistoryRepository stores receipts in repo instead of memory.
+func WithHistoryRepository(repo HistoryRepository) Option {
+	return func(f *BankTransferFacade) error {
+		if repo == nil {
+			return errors.New("history repository must not be nil")
+		}
+		f.history = repo
+		return nil
+	}
+}
+
+// History returns the receipts matching filter.
+func (f *BankTransferFacade) History(filter HistoryFilter) ([]Receipt, error) {
+	return f.history.Query(filter)
+}
+
+// WriteStatementJSON writes receipts as an indented JSON array.
+func WriteStatementJSON(w io.Writer, receipts []Receipt) error {
+	if receipts == nil {
+		receipts = []Receipt{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(receipts)
+}
+
+// statementHeader is the column order of CSV statements.
+var statementHeader = []string{
+	"transaction_id", "reference", "created_at", "completed_at",
+	"from_bank", "from_account", "to_bank", "to_account",
+	"amount", "fee", "total", "currency", "credited", "credited_currency", "rate",
+	"status", "error",
+}
+
+// WriteStatementCSV writes receipts as CSV with a header row. Amounts are
+// plain decimals with the currency in its own column.
+func WriteStatementCSV(w io.Writer, receipts []Receipt) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(statementHeader); err != nil {
+		return err
+	}
+	for _, r := range receipts {
+		row := []string{
+			r.TransactionID, r.Reference,
+			r.CreatedAt.Format(time.RFC3339), r.CompletedAt.Format(time.RFC3339),
+			r.FromBank, r.FromAccount, r.ToBank, r.ToAccount,
+			r.Amount.Decimal(), r.Fee.Decimal(), r.Total.Decimal(), r.Amount.Currency(),
+			r.Credited.Decimal(), r.Credited.Currency(), r.Rate,
+			string(r.Status), r.Error,
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// receiptReference turns a bank confirmation token into a short reference
+// that can be shown to customers without exposing the token: it is built
+// from a truncated hash of the token, not the token itself.
+func receiptReference(bank, confToken string) string {
+	sum := sha256.Sum256([]byte(confToken))
+	digest := hex.EncodeToString(sum[:5])
+	prefix := bank
+	if len(prefix) > 2 {
+		prefix = prefix[:2]
+	}
+	return fmt.Sprintf("%s-%s", strings.ToUpper(prefix), strings.ToUpper(digest))
+}
@@ -0,0 +1,47 @@
+package facade
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReceiptReferenceHidesToken(t *testing.T) {
+	token := "a1b2c3d4e5f6a7b8c9d0"
+	ref := receiptReference("KBank", token)
+	if !strings.HasPrefix(ref, "KB-") || len(ref) != len("KB-")+10 {
+		t.Fatalf("reference %q, want KB- and 10 characters", ref)
+	}
+	if strings.Contains(strings.ToLower(ref), token[:4]) {
+		t.Errorf("reference %q exposes the start of token %q", ref, token)
+	}
+	if receiptReference("KBank", token) != ref {
+		t.Error("reference is not stable for the same token")
+	}
+}
+
+func TestReceiptCreditedOnlyAfterCredit(t *testing.T) {
+	amount, _ := ParseMoney("10.00", "THB")
+	fee, _ := ParseMoney("0.50", "THB")
+	credit, _ := ParseMoney("0.28", "USD")
+	tests := []struct {
+		name     string
+		state    TransferState
+		credited bool
+		want     string
+	}{
+		{name: "committed", state: StateCommitted, credited: true, want: "0.28 USD"},
+		{name: "compensated after a reversed credit", state: StateCompensated, want: "0.00 USD"},
+		{name: "failed before the credit", state: StateFailed, want: "0.00 USD"},
+		{name: "failed with an unreversed credit", state: StateFailed, credited: true, want: "0.28 USD"},
+	}
+	for _, tt := range tests {
+		record := TransferRecord{ID: "tx-1", Amount: amount, Fee: fee, Credit: credit, State: tt.state, Credited: tt.credited}
+		receipt := newReceipt(record)
+		if got := receipt.Credited.String(); got != tt.want {
+			t.Errorf("%s: credited %s, want %s", tt.name, got, tt.want)
+		}
+		if got := receipt.Total.String(); got != "10.50 THB" {
+			t.Errorf("%s: total %s, want 10.50 THB", tt.name, got)
+		}
+	}
+}
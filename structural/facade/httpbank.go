@@ -0,0 +1,308 @@
+package facade
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrTransferUncertain is returned when a transfer request got no answer
+// and the hold it may have placed could not be cancelled either. Whether
+// funds are held is unknown, so the transfer must not simply be retried.
+var ErrTransferUncertain = errors.New("transfer outcome unknown")
+
+// holdCancelTimeout bounds the cancel sent after a transfer got no answer.
+const holdCancelTimeout = 10 * time.Second
+
+// HTTPError is a non-2xx answer from a bank server.
+type HTTPError struct {
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("bank server returned %d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// Unwrap exposes the sentinel error matching the wire code, so callers can
+// use errors.Is the same way as with in-process banks.
+func (e *HTTPError) Unwrap() error {
+	for _, entry := range httpErrorCodes {
+		if entry.code == e.Code {
+			return entry.err
+		}
+	}
+	return nil
+}
+
+// Temporary reports whether the server was unavailable or rate limiting,
+// so the same request may succeed later.
+func (e *HTTPError) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// HTTPBankApi implements BankApi and Receiver by calling a BankServer.
+// Credits and reversals are made in a session of the clearing account, so
+// a bank server can only receive transfers once ClearingAccountNo and
+// ClearingPin are set.
+type HTTPBankApi struct {
+	BaseURL           string
+	Client            *http.Client
+	AccountNo         string
+	Bank              string
+	Pin               string
+	Currency          string
+	ClearingAccountNo string
+	ClearingPin       string
+
+	mu            sync.Mutex
+	token         string
+	expires       time.Time
+	clearingToken string
+}
+
+// NewHTTPBankApi creates a client for the bank server at baseURL. A nil
+// client means http.DefaultClient.
+func NewHTTPBankApi(baseURL string, client *http.Client, accountNo, bank, pin, currency string) *HTTPBankApi {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &HTTPBankApi{
+		BaseURL:   strings.TrimSuffix(baseURL, "/"),
+		Client:    client,
+		AccountNo: accountNo,
+		Bank:      bank,
+		Pin:       pin,
+		Currency:  currency,
+	}
+}
+
+// HTTPBankOption configures the clients created by HTTPBankFactory.
+type HTTPBankOption func(*HTTPBankApi)
+
+// WithClearingLogin makes clients credit and reverse in a session of the
+// bank server's clearing account.
+func WithClearingLogin(accountNo, pin string) HTTPBankOption {
+	return func(h *HTTPBankApi) {
+		h.ClearingAccountNo = accountNo
+		h.ClearingPin = pin
+	}
+}
+
+// HTTPBankFactory returns a BankFactory for registering a bank server with
+// the facade, e.g. facade.RegisterBank("KBank", HTTPBankFactory(srv.URL, "THB", srv.Client())).
+func HTTPBankFactory(baseURL, currency string, client *http.Client, opts ...HTTPBankOption) BankFactory {
+	return func(accountNo, bank, pin string) BankApi {
+		api := NewHTTPBankApi(baseURL, client, accountNo, bank, pin, currency)
+		for _, opt := range opts {
+			opt(api)
+		}
+		return api
+	}
+}
+
+func (h *HTTPBankApi) Login(ctx context.Context) error {
+	var resp loginResponse
+	if err := h.send(ctx, "", http.MethodPost, "/login", loginRequest{AccountNo: h.AccountNo, Pin: h.Pin}, &resp); err != nil {
+		return newBankError(h.Bank, OpLogin, err)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.token = resp.Token
+	h.expires = resp.ExpiresAt
+	return nil
+}
+
+// SessionExpiry returns when the server said the session ends.
+func (h *HTTPBankApi) SessionExpiry() time.Time {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.expires
+}
+
+// Logout ends the session on the server.
+func (h *HTTPBankApi) Logout(ctx context.Context) error {
+	if !h.IsLoggedIn() {
+		return nil
+	}
+	if err := h.call(ctx, http.MethodPost, "/logout", nil, nil); err != nil {
+		return newBankError(h.Bank, OpLogout, err)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.token = ""
+	h.expires = time.Time{}
+	return nil
+}
+
+// Transfer places a hold under a fresh request ID. If the server's answer
+// is lost, e.g. because ctx ended, the hold is cancelled by that ID before
+// the error is returned; only then is the failure reported as retryable.
+func (h *HTTPBankApi) Transfer(ctx context.Context, toAcc, toBank string, amount Money) (string, error) {
+	requestID, err := newToken()
+	if err != nil {
+		return "", newBankError(h.Bank, OpTransfer, err)
+	}
+	var resp confirmationRequest
+	err = h.call(ctx, http.MethodPost, "/transfer", transferRequest{ToAccount: toAcc, ToBank: toBank, Amount: amount, RequestID: requestID}, &resp)
+	if err == nil {
+		return resp.ConfirmationToken, nil
+	}
+
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) {
+		cancelCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), holdCancelTimeout)
+		defer cancel()
+		if cancelErr := h.call(cancelCtx, http.MethodPost, "/cancel", confirmationRequest{RequestID: requestID}, nil); cancelErr != nil {
+			return "", &BankError{Bank: h.Bank, Op: OpTransfer, Err: fmt.Errorf("%w: %v; cancel: %v", ErrTransferUncertain, err, cancelErr)}
+		}
+	}
+	return "", newBankError(h.Bank, OpTransfer, err)
+}
+
+func (h *HTTPBankApi) Confirm(ctx context.Context, confToken string) error {
+	err := h.call(ctx, http.MethodPost, "/confirm", confirmationRequest{ConfirmationToken: confToken}, nil)
+	return newBankError(h.Bank, OpConfirm, err)
+}
+
+func (h *HTTPBankApi) Cancel(ctx context.Context, confToken string) error {
+	err := h.call(ctx, http.MethodPost, "/cancel", confirmationRequest{ConfirmationToken: confToken}, nil)
+	return newBankError(h.Bank, OpCancel, err)
+}
+
+// CollectFee asks the server to book the fee of the committed transfer ref.
+func (h *HTTPBankApi) CollectFee(ctx context.Context, ref string, fee Money) error {
+	err := h.call(ctx, http.MethodPost, "/fee", feeRequest{Ref: ref, Amount: fee}, nil)
+	return newBankError(h.Bank, OpFee, err)
+}
+
+func (h *HTTPBankApi) Balance(ctx context.Context) (Money, error) {
+	var resp balanceResponse
+	if err := h.call(ctx, http.MethodGet, "/balance", nil, &resp); err != nil {
+		return Money{}, newBankError(h.Bank, OpBalance, err)
+	}
+	return resp.Balance, nil
+}
+
+// IsLoggedIn reports whether a session token was issued. The server remains
+// the authority on whether it is still valid.
+func (h *HTTPBankApi) IsLoggedIn() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.token != ""
+}
+
+func (h *HTTPBankApi) AccountCurrency() string {
+	return h.Currency
+}
+
+func (h *HTTPBankApi) Credit(ctx context.Context, ref string, amount Money) error {
+	err := h.clearingCall(ctx, "/credit", creditRequest{AccountNo: h.AccountNo, Ref: ref, Amount: &amount})
+	return newBankError(h.Bank, OpCredit, err)
+}
+
+func (h *HTTPBankApi) Reverse(ctx context.Context, ref string) error {
+	err := h.clearingCall(ctx, "/reverse", creditRequest{AccountNo: h.AccountNo, Ref: ref})
+	return newBankError(h.Bank, OpReverse, err)
+}
+
+// clearingCall posts body in a session of the clearing account, logging
+// it in first if needed and once more if the session has ended.
+func (h *HTTPBankApi) clearingCall(ctx context.Context, path string, body any) error {
+	if h.ClearingAccountNo == "" {
+		return fmt.Errorf("%w: no clearing login for %s", ErrNotLoggedIn, h.Bank)
+	}
+
+	h.mu.Lock()
+	token := h.clearingToken
+	h.mu.Unlock()
+
+	for attempt := 1; ; attempt++ {
+		if token == "" {
+			var resp loginResponse
+			login := loginRequest{AccountNo: h.ClearingAccountNo, Pin: h.ClearingPin}
+			if err := h.send(ctx, "", http.MethodPost, "/login", login, &resp); err != nil {
+				return fmt.Errorf("clearing login: %w", err)
+			}
+			token = resp.Token
+			h.mu.Lock()
+			h.clearingToken = token
+			h.mu.Unlock()
+		}
+		err := h.send(ctx, token, http.MethodPost, path, body, nil)
+		if attempt > 1 || !(errors.Is(err, ErrNotLoggedIn) || errors.Is(err, ErrSessionExpired)) {
+			return err
+		}
+		token = ""
+	}
+}
+
+// call sends body as JSON in the customer's session and decodes a
+// successful answer into out.
+func (h *HTTPBankApi) call(ctx context.Context, method, path string, body, out any) error {
+	h.mu.Lock()
+	token := h.token
+	h.mu.Unlock()
+	return h.send(ctx, token, method, path, body, out)
+}
+
+// send sends body as JSON, with token as bearer if set, and decodes a
+// successful answer into out.
+func (h *HTTPBankApi) send(ctx context.Context, token, method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, h.BaseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := h.Client.Do(req)
+	if err != nil {
+		// Surface context errors unwrapped from *url.Error
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var body errorBody
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || body.Code == "" {
+			body = errorBody{Code: codeInternal, Message: resp.Status}
+		}
+		return &HTTPError{StatusCode: resp.StatusCode, Code: body.Code, Message: body.Message}
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode bank response: %w", err)
+	}
+	return nil
+}
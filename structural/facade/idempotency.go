@@ -0,0 +1,300 @@
+package facade
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrIdempotencyKeyConflict is returned when a key is reused with different transfer parameters.
+	ErrIdempotencyKeyConflict = errors.New("idempotency key reused with different parameters")
+	// ErrIdempotencyInProgress is returned while the original request of a key is still running.
+	ErrIdempotencyInProgress = errors.New("request with this idempotency key is in progress")
+)
+
+const (
+	defaultIdempotencyTTL = 24 * time.Hour
+	// defaultClaimLease bounds how long a claim left by a request that never
+	// completed, e.g. because the process crashed, blocks its key.
+	defaultClaimLease = 5 * time.Minute
+)
+
+// IdempotencyRecord remembers the outcome of a transfer made under a key.
+// A record without a final State belongs to a request still in progress;
+// it expires when its claim lease runs out rather than after the TTL.
+type IdempotencyRecord struct {
+	Key         string        `json:"key"`
+	Fingerprint string        `json:"fingerprint"`
+	TransferID  string        `json:"transfer_id,omitempty"`
+	State       TransferState `json:"state,omitempty"`
+	FailedStep  string        `json:"failed_step,omitempty"`
+	Error       string        `json:"error,omitempty"`
+	Receipt     *Receipt      `json:"receipt,omitempty"`
+	CreatedAt   time.Time     `json:"created_at"`
+	ExpiresAt   time.Time     `json:"expires_at"`
+}
+
+// IdempotencyStore remembers transfer outcomes per idempotency key.
+type IdempotencyStore interface {
+	// Claim stores record unless an unexpired record with the same key
+	// exists, in which case it returns that record and false.
+	Claim(record IdempotencyRecord) (IdempotencyRecord, bool, error)
+	// Complete replaces the claimed record with its final outcome.
+	Complete(record IdempotencyRecord) error
+	// Release forgets a claim whose request ended without a final outcome,
+	// so a retry runs the transfer again.
+	Release(key string) error
+}
+
+// idempotencyEntries is the expiring key map shared by the store implementations.
+type idempotencyEntries struct {
+	ttl     time.Duration
+	lease   time.Duration
+	now     func() time.Time
+	records map[string]IdempotencyRecord
+}
+
+// newIdempotencyEntries keeps outcomes for ttl and claims for the default
+// lease, or half the TTL if that is shorter.
+func newIdempotencyEntries(ttl time.Duration) idempotencyEntries {
+	if ttl <= 0 {
+		ttl = defaultIdempotencyTTL
+	}
+	return idempotencyEntries{
+		ttl:     ttl,
+		lease:   min(defaultClaimLease, ttl/2),
+		now:     time.Now,
+		records: make(map[string]IdempotencyRecord),
+	}
+}
+
+// setLease changes the claim lease, keeping it below the TTL.
+func (e *idempotencyEntries) setLease(lease time.Duration) error {
+	if lease <= 0 || lease >= e.ttl {
+		return fmt.Errorf("claim lease %s must be positive and shorter than the TTL of %s", lease, e.ttl)
+	}
+	e.lease = lease
+	return nil
+}
+
+func (e *idempotencyEntries) claim(record IdempotencyRecord) (IdempotencyRecord, bool) {
+	now := e.now()
+	if existing, exists := e.records[record.Key]; exists && now.Before(existing.ExpiresAt) {
+		return existing, false
+	}
+	record.CreatedAt = now
+	record.ExpiresAt = now.Add(e.lease)
+	e.records[record.Key] = record
+	return record, true
+}
+
+func (e *idempotencyEntries) complete(record IdempotencyRecord) error {
+	existing, exists := e.records[record.Key]
+	if !exists {
+		return fmt.Errorf("idempotency key %q was not claimed", record.Key)
+	}
+	record.CreatedAt = existing.CreatedAt
+	record.ExpiresAt = e.now().Add(e.ttl)
+	e.records[record.Key] = record
+	return nil
+}
+
+// purge drops expired records.
+func (e *idempotencyEntries) purge() {
+	now := e.now()
+	for key, record := range e.records {
+		if !now.Before(record.ExpiresAt) {
+			delete(e.records, key)
+		}
+	}
+}
+
+// MemoryIdempotencyStore keeps idempotency records in memory until their TTL passes.
+type MemoryIdempotencyStore struct {
+	mu      sync.Mutex
+	entries idempotencyEntries
+}
+
+// NewMemoryIdempotencyStore creates an in-memory store whose outcomes
+// expire after ttl. Claims of requests that never complete expire after
+// five minutes, or half of ttl if that is shorter.
+func NewMemoryIdempotencyStore(ttl time.Duration) *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{entries: newIdempotencyEntries(ttl)}
+}
+
+// SetClaimLease changes how long an uncompleted claim blocks its key. It
+// must outlast the slowest transfer and be shorter than the TTL.
+func (s *MemoryIdempotencyStore) SetClaimLease(lease time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.entries.setLease(lease)
+}
+
+func (s *MemoryIdempotencyStore) Claim(record IdempotencyRecord) (IdempotencyRecord, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries.purge()
+	stored, claimed := s.entries.claim(record)
+	return stored, claimed, nil
+}
+
+func (s *MemoryIdempotencyStore) Complete(record IdempotencyRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.entries.complete(record)
+}
+
+func (s *MemoryIdempotencyStore) Release(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries.records, key)
+	return nil
+}
+
+// FileIdempotencyStore keeps idempotency records in a JSON file so retries
+// are recognised across process restarts.
+type FileIdempotencyStore struct {
+	mu      sync.Mutex
+	path    string
+	entries idempotencyEntries
+}
+
+// NewFileIdempotencyStore opens the store at path, loading existing records
+// if the file is present. Outcomes expire after ttl and claims as in
+// NewMemoryIdempotencyStore, so a claim left by a crashed process stops
+// blocking its key once its lease runs out.
+func NewFileIdempotencyStore(path string, ttl time.Duration) (*FileIdempotencyStore, error) {
+	s := &FileIdempotencyStore{path: path, entries: newIdempotencyEntries(ttl)}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read idempotency file: %w", err)
+	}
+
+	var list []IdempotencyRecord
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("decode idempotency file: %w", err)
+	}
+	for _, record := range list {
+		s.entries.records[record.Key] = record
+	}
+	return s, nil
+}
+
+// SetClaimLease changes how long an uncompleted claim blocks its key. It
+// must outlast the slowest transfer and be shorter than the TTL.
+func (s *FileIdempotencyStore) SetClaimLease(lease time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.entries.setLease(lease)
+}
+
+func (s *FileIdempotencyStore) Claim(record IdempotencyRecord) (IdempotencyRecord, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries.purge()
+	stored, claimed := s.entries.claim(record)
+	if !claimed {
+		return stored, false, nil
+	}
+	if err := s.flush(); err != nil {
+		delete(s.entries.records, record.Key)
+		return IdempotencyRecord{}, false, err
+	}
+	return stored, true, nil
+}
+
+func (s *FileIdempotencyStore) Complete(record IdempotencyRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.entries.complete(record); err != nil {
+		return err
+	}
+	return s.flush()
+}
+
+func (s *FileIdempotencyStore) Release(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries.records, key)
+	return s.flush()
+}
+
+// flush writes all records to disk. Callers must hold s.mu.
+func (s *FileIdempotencyStore) flush() error {
+	list := make([]IdempotencyRecord, 0, len(s.entries.records))
+	for _, record := range s.entries.records {
+		list = append(list, record)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Key < list[j].Key
+	})
+
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode idempotency file: %w", err)
+	}
+	return writeFileAtomic(s.path, data)
+}
+
+// WithIdempotencyStore replaces the in-memory idempotency store.
+func WithIdempotencyStore(store IdempotencyStore) Option {
+	return func(f *BankTransferFacade) error {
+		if store == nil {
+			return errors.New("idempotency store must not be nil")
+		}
+		f.idempotency = store
+		return nil
+	}
+}
+
+// WithIdempotencyKey makes retries of a transfer with the same key return the
+// outcome of the first attempt instead of moving money again.
+func WithIdempotencyKey(key string) TransferOption {
+	return func(o *transferOptions) {
+		o.idempotencyKey = key
+	}
+}
+
+// transferFingerprint identifies the parameters of a transfer. The PIN is
+// left out so it is never persisted, even hashed.
+func transferFingerprint(accountNo, fromBank, toAcc, toBank string, amount Money) string {
+	parts := []string{fromBank, accountNo, toBank, toAcc, amount.String()}
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// replay rebuilds the result of the transfer recorded under a key.
+func (r IdempotencyRecord) replay() (Receipt, error) {
+	var receipt Receipt
+	if r.Receipt != nil {
+		receipt = *r.Receipt
+	}
+	switch {
+	case r.State == "":
+		return Receipt{}, ErrIdempotencyInProgress
+	case r.State == StateCommitted && r.Error == "":
+		return receipt, nil
+	default:
+		return receipt, newTransferError(r.TransferID, r.FailedStep, receipt.FromBank, receipt.ToBank, r.State, errors.New(r.Error))
+	}
+}
@@ -0,0 +1,74 @@
+package facade_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go-design-patterns/structural/facade"
+	"go-design-patterns/structural/facade/facadetest"
+)
+
+func TestIdempotentRetryRerunsTransientFailure(t *testing.T) {
+	ctx := context.Background()
+	h, err := facadetest.NewHarness(facadetest.StandardBanks())
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Bank("Beta").Inject(facadetest.Fault{Op: facade.OpCredit, Retryable: true, Times: 1})
+
+	from, to := facadetest.AlphaMain, facadetest.BetaMain
+	amount, err := facade.ParseMoney("100.00", "THB")
+	if err != nil {
+		t.Fatal(err)
+	}
+	transfer := func() (facade.Receipt, error) {
+		return h.Facade.Transfer(ctx, from.AccountNo, from.Pin, from.Bank, to.AccountNo, to.Bank, amount,
+			facade.WithIdempotencyKey("invoice-1"))
+	}
+
+	receipt, err := transfer()
+	if !errors.Is(err, facadetest.ErrInjected) || receipt.Status != facade.StateCompensated {
+		t.Fatalf("first attempt: got %s, %v; want compensated, %v", receipt.Status, err, facadetest.ErrInjected)
+	}
+	receipt, err = transfer()
+	if err != nil || receipt.Status != facade.StateCommitted {
+		t.Fatalf("retry: got %s, %v; want committed", receipt.Status, err)
+	}
+	replayed, err := transfer()
+	if err != nil || replayed.TransactionID != receipt.TransactionID {
+		t.Fatalf("replay: got %s, %v; want %s", replayed.TransactionID, err, receipt.TransactionID)
+	}
+}
+
+func TestIdempotencyClaimLease(t *testing.T) {
+	store := facade.NewMemoryIdempotencyStore(time.Hour)
+	if err := store.SetClaimLease(time.Hour); err == nil {
+		t.Error("SetClaimLease accepted a lease as long as the TTL")
+	}
+	if err := store.SetClaimLease(10 * time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	record := facade.IdempotencyRecord{Key: "k", Fingerprint: "f"}
+	if _, claimed, err := store.Claim(record); err != nil || !claimed {
+		t.Fatalf("first claim: claimed %v, %v", claimed, err)
+	}
+	if _, claimed, _ := store.Claim(record); claimed {
+		t.Fatal("second claim succeeded while the first was leased")
+	}
+	time.Sleep(20 * time.Millisecond)
+	if _, claimed, err := store.Claim(record); err != nil || !claimed {
+		t.Fatalf("claim after the lease ran out: claimed %v, %v", claimed, err)
+	}
+
+	record.State = facade.StateCommitted
+	if err := store.Complete(record); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if stored, claimed, _ := store.Claim(record); claimed || stored.State != facade.StateCommitted {
+		t.Fatalf("completed outcome expired with the claim lease: claimed %v, state %s", claimed, stored.State)
+	}
+}
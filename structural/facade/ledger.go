@@ -0,0 +1,368 @@
+package facade
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrAccountNotFound is returned for an account that was never opened.
+	ErrAccountNotFound = errors.New("account not found")
+	// ErrAccountExists is returned when opening an account twice.
+	ErrAccountExists = errors.New("account already exists")
+	// ErrInsufficientFunds is returned when a posting would overdraw a customer account.
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	// ErrUnbalancedPosting is returned for postings whose legs do not sum to zero.
+	ErrUnbalancedPosting = errors.New("unbalanced posting")
+)
+
+// systemAccountPrefix marks bank-internal accounts such as suspense or cash.
+const systemAccountPrefix = "sys:"
+
+// AccountID identifies an account in the ledger.
+type AccountID struct {
+	Bank      string
+	AccountNo string
+}
+
+// SystemAccount returns the ID of a bank-internal account, e.g. its cash account.
+func SystemAccount(bank, name string) AccountID {
+	return AccountID{Bank: bank, AccountNo: systemAccountPrefix + name}
+}
+
+// IsSystem reports whether the account is bank-internal.
+func (id AccountID) IsSystem() bool {
+	return strings.HasPrefix(id.AccountNo, systemAccountPrefix)
+}
+
+func (id AccountID) String() string {
+	return id.Bank + "/" + id.AccountNo
+}
+
+// InsufficientFundsError reports the account and amounts of a rejected debit.
+type InsufficientFundsError struct {
+	Account   AccountID
+	Balance   Money
+	Requested Money
+}
+
+func (e *InsufficientFundsError) Error() string {
+	return fmt.Sprintf("%v: %s has %s, needs %s", ErrInsufficientFunds, e.Account, e.Balance, e.Requested)
+}
+
+func (e *InsufficientFundsError) Unwrap() error {
+	return ErrInsufficientFunds
+}
+
+// Leg is one side of a posting: a signed change to an account balance.
+type Leg struct {
+	Account AccountID
+	Amount  Money
+}
+
+// Posting is a balanced journal entry. The amounts of its legs sum to zero
+// in every currency, so money is only ever moved, never created or lost.
+type Posting struct {
+	ID   int
+	Ref  string
+	Memo string
+	Time time.Time
+	Legs []Leg
+}
+
+type ledgerAccount struct {
+	balance Money
+	system  bool
+}
+
+// Ledger is an in-memory double-entry ledger shared by the simulated banks.
+// Customer accounts can never go negative; system accounts can.
+type Ledger struct {
+	mu       sync.RWMutex
+	accounts map[AccountID]*ledgerAccount
+	postings []Posting
+	now      func() time.Time
+}
+
+// NewLedger creates an empty ledger.
+func NewLedger() *Ledger {
+	return &Ledger{
+		accounts: make(map[AccountID]*ledgerAccount),
+		now:      time.Now,
+	}
+}
+
+// OpenAccount opens an account holding currency with a zero balance.
+func (l *Ledger) OpenAccount(id AccountID, currency string) error {
+	if _, err := CurrencyExponent(currency); err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, exists := l.accounts[id]; exists {
+		return fmt.Errorf("%w: %s", ErrAccountExists, id)
+	}
+	l.accounts[id] = &ledgerAccount{
+		balance: Money{currency: currency},
+		system:  id.IsSystem(),
+	}
+	return nil
+}
+
+// EnsureAccount opens the account unless it already exists in the same currency.
+func (l *Ledger) EnsureAccount(id AccountID, currency string) error {
+	err := l.OpenAccount(id, currency)
+	if !errors.Is(err, ErrAccountExists) {
+		return err
+	}
+	balance, err := l.Balance(id)
+	if err != nil {
+		return err
+	}
+	if balance.Currency() != currency {
+		return fmt.Errorf("%w: %s holds %s, not %s", ErrCurrencyMismatch, id, balance.Currency(), currency)
+	}
+	return nil
+}
+
+// Deposit funds a customer account from its bank's cash system account,
+// opening the cash account on first use.
+func (l *Ledger) Deposit(id AccountID, amount Money) (Posting, error) {
+	if !amount.IsPositive() {
+		return Posting{}, fmt.Errorf("%w: %s", ErrNonPositiveAmount, amount)
+	}
+	cash := SystemAccount(id.Bank, "cash")
+	if err := l.EnsureAccount(cash, amount.Currency()); err != nil {
+		return Posting{}, err
+	}
+	return l.Move("deposit", "cash deposit", cash, id, amount)
+}
+
+// Move posts amount from one account to another.
+func (l *Ledger) Move(ref, memo string, from, to AccountID, amount Money) (Posting, error) {
+	debit, err := amount.Neg()
+	if err != nil {
+		return Posting{}, err
+	}
+	return l.Post(ref, memo,
+		Leg{Account: from, Amount: debit},
+		Leg{Account: to, Amount: amount},
+	)
+}
+
+// Post applies a balanced set of legs atomically. Either every leg is
+// applied or, on error, none is.
+func (l *Ledger) Post(ref, memo string, legs ...Leg) (Posting, error) {
+	if len(legs) < 2 {
+		return Posting{}, fmt.Errorf("%w: need at least two legs", ErrUnbalancedPosting)
+	}
+
+	sums := make(map[string]int64)
+	for _, leg := range legs {
+		if err := leg.Amount.Validate(); err != nil {
+			return Posting{}, err
+		}
+		sums[leg.Amount.Currency()] += leg.Amount.Minor()
+	}
+	for currency, sum := range sums {
+		if sum != 0 {
+			return Posting{}, fmt.Errorf("%w: %s legs sum to %d", ErrUnbalancedPosting, currency, sum)
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Compute every new balance before touching any account
+	next := make(map[AccountID]Money, len(legs))
+	for _, leg := range legs {
+		acc, exists := l.accounts[leg.Account]
+		if !exists {
+			return Posting{}, fmt.Errorf("%w: %s", ErrAccountNotFound, leg.Account)
+		}
+		current, seen := next[leg.Account]
+		if !seen {
+			current = acc.balance
+		}
+		updated, err := current.Add(leg.Amount)
+		if err != nil {
+			return Posting{}, fmt.Errorf("account %s: %w", leg.Account, err)
+		}
+		next[leg.Account] = updated
+	}
+	for id, balance := range next {
+		if !l.accounts[id].system && balance.IsNegative() {
+			requested, _ := l.accounts[id].balance.Sub(balance)
+			return Posting{}, &InsufficientFundsError{Account: id, Balance: l.accounts[id].balance, Requested: requested}
+		}
+	}
+
+	for id, balance := range next {
+		l.accounts[id].balance = balance
+	}
+	posting := Posting{
+		ID:   len(l.postings) + 1,
+		Ref:  ref,
+		Memo: memo,
+		Time: l.now(),
+		Legs: append([]Leg(nil), legs...),
+	}
+	l.postings = append(l.postings, posting)
+	return posting, nil
+}
+
+// Balance returns the current balance of an account.
+func (l *Ledger) Balance(id AccountID) (Money, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	acc, exists := l.accounts[id]
+	if !exists {
+		return Money{}, fmt.Errorf("%w: %s", ErrAccountNotFound, id)
+	}
+	return acc.balance, nil
+}
+
+// HasAccount reports whether the account has been opened.
+func (l *Ledger) HasAccount(id AccountID) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	_, exists := l.accounts[id]
+	return exists
+}
+
+// Accounts returns the IDs of all open accounts in sorted order.
+func (l *Ledger) Accounts() []AccountID {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	ids := make([]AccountID, 0, len(l.accounts))
+	for id := range l.accounts {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i].String() < ids[j].String()
+	})
+	return ids
+}
+
+// Postings returns the audit trail of all postings in the order they were applied.
+func (l *Ledger) Postings() []Posting {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return append([]Posting(nil), l.postings...)
+}
+
+// PostingsFor returns the postings that touched an account.
+func (l *Ledger) PostingsFor(id AccountID) []Posting {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	var result []Posting
+	for _, p := range l.postings {
+		for _, leg := range p.Legs {
+			if leg.Account == id {
+				result = append(result, p)
+				break
+			}
+		}
+	}
+	return result
+}
+
+// Verify replays the audit trail and checks that every posting balances and
+// that the replayed balances match the current ones.
+func (l *Ledger) Verify() error {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	replayed := make(map[AccountID]int64, len(l.accounts))
+	for _, p := range l.postings {
+		sums := make(map[string]int64)
+		for _, leg := range p.Legs {
+			sums[leg.Amount.Currency()] += leg.Amount.Minor()
+			replayed[leg.Account] += leg.Amount.Minor()
+		}
+		for currency, sum := range sums {
+			if sum != 0 {
+				return fmt.Errorf("%w: posting %d %s legs sum to %d", ErrUnbalancedPosting, p.ID, currency, sum)
+			}
+		}
+	}
+	for id, acc := range l.accounts {
+		if replayed[id] != acc.balance.Minor() {
+			return fmt.Errorf("account %s: balance %s does not match replayed %d", id, acc.balance, replayed[id])
+		}
+	}
+	return nil
+}
+
+// ledgerFile is the on-disk form of a ledger.
+type ledgerFile struct {
+	Accounts []ledgerFileAccount `json:"accounts"`
+	Postings []Posting           `json:"postings"`
+}
+
+type ledgerFileAccount struct {
+	ID       AccountID `json:"id"`
+	Currency string    `json:"currency"`
+}
+
+// Save writes the accounts and postings of the ledger to path.
+func (l *Ledger) Save(path string) error {
+	var file ledgerFile
+	for _, id := range l.Accounts() {
+		balance, err := l.Balance(id)
+		if err != nil {
+			return err
+		}
+		file.Accounts = append(file.Accounts, ledgerFileAccount{ID: id, Currency: balance.Currency()})
+	}
+	file.Postings = l.Postings()
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode ledger file: %w", err)
+	}
+	return writeFileAtomic(path, data)
+}
+
+// LoadLedger reads a ledger written by Save. Its postings are replayed in
+// order, so the balances are rebuilt under the same checks as when the
+// postings were first made.
+func LoadLedger(path string) (*Ledger, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read ledger file: %w", err)
+	}
+	var file ledgerFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("decode ledger file: %w", err)
+	}
+
+	l := NewLedger()
+	for _, acc := range file.Accounts {
+		if err := l.OpenAccount(acc.ID, acc.Currency); err != nil {
+			return nil, err
+		}
+	}
+	for _, p := range file.Postings {
+		posted := p.Time
+		l.now = func() time.Time { return posted }
+		if _, err := l.Post(p.Ref, p.Memo, p.Legs...); err != nil {
+			return nil, fmt.Errorf("replay posting %d: %w", p.ID, err)
+		}
+	}
+	l.now = time.Now
+	return l, nil
+}
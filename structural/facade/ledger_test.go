@@ -0,0 +1,246 @@
+package facade_test
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"go-design-patterns/structural/facade"
+)
+
+func mustMoney(t *testing.T, amount, currency string) facade.Money {
+	t.Helper()
+	m, err := facade.ParseMoney(amount, currency)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func newTestLedger(t *testing.T) (*facade.Ledger, facade.AccountID, facade.AccountID) {
+	t.Helper()
+	l := facade.NewLedger()
+	alice := facade.AccountID{Bank: "KBank", AccountNo: "1001"}
+	bob := facade.AccountID{Bank: "KBank", AccountNo: "1002"}
+	for _, id := range []facade.AccountID{alice, bob} {
+		if err := l.OpenAccount(id, "THB"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := l.Deposit(alice, mustMoney(t, "100.00", "THB")); err != nil {
+		t.Fatal(err)
+	}
+	return l, alice, bob
+}
+
+func wantBalance(t *testing.T, l *facade.Ledger, id facade.AccountID, want string) {
+	t.Helper()
+	balance, err := l.Balance(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance.Decimal() != want {
+		t.Errorf("balance of %s = %s, want %s", id, balance, want)
+	}
+}
+
+func TestLedgerRejectsOverdraft(t *testing.T) {
+	l, alice, bob := newTestLedger(t)
+
+	_, err := l.Move("tx-1", "too much", alice, bob, mustMoney(t, "100.01", "THB"))
+	var insufficient *facade.InsufficientFundsError
+	if !errors.As(err, &insufficient) || !errors.Is(err, facade.ErrInsufficientFunds) {
+		t.Fatalf("overdraft = %v, want an InsufficientFundsError", err)
+	}
+	if insufficient.Account != alice || insufficient.Balance.Decimal() != "100.00" || insufficient.Requested.Decimal() != "100.01" {
+		t.Errorf("error reports %s with %s needing %s", insufficient.Account, insufficient.Balance, insufficient.Requested)
+	}
+	// A rejected posting changes nothing
+	wantBalance(t, l, alice, "100.00")
+	wantBalance(t, l, bob, "0.00")
+	if n := len(l.Postings()); n != 1 {
+		t.Errorf("%d postings after a rejected one, want only the deposit", n)
+	}
+
+	if _, err := l.Move("tx-2", "everything", alice, bob, mustMoney(t, "100.00", "THB")); err != nil {
+		t.Fatalf("moving the whole balance = %v", err)
+	}
+	wantBalance(t, l, alice, "0.00")
+	wantBalance(t, l, bob, "100.00")
+}
+
+func TestLedgerSystemAccountsMayGoNegative(t *testing.T) {
+	l, alice, _ := newTestLedger(t)
+	suspense := facade.SystemAccount("KBank", "suspense")
+	if err := l.EnsureAccount(suspense, "THB"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Move("tx-1", "advance", suspense, alice, mustMoney(t, "50.00", "THB")); err != nil {
+		t.Fatalf("debiting a system account = %v", err)
+	}
+	wantBalance(t, l, suspense, "-50.00")
+	wantBalance(t, l, alice, "150.00")
+	if !suspense.IsSystem() || alice.IsSystem() {
+		t.Error("IsSystem does not tell system and customer accounts apart")
+	}
+}
+
+func TestLedgerPostingsBalancePerCurrency(t *testing.T) {
+	l, alice, _ := newTestLedger(t)
+	usd := facade.AccountID{Bank: "GlobalBank", AccountNo: "2001"}
+	if err := l.OpenAccount(usd, "USD"); err != nil {
+		t.Fatal(err)
+	}
+	thbClearing := facade.SystemAccount("clearing", "THB")
+	usdClearing := facade.SystemAccount("clearing", "USD")
+	if err := l.EnsureAccount(thbClearing, "THB"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.EnsureAccount(usdClearing, "USD"); err != nil {
+		t.Fatal(err)
+	}
+
+	thb, usd1 := mustMoney(t, "35.00", "THB"), mustMoney(t, "1.00", "USD")
+	negTHB, _ := thb.Neg()
+	negUSD, _ := usd1.Neg()
+
+	// 35 THB out and 1 USD in do not cancel out: each currency must balance
+	_, err := l.Post("fx-1", "unbalanced exchange",
+		facade.Leg{Account: alice, Amount: negTHB},
+		facade.Leg{Account: usd, Amount: usd1},
+	)
+	if !errors.Is(err, facade.ErrUnbalancedPosting) {
+		t.Fatalf("cross-currency posting without clearing legs = %v, want ErrUnbalancedPosting", err)
+	}
+
+	_, err = l.Post("fx-2", "exchange",
+		facade.Leg{Account: alice, Amount: negTHB},
+		facade.Leg{Account: thbClearing, Amount: thb},
+		facade.Leg{Account: usdClearing, Amount: negUSD},
+		facade.Leg{Account: usd, Amount: usd1},
+	)
+	if err != nil {
+		t.Fatalf("balanced cross-currency posting = %v", err)
+	}
+	wantBalance(t, l, alice, "65.00")
+	wantBalance(t, l, usd, "1.00")
+	if err := l.Verify(); err != nil {
+		t.Errorf("Verify = %v", err)
+	}
+
+	// An account holds a single currency
+	if _, err := l.Move("tx-3", "wrong currency", usdClearing, alice, usd1); !errors.Is(err, facade.ErrCurrencyMismatch) {
+		t.Errorf("crediting USD to a THB account = %v, want ErrCurrencyMismatch", err)
+	}
+	if err := l.EnsureAccount(alice, "USD"); !errors.Is(err, facade.ErrCurrencyMismatch) {
+		t.Errorf("EnsureAccount in another currency = %v, want ErrCurrencyMismatch", err)
+	}
+}
+
+func TestLedgerErrors(t *testing.T) {
+	l, alice, _ := newTestLedger(t)
+	ghost := facade.AccountID{Bank: "KBank", AccountNo: "9999"}
+
+	if err := l.OpenAccount(alice, "THB"); !errors.Is(err, facade.ErrAccountExists) {
+		t.Errorf("opening an account twice = %v, want ErrAccountExists", err)
+	}
+	if _, err := l.Move("tx-1", "", alice, ghost, mustMoney(t, "1.00", "THB")); !errors.Is(err, facade.ErrAccountNotFound) {
+		t.Errorf("posting to an unknown account = %v, want ErrAccountNotFound", err)
+	}
+	if _, err := l.Balance(ghost); !errors.Is(err, facade.ErrAccountNotFound) {
+		t.Errorf("balance of an unknown account = %v, want ErrAccountNotFound", err)
+	}
+	if _, err := l.Post("tx-2", "", facade.Leg{Account: alice, Amount: mustMoney(t, "1.00", "THB")}); !errors.Is(err, facade.ErrUnbalancedPosting) {
+		t.Errorf("single-leg posting = %v, want ErrUnbalancedPosting", err)
+	}
+	if _, err := l.Deposit(alice, mustMoney(t, "0", "THB")); !errors.Is(err, facade.ErrNonPositiveAmount) {
+		t.Errorf("zero deposit = %v, want ErrNonPositiveAmount", err)
+	}
+}
+
+func TestLedgerSaveLoadRoundTrip(t *testing.T) {
+	l, alice, bob := newTestLedger(t)
+	if _, err := l.Move("tx-1", "rent", alice, bob, mustMoney(t, "40.00", "THB")); err != nil {
+		t.Fatal(err)
+	}
+	usd := facade.AccountID{Bank: "GlobalBank", AccountNo: "2001"}
+	if err := l.OpenAccount(usd, "USD"); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "ledger.json")
+	if err := l.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := facade.LoadLedger(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := loaded.Accounts(), l.Accounts(); !reflect.DeepEqual(got, want) {
+		t.Errorf("loaded accounts %v, want %v", got, want)
+	}
+	for _, id := range l.Accounts() {
+		want, _ := l.Balance(id)
+		got, err := loaded.Balance(id)
+		if err != nil || got != want {
+			t.Errorf("loaded balance of %s = %v, %v; want %s", id, got, err, want)
+		}
+	}
+	original, replayed := l.Postings(), loaded.Postings()
+	if len(replayed) != len(original) {
+		t.Fatalf("loaded %d postings, want %d", len(replayed), len(original))
+	}
+	for i := range original {
+		if !replayed[i].Time.Equal(original[i].Time) || replayed[i].Ref != original[i].Ref || !reflect.DeepEqual(replayed[i].Legs, original[i].Legs) {
+			t.Errorf("posting %d = %+v, want %+v", i+1, replayed[i], original[i])
+		}
+	}
+	if err := loaded.Verify(); err != nil {
+		t.Errorf("Verify of the loaded ledger = %v", err)
+	}
+}
+
+func TestLoadLedgerReplaysPostingChecks(t *testing.T) {
+	l, alice, bob := newTestLedger(t)
+	if _, err := l.Move("tx-1", "", alice, bob, mustMoney(t, "100.00", "THB")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Move("tx-2", "", bob, alice, mustMoney(t, "100.00", "THB")); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "ledger.json")
+	if err := l.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	// Replayed out of order, tx-2 would overdraw bob
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var file struct {
+		Accounts json.RawMessage   `json:"accounts"`
+		Postings []json.RawMessage `json:"postings"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		t.Fatal(err)
+	}
+	file.Postings[1], file.Postings[2] = file.Postings[2], file.Postings[1]
+	if data, err = json.Marshal(file); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := facade.LoadLedger(path); !errors.Is(err, facade.ErrInsufficientFunds) {
+		t.Errorf("LoadLedger of reordered postings = %v, want ErrInsufficientFunds", err)
+	}
+
+	if _, err := facade.LoadLedger(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("LoadLedger of a missing file succeeded")
+	}
+}
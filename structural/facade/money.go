@@ -0,0 +1,306 @@
+package facade
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrUnknownCurrency is returned for a currency code missing from the ISO 4217 table.
+	ErrUnknownCurrency = errors.New("unknown currency")
+	// ErrCurrencyMismatch is returned when combining amounts of different currencies.
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+	// ErrInvalidAmount is returned when an amount cannot be parsed or represented exactly.
+	ErrInvalidAmount = errors.New("invalid amount")
+	// ErrAmountOverflow is returned when arithmetic exceeds the int64 range of minor units.
+	ErrAmountOverflow = errors.New("amount overflow")
+	// ErrNonPositiveAmount is returned for zero or negative transfer amounts.
+	ErrNonPositiveAmount = errors.New("amount must be positive")
+)
+
+// currencyExponents maps supported ISO 4217 codes to their number of minor-unit digits.
+var currencyExponents = map[string]int{
+	"THB": 2,
+	"USD": 2,
+	"EUR": 2,
+	"GBP": 2,
+	"SGD": 2,
+	"JPY": 0,
+	"KWD": 3,
+}
+
+// CurrencyExponent returns the number of minor-unit digits of an ISO 4217 currency.
+func CurrencyExponent(currency string) (int, error) {
+	exp, ok := currencyExponents[currency]
+	if !ok {
+		return 0, fmt.Errorf("%w: %q", ErrUnknownCurrency, currency)
+	}
+	return exp, nil
+}
+
+// RoundingMode selects how fractional minor units are rounded.
+type RoundingMode int
+
+const (
+	// RoundHalfEven rounds to the nearest minor unit, ties to even (banker's rounding).
+	RoundHalfEven RoundingMode = iota
+	// RoundHalfUp rounds to the nearest minor unit, ties away from zero.
+	RoundHalfUp
+	// RoundDown truncates towards zero.
+	RoundDown
+	// RoundUp rounds away from zero.
+	RoundUp
+)
+
+// Money is an exact amount in the minor units (e.g. satang) of one currency.
+// The zero value has no currency and is rejected by Validate.
+type Money struct {
+	minor    int64
+	currency string
+}
+
+// NewMoney creates an amount from minor units.
+func NewMoney(minor int64, currency string) (Money, error) {
+	if _, err := CurrencyExponent(currency); err != nil {
+		return Money{}, err
+	}
+	return Money{minor: minor, currency: currency}, nil
+}
+
+// ParseMoney parses a decimal string such as "1000.50" or "-3" in currency.
+// More fractional digits than the currency allows is an error rather than a
+// silent rounding.
+func ParseMoney(amount, currency string) (Money, error) {
+	exp, err := CurrencyExponent(currency)
+	if err != nil {
+		return Money{}, err
+	}
+
+	s := strings.TrimSpace(amount)
+	negative := strings.HasPrefix(s, "-")
+	if negative || strings.HasPrefix(s, "+") {
+		s = s[1:]
+	}
+
+	whole, frac, hasDot := strings.Cut(s, ".")
+	if whole == "" || (hasDot && frac == "") || !isDigits(whole) || !isDigits(frac) {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, amount)
+	}
+	if len(frac) > exp {
+		return Money{}, fmt.Errorf("%w: %q has more than %d decimal places for %s", ErrInvalidAmount, amount, exp, currency)
+	}
+
+	digits := whole + frac + strings.Repeat("0", exp-len(frac))
+	minor, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("%w: %q", ErrAmountOverflow, amount)
+	}
+	if negative {
+		minor = -minor
+	}
+	return Money{minor: minor, currency: currency}, nil
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Minor returns the amount in minor units.
+func (m Money) Minor() int64 {
+	return m.minor
+}
+
+// Currency returns the ISO 4217 currency code.
+func (m Money) Currency() string {
+	return m.currency
+}
+
+// Validate reports whether m has a known currency.
+func (m Money) Validate() error {
+	_, err := CurrencyExponent(m.currency)
+	return err
+}
+
+// IsZero reports whether the amount is zero.
+func (m Money) IsZero() bool {
+	return m.minor == 0
+}
+
+// IsPositive reports whether the amount is greater than zero.
+func (m Money) IsPositive() bool {
+	return m.minor > 0
+}
+
+// IsNegative reports whether the amount is less than zero.
+func (m Money) IsNegative() bool {
+	return m.minor < 0
+}
+
+// Add returns m + o. Both amounts must share a currency.
+func (m Money) Add(o Money) (Money, error) {
+	if err := m.sameCurrency(o); err != nil {
+		return Money{}, err
+	}
+	sum := m.minor + o.minor
+	if (sum > m.minor) != (o.minor > 0) {
+		return Money{}, ErrAmountOverflow
+	}
+	return Money{minor: sum, currency: m.currency}, nil
+}
+
+// Sub returns m - o. Both amounts must share a currency.
+func (m Money) Sub(o Money) (Money, error) {
+	neg, err := o.Neg()
+	if err != nil {
+		return Money{}, err
+	}
+	return m.Add(neg)
+}
+
+// Neg returns -m. The most negative amount has no positive counterpart and
+// is reported as an overflow.
+func (m Money) Neg() (Money, error) {
+	if m.minor == math.MinInt64 {
+		return Money{}, ErrAmountOverflow
+	}
+	return Money{minor: -m.minor, currency: m.currency}, nil
+}
+
+// Mul returns m multiplied by an integer factor.
+func (m Money) Mul(factor int64) (Money, error) {
+	if m.minor == 0 || factor == 0 {
+		return Money{currency: m.currency}, nil
+	}
+	product := m.minor * factor
+	if product/factor != m.minor || (factor == -1 && m.minor == math.MinInt64) {
+		return Money{}, ErrAmountOverflow
+	}
+	return Money{minor: product, currency: m.currency}, nil
+}
+
+// MulFrac returns m * num / den rounded to whole minor units with mode. It
+// is the building block for percentages and rate conversions.
+func (m Money) MulFrac(num, den int64, mode RoundingMode) (Money, error) {
+	if den == 0 {
+		return Money{}, fmt.Errorf("%w: zero denominator", ErrInvalidAmount)
+	}
+	product, err := m.Mul(num)
+	if err != nil {
+		return Money{}, err
+	}
+	if den < 0 {
+		if product.minor == math.MinInt64 || den == math.MinInt64 {
+			return Money{}, ErrAmountOverflow
+		}
+		product.minor, den = -product.minor, -den
+	}
+	return Money{minor: divRound(product.minor, den, mode), currency: m.currency}, nil
+}
+
+// divRound divides n by a positive d using mode for the remainder.
+func divRound(n, d int64, mode RoundingMode) int64 {
+	q, r := n/d, n%d
+	if r == 0 {
+		return q
+	}
+	sign := int64(1)
+	if n < 0 {
+		sign, r = -1, -r
+	}
+	switch mode {
+	case RoundDown:
+		return q
+	case RoundUp:
+		return q + sign
+	case RoundHalfUp:
+		if 2*r >= d {
+			return q + sign
+		}
+		return q
+	default:
+		if 2*r > d || (2*r == d && q%2 != 0) {
+			return q + sign
+		}
+		return q
+	}
+}
+
+// Cmp compares m with o and returns -1, 0 or +1.
+func (m Money) Cmp(o Money) (int, error) {
+	if err := m.sameCurrency(o); err != nil {
+		return 0, err
+	}
+	switch {
+	case m.minor < o.minor:
+		return -1, nil
+	case m.minor > o.minor:
+		return 1, nil
+	default:
+		return 0, nil
+	}
+}
+
+func (m Money) sameCurrency(o Money) error {
+	if m.currency != o.currency {
+		return fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.currency, o.currency)
+	}
+	return nil
+}
+
+// Decimal formats the amount without currency, e.g. "-1234.50".
+func (m Money) Decimal() string {
+	exp := currencyExponents[m.currency]
+	sign := ""
+	abs := uint64(m.minor)
+	if m.minor < 0 {
+		sign = "-"
+		abs = uint64(-m.minor)
+	}
+	digits := strconv.FormatUint(abs, 10)
+	if exp == 0 {
+		return sign + digits
+	}
+	if len(digits) <= exp {
+		digits = strings.Repeat("0", exp-len(digits)+1) + digits
+	}
+	cut := len(digits) - exp
+	return sign + digits[:cut] + "." + digits[cut:]
+}
+
+// String formats the amount with its currency, e.g. "1000.00 THB".
+func (m Money) String() string {
+	return m.Decimal() + " " + m.currency
+}
+
+type moneyJSON struct {
+	Amount   string `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+// MarshalJSON encodes the amount as a decimal string to avoid float rounding.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(moneyJSON{Amount: m.Decimal(), Currency: m.currency})
+}
+
+// UnmarshalJSON decodes the format written by MarshalJSON.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var raw moneyJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	parsed, err := ParseMoney(raw.Amount, raw.Currency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
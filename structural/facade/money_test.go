@@ -0,0 +1,96 @@
+package facade_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"go-design-patterns/structural/facade"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		amount, currency string
+		want             int64
+		wantErr          error
+	}{
+		{amount: "1000.50", currency: "THB", want: 100050},
+		{amount: "-3", currency: "THB", want: -300},
+		{amount: "+5", currency: "THB", want: 500},
+		{amount: " 7.1 ", currency: "USD", want: 710},
+		{amount: "1500", currency: "JPY", want: 1500},
+		{amount: "0.125", currency: "KWD", want: 125},
+		{amount: "-+5", currency: "THB", wantErr: facade.ErrInvalidAmount},
+		{amount: "+-5", currency: "THB", wantErr: facade.ErrInvalidAmount},
+		{amount: "--5", currency: "THB", wantErr: facade.ErrInvalidAmount},
+		{amount: "++5", currency: "THB", wantErr: facade.ErrInvalidAmount},
+		{amount: "-", currency: "THB", wantErr: facade.ErrInvalidAmount},
+		{amount: "5.", currency: "THB", wantErr: facade.ErrInvalidAmount},
+		{amount: ".5", currency: "THB", wantErr: facade.ErrInvalidAmount},
+		{amount: "1.005", currency: "THB", wantErr: facade.ErrInvalidAmount},
+		{amount: "1.5", currency: "JPY", wantErr: facade.ErrInvalidAmount},
+		{amount: "1e3", currency: "THB", wantErr: facade.ErrInvalidAmount},
+		{amount: "92233720368547758.08", currency: "THB", wantErr: facade.ErrAmountOverflow},
+		{amount: "1", currency: "XXX", wantErr: facade.ErrUnknownCurrency},
+	}
+	for _, tt := range tests {
+		got, err := facade.ParseMoney(tt.amount, tt.currency)
+		if tt.wantErr != nil {
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("ParseMoney(%q, %s) = %v, %v; want %v", tt.amount, tt.currency, got, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got.Minor() != tt.want || got.Currency() != tt.currency {
+			t.Errorf("ParseMoney(%q, %s) = %v, %v; want %d minor units", tt.amount, tt.currency, got, err, tt.want)
+		}
+	}
+}
+
+func TestMoneyOverflow(t *testing.T) {
+	most, _ := facade.NewMoney(math.MaxInt64, "THB")
+	least, _ := facade.NewMoney(math.MinInt64, "THB")
+	one, _ := facade.NewMoney(1, "THB")
+
+	if _, err := least.Neg(); !errors.Is(err, facade.ErrAmountOverflow) {
+		t.Errorf("Neg of the most negative amount = %v, want ErrAmountOverflow", err)
+	}
+	if neg, err := most.Neg(); err != nil || neg.Minor() != -math.MaxInt64 {
+		t.Errorf("Neg of the largest amount = %v, %v", neg, err)
+	}
+	if _, err := most.Add(one); !errors.Is(err, facade.ErrAmountOverflow) {
+		t.Errorf("Add past the largest amount = %v, want ErrAmountOverflow", err)
+	}
+	if _, err := one.Sub(least); !errors.Is(err, facade.ErrAmountOverflow) {
+		t.Errorf("Sub of the most negative amount = %v, want ErrAmountOverflow", err)
+	}
+	if _, err := least.Mul(-1); !errors.Is(err, facade.ErrAmountOverflow) {
+		t.Errorf("Mul of the most negative amount by -1 = %v, want ErrAmountOverflow", err)
+	}
+	if _, err := most.MulFrac(2, 3, facade.RoundHalfEven); !errors.Is(err, facade.ErrAmountOverflow) {
+		t.Errorf("MulFrac overflowing the product = %v, want ErrAmountOverflow", err)
+	}
+}
+
+func TestMoneyMulFracRounding(t *testing.T) {
+	tests := []struct {
+		minor int64
+		mode  facade.RoundingMode
+		want  int64
+	}{
+		{minor: 25, mode: facade.RoundHalfEven, want: 2},
+		{minor: 35, mode: facade.RoundHalfEven, want: 4},
+		{minor: 25, mode: facade.RoundHalfUp, want: 3},
+		{minor: -25, mode: facade.RoundHalfUp, want: -3},
+		{minor: 29, mode: facade.RoundDown, want: 2},
+		{minor: 21, mode: facade.RoundUp, want: 3},
+		{minor: -21, mode: facade.RoundUp, want: -3},
+	}
+	for _, tt := range tests {
+		m, _ := facade.NewMoney(tt.minor, "THB")
+		got, err := m.MulFrac(1, 10, tt.mode)
+		if err != nil || got.Minor() != tt.want {
+			t.Errorf("%d/10 with mode %d = %v, %v; want %d", tt.minor, tt.mode, got.Minor(), err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,330 @@
+package facade
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrRateUnavailable is returned when no exchange rate is known for a currency pair.
+var ErrRateUnavailable = errors.New("exchange rate unavailable")
+
+// FeePolicy decides the fee charged to the sender of a transfer. The fee is
+// in the currency of amount and is debited on top of it.
+type FeePolicy interface {
+	Fee(fromBank, toBank string, amount Money) (Money, error)
+}
+
+// FlatFee charges the same fee for every transfer.
+type FlatFee struct {
+	Amount Money
+}
+
+func (p FlatFee) Fee(fromBank, toBank string, amount Money) (Money, error) {
+	if p.Amount.Currency() != amount.Currency() {
+		return Money{}, fmt.Errorf("%w: flat fee in %s, transfer in %s", ErrCurrencyMismatch, p.Amount.Currency(), amount.Currency())
+	}
+	return p.Amount, nil
+}
+
+// PercentageFee charges a share of the amount in basis points (1/100 of a
+// percent), optionally bounded by Min and Max. Bounds without a currency are
+// ignored.
+type PercentageFee struct {
+	BasisPoints int64
+	Min         Money
+	Max         Money
+	Rounding    RoundingMode
+}
+
+func (p PercentageFee) Fee(fromBank, toBank string, amount Money) (Money, error) {
+	fee, err := amount.MulFrac(p.BasisPoints, 10000, p.Rounding)
+	if err != nil {
+		return Money{}, err
+	}
+	if p.Min.Currency() != "" {
+		if cmp, err := fee.Cmp(p.Min); err != nil {
+			return Money{}, err
+		} else if cmp < 0 {
+			fee = p.Min
+		}
+	}
+	if p.Max.Currency() != "" {
+		if cmp, err := fee.Cmp(p.Max); err != nil {
+			return Money{}, err
+		} else if cmp > 0 {
+			fee = p.Max
+		}
+	}
+	return fee, nil
+}
+
+// FeeTier applies Policy to amounts up to and including UpTo. A tier whose
+// UpTo has no currency has no upper bound.
+type FeeTier struct {
+	UpTo   Money
+	Policy FeePolicy
+}
+
+// TieredFee picks the first tier whose bound covers the amount.
+type TieredFee struct {
+	Tiers []FeeTier
+}
+
+func (p TieredFee) Fee(fromBank, toBank string, amount Money) (Money, error) {
+	for _, tier := range p.Tiers {
+		if tier.UpTo.Currency() == "" {
+			return tier.Policy.Fee(fromBank, toBank, amount)
+		}
+		cmp, err := amount.Cmp(tier.UpTo)
+		if err != nil {
+			return Money{}, err
+		}
+		if cmp <= 0 {
+			return tier.Policy.Fee(fromBank, toBank, amount)
+		}
+	}
+	return Money{}, fmt.Errorf("no fee tier covers %s", amount)
+}
+
+// InterbankFee applies Policy only when the source and destination banks
+// differ; transfers within one bank are free.
+type InterbankFee struct {
+	Policy FeePolicy
+}
+
+func (p InterbankFee) Fee(fromBank, toBank string, amount Money) (Money, error) {
+	if fromBank == toBank {
+		return Money{currency: amount.Currency()}, nil
+	}
+	return p.Policy.Fee(fromBank, toBank, amount)
+}
+
+// Rate is an exact exchange rate: one major unit of From buys num/den major
+// units of To.
+type Rate struct {
+	From string
+	To   string
+	num  int64
+	den  int64
+}
+
+// ParseRate parses a decimal rate such as "35.125" (THB per USD for From
+// "USD", To "THB").
+func ParseRate(from, to, value string) (Rate, error) {
+	for _, currency := range []string{from, to} {
+		if _, err := CurrencyExponent(currency); err != nil {
+			return Rate{}, err
+		}
+	}
+
+	whole, frac, _ := strings.Cut(strings.TrimSpace(value), ".")
+	if whole == "" || !isDigits(whole) || !isDigits(frac) || len(frac) > 9 {
+		return Rate{}, fmt.Errorf("invalid rate %q", value)
+	}
+	num, err := strconv.ParseInt(whole+frac, 10, 64)
+	if err != nil || num == 0 {
+		return Rate{}, fmt.Errorf("invalid rate %q", value)
+	}
+	den := int64(1)
+	for range frac {
+		den *= 10
+	}
+	return Rate{From: from, To: to, num: num, den: den}, nil
+}
+
+// Inverse returns the rate for the opposite direction.
+func (r Rate) Inverse() Rate {
+	return Rate{From: r.To, To: r.From, num: r.den, den: r.num}
+}
+
+// String formats the rate, e.g. "1 USD = 35.125 THB".
+func (r Rate) String() string {
+	if r.den == 0 {
+		return ""
+	}
+	value := strings.TrimRight(big.NewRat(r.num, r.den).FloatString(9), "0")
+	return fmt.Sprintf("1 %s = %s %s", r.From, strings.TrimSuffix(value, "."), r.To)
+}
+
+// Convert turns m (in From) into To, rounding to whole minor units with mode.
+func (r Rate) Convert(m Money, mode RoundingMode) (Money, error) {
+	if m.Currency() != r.From {
+		return Money{}, fmt.Errorf("%w: rate is for %s, amount in %s", ErrCurrencyMismatch, r.From, m.Currency())
+	}
+	fromExp, _ := CurrencyExponent(r.From)
+	toExp, _ := CurrencyExponent(r.To)
+
+	// Scale the rate to minor units; a rate too precise to scale cannot
+	// be applied exactly
+	num, den := r.num, r.den
+	for ; toExp > fromExp; toExp-- {
+		if num > math.MaxInt64/10 {
+			return Money{}, fmt.Errorf("%w: converting with rate %s", ErrAmountOverflow, r)
+		}
+		num *= 10
+	}
+	for ; fromExp > toExp; fromExp-- {
+		if den > math.MaxInt64/10 {
+			return Money{}, fmt.Errorf("%w: converting with rate %s", ErrAmountOverflow, r)
+		}
+		den *= 10
+	}
+	converted, err := m.MulFrac(num, den, mode)
+	if err != nil {
+		return Money{}, err
+	}
+	return Money{minor: converted.Minor(), currency: r.To}, nil
+}
+
+// RateProvider supplies exchange rates between currencies.
+type RateProvider interface {
+	Rate(from, to string) (Rate, error)
+}
+
+// StaticRates is a RateProvider backed by a fixed table. A pair is also
+// answered from its inverse when only the opposite direction is known.
+type StaticRates struct {
+	mu    sync.RWMutex
+	rates map[[2]string]Rate
+}
+
+// NewStaticRates creates a table holding rates.
+func NewStaticRates(rates ...Rate) *StaticRates {
+	s := &StaticRates{rates: make(map[[2]string]Rate)}
+	for _, rate := range rates {
+		s.Set(rate)
+	}
+	return s
+}
+
+// Set adds or replaces the rate for its currency pair.
+func (s *StaticRates) Set(rate Rate) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rates[[2]string{rate.From, rate.To}] = rate
+}
+
+func (s *StaticRates) Rate(from, to string) (Rate, error) {
+	if from == to {
+		return Rate{From: from, To: to, num: 1, den: 1}, nil
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if rate, exists := s.rates[[2]string{from, to}]; exists {
+		return rate, nil
+	}
+	if rate, exists := s.rates[[2]string{to, from}]; exists {
+		return rate.Inverse(), nil
+	}
+	return Rate{}, fmt.Errorf("%w: %s to %s", ErrRateUnavailable, from, to)
+}
+
+// WithFeePolicy charges senders the fee decided by policy.
+func WithFeePolicy(policy FeePolicy) Option {
+	return func(f *BankTransferFacade) error {
+		f.fees = policy
+		return nil
+	}
+}
+
+// WithRateProvider converts cross-currency transfers with rates from provider.
+func WithRateProvider(provider RateProvider) Option {
+	return func(f *BankTransferFacade) error {
+		if provider == nil {
+			return errors.New("rate provider must not be nil")
+		}
+		f.rates = provider
+		return nil
+	}
+}
+
+// quote is the priced form of a transfer request.
+type quote struct {
+	fee    Money
+	credit Money
+	rate   Rate
+}
+
+// rateText describes the applied rate, or is empty for same-currency transfers.
+func (q quote) rateText() string {
+	if q.rate.From == q.rate.To {
+		return ""
+	}
+	return q.rate.String()
+}
+
+// price computes the fee in the source currency and the amount the
+// destination account receives in its own currency.
+func (f *BankTransferFacade) price(fromBank, toBank string, amount Money, destinationCurrency string) (quote, error) {
+	q := quote{fee: Money{currency: amount.Currency()}, credit: amount}
+	if f.fees != nil {
+		fee, err := f.fees.Fee(fromBank, toBank, amount)
+		if err != nil {
+			return quote{}, fmt.Errorf("fee: %w", err)
+		}
+		if fee.Currency() != amount.Currency() || fee.IsNegative() {
+			return quote{}, fmt.Errorf("fee: policy returned invalid fee %s", fee)
+		}
+		q.fee = fee
+	}
+
+	rate, err := f.rates.Rate(amount.Currency(), destinationCurrency)
+	if err != nil {
+		return quote{}, err
+	}
+	q.rate = rate
+	if amount.Currency() != destinationCurrency {
+		if q.credit, err = rate.Convert(amount, RoundHalfEven); err != nil {
+			return quote{}, fmt.Errorf("convert: %w", err)
+		}
+		if !q.credit.IsPositive() {
+			return quote{}, fmt.Errorf("%w: %s converts to %s", ErrNonPositiveAmount, amount, q.credit)
+		}
+	}
+	return q, nil
+}
+
+// feeAccount is the fee income account of a bank.
+func feeAccount(bank string) AccountID {
+	return SystemAccount(bank, "fees")
+}
+
+// collectFee moves a committed fee out of settlement into the fee income
+// account of bank on ledger.
+func collectFee(ledger *Ledger, bank, ref string, fee Money) error {
+	if !fee.IsPositive() {
+		return fmt.Errorf("%w: %s", ErrNonPositiveAmount, fee)
+	}
+	income := feeAccount(bank)
+	if err := ledger.EnsureAccount(income, fee.Currency()); err != nil {
+		return err
+	}
+	settlement := settlementAccount(fee.Currency())
+	if err := ledger.EnsureAccount(settlement, fee.Currency()); err != nil {
+		return err
+	}
+	_, err := ledger.Move(ref, "transfer fee", settlement, income, fee)
+	return err
+}
+
+// bookFee asks the source bank, which holds the fee in its settlement, to
+// book a committed fee into its own fee income. Like compensation, it runs
+// detached from ctx: the transfer is committed and the fee must follow.
+func (f *BankTransferFacade) bookFee(ctx context.Context, source BankApi, record TransferRecord) error {
+	collector, ok := source.(FeeCollector)
+	if !ok || !record.Fee.IsPositive() {
+		return nil
+	}
+	ctx, cancel := f.bankContext(context.WithoutCancel(ctx), record.FromBank)
+	defer cancel()
+	return collector.CollectFee(ctx, record.ID, record.Fee)
+}
@@ -0,0 +1,125 @@
+package facade_test
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"go-design-patterns/structural/facade"
+)
+
+func TestRateConvert(t *testing.T) {
+	tests := []struct {
+		from, to, rate string
+		amount         string
+		mode           facade.RoundingMode
+		want           string
+	}{
+		{from: "USD", to: "THB", rate: "35.125", amount: "10.00", mode: facade.RoundHalfEven, want: "351.25 THB"},
+		{from: "THB", to: "JPY", rate: "4.1", amount: "0.05", mode: facade.RoundHalfEven, want: "0 JPY"},
+		{from: "THB", to: "JPY", rate: "4.1", amount: "0.15", mode: facade.RoundUp, want: "1 JPY"},
+		{from: "JPY", to: "KWD", rate: "0.002", amount: "1500", mode: facade.RoundHalfEven, want: "3.000 KWD"},
+	}
+	for _, tt := range tests {
+		rate, err := facade.ParseRate(tt.from, tt.to, tt.rate)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := rate.Convert(mustMoney(t, tt.amount, tt.from), tt.mode)
+		if err != nil || got.String() != tt.want {
+			t.Errorf("%s at %s = %v, %v; want %s", tt.amount, rate, got, err, tt.want)
+		}
+	}
+}
+
+func TestRateConvertOverflow(t *testing.T) {
+	// Scaling to three more decimals multiplies the rate by 1000
+	rate, err := facade.ParseRate("JPY", "KWD", "922337203685477580.7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := rate.Convert(mustMoney(t, "1", "JPY"), facade.RoundHalfEven); !errors.Is(err, facade.ErrAmountOverflow) {
+		t.Errorf("Convert = %v, %v; want ErrAmountOverflow", got, err)
+	}
+
+	if _, err := rate.Convert(mustMoney(t, "1", "THB"), facade.RoundHalfEven); !errors.Is(err, facade.ErrCurrencyMismatch) {
+		t.Errorf("Convert of the wrong currency = %v, want ErrCurrencyMismatch", err)
+	}
+}
+
+// TestFeeBookedByPayingBank checks that the fee ends up on the books of the
+// bank that holds the sender's account, not on the facade's own backend.
+func TestFeeBookedByPayingBank(t *testing.T) {
+	tests := []struct {
+		name    string
+		factory func(remote *facade.Backend) facade.BankFactory
+	}{
+		{
+			name: "in process",
+			factory: func(remote *facade.Backend) facade.BankFactory {
+				return func(accountNo, bank, pin string) facade.BankApi {
+					return facade.NewKBankApi(accountNo, bank, pin, remote)
+				}
+			},
+		},
+		{
+			name: "over HTTP",
+			factory: func(remote *facade.Backend) facade.BankFactory {
+				if err := remote.OpenAccount("Remote", "9000", "9999", mustMoney(t, "0", "THB")); err != nil {
+					t.Fatal(err)
+				}
+				bankServer, err := facade.NewBankServer("Remote", func(accountNo, bank, pin string) facade.BankApi {
+					return facade.NewKBankApi(accountNo, bank, pin, remote)
+				}, facade.WithClearingAccount("9000"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				server := httptest.NewServer(bankServer)
+				t.Cleanup(server.Close)
+				return facade.HTTPBankFactory(server.URL, "THB", server.Client(), facade.WithClearingLogin("9000", "9999"))
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			local, err := facade.NewDemoBackend()
+			if err != nil {
+				t.Fatal(err)
+			}
+			remote := facade.NewBackend(facade.NewMemoryCredentialStore(), facade.WithPinHashIterations(1000))
+			if err := remote.OpenAccount("Remote", "1001", "1111", mustMoney(t, "100.00", "THB")); err != nil {
+				t.Fatal(err)
+			}
+			if err := remote.OpenAccount("Remote", "1002", "2222", mustMoney(t, "0", "THB")); err != nil {
+				t.Fatal(err)
+			}
+
+			f, err := facade.NewBankTransferFacade(
+				facade.WithBackend(local),
+				facade.WithBank("Remote", tt.factory(remote)),
+				facade.WithFeePolicy(facade.FlatFee{Amount: mustMoney(t, "5.00", "THB")}),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			receipt, err := f.Transfer(context.Background(), "1001", "1111", "Remote", "1002", "Remote", mustMoney(t, "10.00", "THB"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if receipt.Total.Decimal() != "15.00" {
+				t.Errorf("total %s, want 15.00", receipt.Total)
+			}
+
+			fees := facade.SystemAccount("Remote", "fees")
+			wantBalance(t, remote.Ledger, fees, "5.00")
+			wantBalance(t, remote.Ledger, facade.SystemAccount("clearing", "settlement-THB"), "0.00")
+			if local.Ledger.HasAccount(fees) {
+				t.Error("fee booked on the facade's backend")
+			}
+			if err := remote.Ledger.Verify(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
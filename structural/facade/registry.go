@@ -0,0 +1,120 @@
+package facade
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// BankFactory creates a BankApi session for the given account.
+type BankFactory func(accountNo, bank, pin string) BankApi
+
+var (
+	// ErrBankAlreadyRegistered is returned when a bank name is registered twice.
+	ErrBankAlreadyRegistered = errors.New("bank already registered")
+	// ErrBankNotRegistered is returned when a bank name is not in the registry.
+	ErrBankNotRegistered = fmt.Errorf("%w: bank not registered", ErrUnsupportedBank)
+	// ErrInvalidBank is returned for an empty bank name or a nil factory.
+	ErrInvalidBank = errors.New("invalid bank registration")
+)
+
+// RegistryError describes a failed registry operation for a single bank.
+type RegistryError struct {
+	Op   string // "register", "unregister" or "lookup"
+	Bank string
+	Err  error
+}
+
+func (e *RegistryError) Error() string {
+	return fmt.Sprintf("%s bank %q: %v", e.Op, e.Bank, e.Err)
+}
+
+func (e *RegistryError) Unwrap() error {
+	return e.Err
+}
+
+// Option configures a BankTransferFacade during construction.
+type Option func(*BankTransferFacade) error
+
+// WithBank registers an additional bank. It replaces a built-in bank of the
+// same name but fails if the name was already added by another option.
+func WithBank(name string, factory BankFactory) Option {
+	return func(f *BankTransferFacade) error {
+		return f.RegisterBank(name, factory)
+	}
+}
+
+// WithoutDefaultBanks skips registration of the built-in SiamBank and KBank.
+func WithoutDefaultBanks() Option {
+	return func(f *BankTransferFacade) error {
+		f.registerDefaults = false
+		return nil
+	}
+}
+
+// WithBackend makes the built-in banks use backend instead of the demo backend.
+func WithBackend(backend *Backend) Option {
+	return func(f *BankTransferFacade) error {
+		if backend == nil || backend.Auth == nil || backend.Ledger == nil {
+			return errors.New("backend requires an authenticator and a ledger")
+		}
+		f.backend = backend
+		return nil
+	}
+}
+
+// RegisterBank adds a bank to the facade so it can be used as a source bank.
+func (f *BankTransferFacade) RegisterBank(name string, factory BankFactory) error {
+	if name == "" || factory == nil {
+		return &RegistryError{Op: "register", Bank: name, Err: ErrInvalidBank}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, exists := f.banks[name]; exists {
+		return &RegistryError{Op: "register", Bank: name, Err: ErrBankAlreadyRegistered}
+	}
+	f.banks[name] = factory
+	return nil
+}
+
+// UnregisterBank removes a bank from the facade.
+func (f *BankTransferFacade) UnregisterBank(name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, exists := f.banks[name]; !exists {
+		return &RegistryError{Op: "unregister", Bank: name, Err: ErrBankNotRegistered}
+	}
+	delete(f.banks, name)
+	return nil
+}
+
+// Banks returns the names of all registered banks in sorted order.
+func (f *BankTransferFacade) Banks() []string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	names := make([]string, 0, len(f.banks))
+	for name := range f.banks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// bankFactory looks up the factory registered for the given bank. The APIs
+// it creates are wrapped by the configured breaker, retries and decorators.
+func (f *BankTransferFacade) bankFactory(name string) (BankFactory, error) {
+	f.mu.RLock()
+	factory, exists := f.banks[name]
+	f.mu.RUnlock()
+
+	if !exists {
+		return nil, &RegistryError{Op: "lookup", Bank: name, Err: ErrBankNotRegistered}
+	}
+	return func(accountNo, bank, pin string) BankApi {
+		return f.decorate(name, factory(accountNo, bank, pin))
+	}, nil
+}
@@ -0,0 +1,110 @@
+package facade_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"go-design-patterns/structural/facade"
+	"go-design-patterns/structural/facade/facadetest"
+)
+
+func TestDefaultBanks(t *testing.T) {
+	f, err := facade.NewBankTransferFacade()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := f.Banks(), []string{"KBank", "SiamBank"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Banks() = %v, want %v", got, want)
+	}
+
+	f, err = facade.NewBankTransferFacade(facade.WithoutDefaultBanks())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := f.Banks(); len(got) != 0 {
+		t.Errorf("Banks() without defaults = %v, want none", got)
+	}
+}
+
+func TestWithBankReplacesDefault(t *testing.T) {
+	backend, err := facade.NewDemoBackend()
+	if err != nil {
+		t.Fatal(err)
+	}
+	created := 0
+	custom := func(accountNo, bank, pin string) facade.BankApi {
+		created++
+		return facade.NewKBankApi(accountNo, bank, pin, backend)
+	}
+	f, err := facade.NewBankTransferFacade(facade.WithBackend(backend), facade.WithBank("KBank", custom))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Balance(context.Background(), "9876543210", "4321", "KBank"); err != nil {
+		t.Fatal(err)
+	}
+	if created != 1 {
+		t.Errorf("custom KBank factory called %d times, want 1", created)
+	}
+	if got, want := f.Banks(), []string{"KBank", "SiamBank"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Banks() = %v, want %v", got, want)
+	}
+}
+
+func TestRegistryErrors(t *testing.T) {
+	factory := func(accountNo, bank, pin string) facade.BankApi { return nil }
+
+	_, err := facade.NewBankTransferFacade(facade.WithBank("Extra", factory), facade.WithBank("Extra", factory))
+	wantRegistryError(t, err, "register", "Extra", facade.ErrBankAlreadyRegistered)
+
+	f, err := facade.NewBankTransferFacade()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantRegistryError(t, f.RegisterBank("", factory), "register", "", facade.ErrInvalidBank)
+	wantRegistryError(t, f.RegisterBank("Extra", nil), "register", "Extra", facade.ErrInvalidBank)
+	wantRegistryError(t, f.RegisterBank("KBank", factory), "register", "KBank", facade.ErrBankAlreadyRegistered)
+	wantRegistryError(t, f.UnregisterBank("Nowhere"), "unregister", "Nowhere", facade.ErrBankNotRegistered)
+
+	if _, err := facade.NewBankTransferFacade(facade.WithBackend(nil)); err == nil {
+		t.Error("WithBackend(nil) was accepted")
+	}
+}
+
+func TestUnregisteredBankIsUnsupported(t *testing.T) {
+	ctx := context.Background()
+	h, err := facadetest.NewHarness(facadetest.StandardBanks())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Facade.UnregisterBank("Beta"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := h.Facade.Banks(), []string{"Alpha", "Gamma", "Sink"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Banks() = %v, want %v", got, want)
+	}
+
+	from, to := facadetest.AlphaMain, facadetest.BetaMain
+	amount, _ := facade.ParseMoney("10.00", "THB")
+	_, err = h.Facade.Transfer(ctx, from.AccountNo, from.Pin, from.Bank, to.AccountNo, to.Bank, amount)
+	wantRegistryError(t, err, "lookup", "Beta", facade.ErrBankNotRegistered)
+	if !errors.Is(err, facade.ErrUnsupportedBank) {
+		t.Errorf("error %v does not match ErrUnsupportedBank", err)
+	}
+	if calls := h.Bank("Alpha").Calls(facade.OpLogin); calls != 0 {
+		t.Errorf("source bank was called %d times for an unregistered destination", calls)
+	}
+}
+
+func wantRegistryError(t *testing.T, err error, op, bank string, sentinel error) {
+	t.Helper()
+	var registryErr *facade.RegistryError
+	if !errors.As(err, &registryErr) {
+		t.Fatalf("error %v is not a *RegistryError", err)
+	}
+	if registryErr.Op != op || registryErr.Bank != bank || !errors.Is(err, sentinel) {
+		t.Errorf("error %v, want %s of %q matching %v", err, op, bank, sentinel)
+	}
+}
@@ -0,0 +1,506 @@
+package facade
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without calling the bank while its circuit breaker is open.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// BankOperation names a bank call for retry and breaker configuration.
+type BankOperation string
+
+const (
+	OpLogin    BankOperation = "login"
+	OpLogout   BankOperation = "logout"
+	OpTransfer BankOperation = "transfer"
+	OpConfirm  BankOperation = "confirm"
+	OpCancel   BankOperation = "cancel"
+	OpBalance  BankOperation = "balance"
+	OpCredit   BankOperation = "credit"
+	OpReverse  BankOperation = "reverse"
+	OpFee      BankOperation = "fee"
+)
+
+// IsTransient reports whether err is a temporary bank failure, such as an
+// unavailable or rate-limited bank server, that may succeed when repeated.
+func IsTransient(err error) bool {
+	var temporary interface{ Temporary() bool }
+	return errors.As(err, &temporary) && temporary.Temporary()
+}
+
+// bankCall runs call, possibly several times or not at all.
+type bankCall func(ctx context.Context, op BankOperation, call func(context.Context) error) error
+
+// guardedBankApi routes every call of a BankApi through a bankCall.
+type guardedBankApi struct {
+	api  BankApi
+	call bankCall
+}
+
+// guardedReceiver also guards the Receiver methods of the wrapped API.
+type guardedReceiver struct {
+	*guardedBankApi
+	receiver Receiver
+}
+
+// guard wraps api, keeping it usable as a Receiver if it was one.
+func guard(api BankApi, call bankCall) BankApi {
+	guarded := &guardedBankApi{api: api, call: call}
+	if receiver, ok := api.(Receiver); ok {
+		return &guardedReceiver{guardedBankApi: guarded, receiver: receiver}
+	}
+	return guarded
+}
+
+func (g *guardedBankApi) Login(ctx context.Context) error {
+	return g.call(ctx, OpLogin, g.api.Login)
+}
+
+func (g *guardedBankApi) Transfer(ctx context.Context, toAcc, toBank string, amount Money) (string, error) {
+	var confToken string
+	err := g.call(ctx, OpTransfer, func(ctx context.Context) error {
+		var err error
+		confToken, err = g.api.Transfer(ctx, toAcc, toBank, amount)
+		return err
+	})
+	return confToken, err
+}
+
+func (g *guardedBankApi) Confirm(ctx context.Context, confToken string) error {
+	return g.call(ctx, OpConfirm, func(ctx context.Context) error {
+		return g.api.Confirm(ctx, confToken)
+	})
+}
+
+func (g *guardedBankApi) Cancel(ctx context.Context, confToken string) error {
+	return g.call(ctx, OpCancel, func(ctx context.Context) error {
+		return g.api.Cancel(ctx, confToken)
+	})
+}
+
+func (g *guardedBankApi) Balance(ctx context.Context) (Money, error) {
+	var balance Money
+	err := g.call(ctx, OpBalance, func(ctx context.Context) error {
+		var err error
+		balance, err = g.api.Balance(ctx)
+		return err
+	})
+	return balance, err
+}
+
+func (g *guardedBankApi) IsLoggedIn() bool {
+	return g.api.IsLoggedIn()
+}
+
+// SessionExpiry forwards to the wrapped API, or is zero if it does not know.
+func (g *guardedBankApi) SessionExpiry() time.Time {
+	if expirer, ok := g.api.(sessionExpirer); ok {
+		return expirer.SessionExpiry()
+	}
+	return time.Time{}
+}
+
+// Logout forwards to the wrapped API, if it supports logging out.
+func (g *guardedBankApi) Logout(ctx context.Context) error {
+	logouter, ok := g.api.(sessionLogouter)
+	if !ok {
+		return nil
+	}
+	return g.call(ctx, OpLogout, logouter.Logout)
+}
+
+// CollectFee forwards to the wrapped API, if it collects fees.
+func (g *guardedBankApi) CollectFee(ctx context.Context, ref string, fee Money) error {
+	collector, ok := g.api.(FeeCollector)
+	if !ok {
+		return nil
+	}
+	return g.call(ctx, OpFee, func(ctx context.Context) error {
+		return collector.CollectFee(ctx, ref, fee)
+	})
+}
+
+func (g *guardedReceiver) AccountCurrency() string {
+	return g.receiver.AccountCurrency()
+}
+
+func (g *guardedReceiver) Credit(ctx context.Context, ref string, amount Money) error {
+	return g.call(ctx, OpCredit, func(ctx context.Context) error {
+		return g.receiver.Credit(ctx, ref, amount)
+	})
+}
+
+func (g *guardedReceiver) Reverse(ctx context.Context, ref string) error {
+	return g.call(ctx, OpReverse, func(ctx context.Context) error {
+		return g.receiver.Reverse(ctx, ref)
+	})
+}
+
+// RetryPolicy repeats failed bank calls with exponential backoff and jitter.
+// Only operations listed in SafeOperations are repeated, since repeating a
+// call that moves money may move it twice.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first.
+	MaxAttempts int
+	// BaseDelay is the wait before the second attempt; each further wait
+	// is Multiplier times longer, capped at MaxDelay.
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+	Multiplier float64
+	// Jitter randomly shortens each wait by up to this fraction (0 to 1)
+	// so clients that failed together do not retry together.
+	Jitter float64
+	// SafeOperations lists the operations that may be repeated. Empty
+	// means OpLogin and OpBalance.
+	SafeOperations []BankOperation
+	// Retryable decides which errors are worth another attempt. Nil means
+	// IsTransient.
+	Retryable func(error) bool
+	// OnRetry, if set, is called before waiting for the next attempt.
+	OnRetry func(op BankOperation, attempt int, err error, delay time.Duration)
+}
+
+// NewRetryingBankApi wraps api so that safe operations failing with a
+// retryable error are attempted again according to policy.
+func NewRetryingBankApi(api BankApi, policy RetryPolicy) BankApi {
+	return guard(api, policy.do)
+}
+
+func (p RetryPolicy) safe(op BankOperation) bool {
+	if len(p.SafeOperations) == 0 {
+		return op == OpLogin || op == OpBalance
+	}
+	for _, safe := range p.SafeOperations {
+		if safe == op {
+			return true
+		}
+	}
+	return false
+}
+
+// delay is the wait after the given failed attempt (1-based).
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 2
+	}
+	delay := float64(p.BaseDelay)
+	for i := 1; i < attempt; i++ {
+		delay *= multiplier
+		if p.MaxDelay > 0 && delay >= float64(p.MaxDelay) {
+			break
+		}
+	}
+	if p.MaxDelay > 0 && delay > float64(p.MaxDelay) {
+		delay = float64(p.MaxDelay)
+	}
+	if jitter := min(max(p.Jitter, 0), 1); jitter > 0 {
+		delay -= delay * jitter * rand.Float64()
+	}
+	return time.Duration(delay)
+}
+
+func (p RetryPolicy) do(ctx context.Context, op BankOperation, call func(context.Context) error) error {
+	retryable := p.Retryable
+	if retryable == nil {
+		retryable = IsTransient
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := call(ctx)
+		if err == nil || attempt >= p.MaxAttempts || !p.safe(op) || !retryable(err) || ctx.Err() != nil {
+			return err
+		}
+
+		delay := p.delay(attempt)
+		if p.OnRetry != nil {
+			p.OnRetry(op, attempt, err, delay)
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// BreakerState is the state of a circuit breaker.
+type BreakerState string
+
+const (
+	// BreakerClosed lets calls through and counts consecutive failures.
+	BreakerClosed BreakerState = "closed"
+	// BreakerOpen rejects calls until the open timeout has passed.
+	BreakerOpen BreakerState = "open"
+	// BreakerHalfOpen lets a limited number of probe calls through.
+	BreakerHalfOpen BreakerState = "half-open"
+)
+
+// BreakerStateChange is reported whenever a circuit breaker changes state.
+type BreakerStateChange struct {
+	Bank string
+	From BreakerState
+	To   BreakerState
+	At   time.Time
+	// Err is the failure that opened the breaker, if any.
+	Err error
+}
+
+// BreakerConfig configures a circuit breaker.
+type BreakerConfig struct {
+	// FailureThreshold is the number of consecutive failures that opens
+	// the breaker. Zero means 5.
+	FailureThreshold int
+	// OpenTimeout is how long the breaker stays open before probing the
+	// bank again. Zero means 30 seconds.
+	OpenTimeout time.Duration
+	// HalfOpenProbes is the number of successful probes that close the
+	// breaker again; no more calls than this run while half-open. Zero means 1.
+	HalfOpenProbes int
+	// IsFailure decides which errors count against the bank. Nil counts
+	// transient errors and timeouts, but not errors such as insufficient
+	// funds that show the bank is working.
+	IsFailure func(error) bool
+	// OnStateChange, if set, is called after every state change.
+	OnStateChange func(BreakerStateChange)
+	// Clock returns the current time. Nil means time.Now.
+	Clock func() time.Time
+}
+
+// CircuitBreaker stops calls to a failing bank for a while so that it can
+// recover and callers fail fast instead of waiting for timeouts.
+type CircuitBreaker struct {
+	bank   string
+	config BreakerConfig
+
+	mu         sync.Mutex
+	state      BreakerState
+	generation uint64
+	failures   int
+	openedAt   time.Time
+	probes     int
+	successes  int
+}
+
+// NewCircuitBreaker creates a closed breaker for bank.
+func NewCircuitBreaker(bank string, config BreakerConfig) *CircuitBreaker {
+	if config.FailureThreshold <= 0 {
+		config.FailureThreshold = 5
+	}
+	if config.OpenTimeout <= 0 {
+		config.OpenTimeout = 30 * time.Second
+	}
+	if config.HalfOpenProbes <= 0 {
+		config.HalfOpenProbes = 1
+	}
+	if config.IsFailure == nil {
+		config.IsFailure = isBankFailure
+	}
+	if config.Clock == nil {
+		config.Clock = time.Now
+	}
+	return &CircuitBreaker{bank: bank, config: config, state: BreakerClosed}
+}
+
+// isBankFailure is the default BreakerConfig.IsFailure.
+func isBankFailure(err error) bool {
+	return IsTransient(err) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// NewCircuitBreakerBankApi wraps api so that all its calls go through breaker.
+func NewCircuitBreakerBankApi(api BankApi, breaker *CircuitBreaker) BankApi {
+	return guard(api, breaker.do)
+}
+
+// Bank returns the name of the bank the breaker protects.
+func (b *CircuitBreaker) Bank() string {
+	return b.bank
+}
+
+// State returns the current state, moving an open breaker whose timeout has
+// passed to half-open.
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	change := b.expire()
+	state := b.state
+	b.mu.Unlock()
+
+	b.notify(change)
+	return state
+}
+
+func (b *CircuitBreaker) do(ctx context.Context, op BankOperation, call func(context.Context) error) error {
+	generation, err := b.admit()
+	if err != nil {
+		return err
+	}
+	err = call(ctx)
+	b.record(generation, err)
+	return err
+}
+
+// admit reserves a slot for a call or rejects it while the breaker is open.
+// It returns the generation the outcome of the call belongs to.
+func (b *CircuitBreaker) admit() (uint64, error) {
+	b.mu.Lock()
+	change := b.expire()
+	var err error
+	switch {
+	case b.state == BreakerOpen:
+		retryAt := b.openedAt.Add(b.config.OpenTimeout)
+		err = fmt.Errorf("bank %s: %w until %s", b.bank, ErrCircuitOpen, retryAt.Format(time.RFC3339))
+	case b.state == BreakerHalfOpen && b.probes >= b.config.HalfOpenProbes:
+		err = fmt.Errorf("bank %s: %w while probing", b.bank, ErrCircuitOpen)
+	case b.state == BreakerHalfOpen:
+		b.probes++
+	}
+	generation := b.generation
+	b.mu.Unlock()
+
+	b.notify(change)
+	return generation, err
+}
+
+// record updates the breaker with the outcome of an admitted call. Outcomes
+// of calls admitted before the last state change are ignored.
+func (b *CircuitBreaker) record(generation uint64, err error) {
+	failed := err != nil && b.config.IsFailure(err)
+	// A caller giving up says nothing about the bank
+	neutral := err != nil && !failed && errors.Is(err, context.Canceled)
+
+	b.mu.Lock()
+	var change *BreakerStateChange
+	if generation != b.generation {
+		b.mu.Unlock()
+		return
+	}
+	switch b.state {
+	case BreakerClosed:
+		switch {
+		case failed:
+			b.failures++
+			if b.failures >= b.config.FailureThreshold {
+				change = b.transition(BreakerOpen, err)
+			}
+		case !neutral:
+			b.failures = 0
+		}
+	case BreakerHalfOpen:
+		b.probes--
+		switch {
+		case failed:
+			change = b.transition(BreakerOpen, err)
+		case !neutral:
+			b.successes++
+			if b.successes >= b.config.HalfOpenProbes {
+				change = b.transition(BreakerClosed, nil)
+			}
+		}
+	}
+	b.mu.Unlock()
+
+	b.notify(change)
+}
+
+// expire moves an open breaker to half-open once its timeout has passed.
+// Callers must hold b.mu.
+func (b *CircuitBreaker) expire() *BreakerStateChange {
+	if b.state != BreakerOpen || b.config.Clock().Before(b.openedAt.Add(b.config.OpenTimeout)) {
+		return nil
+	}
+	return b.transition(BreakerHalfOpen, nil)
+}
+
+// transition switches to state and resets the counters. Callers must hold b.mu.
+func (b *CircuitBreaker) transition(state BreakerState, err error) *BreakerStateChange {
+	change := &BreakerStateChange{Bank: b.bank, From: b.state, To: state, At: b.config.Clock(), Err: err}
+	b.state = state
+	b.generation++
+	b.failures = 0
+	b.probes = 0
+	b.successes = 0
+	if state == BreakerOpen {
+		b.openedAt = change.At
+	}
+	return change
+}
+
+func (b *CircuitBreaker) notify(change *BreakerStateChange) {
+	if change != nil && b.config.OnStateChange != nil {
+		b.config.OnStateChange(*change)
+	}
+}
+
+// BankDecorator wraps the API of a bank with additional behaviour.
+type BankDecorator func(bank string, api BankApi) BankApi
+
+// WithRetry retries safe bank calls of every bank according to policy.
+func WithRetry(policy RetryPolicy) Option {
+	return func(f *BankTransferFacade) error {
+		if policy.MaxAttempts < 1 {
+			return errors.New("retry policy needs at least one attempt")
+		}
+		f.retry = &policy
+		return nil
+	}
+}
+
+// WithCircuitBreaker gives every bank its own circuit breaker configured by config.
+func WithCircuitBreaker(config BreakerConfig) Option {
+	return func(f *BankTransferFacade) error {
+		f.breakerConfig = &config
+		return nil
+	}
+}
+
+// WithBankDecorator wraps every bank API with decorator. Decorators apply in
+// the order given, outside the circuit breaker and retries.
+func WithBankDecorator(decorator BankDecorator) Option {
+	return func(f *BankTransferFacade) error {
+		if decorator == nil {
+			return errors.New("bank decorator must not be nil")
+		}
+		f.decorators = append(f.decorators, decorator)
+		return nil
+	}
+}
+
+// CircuitBreaker returns the breaker of bank, or nil if breakers are not enabled.
+func (f *BankTransferFacade) CircuitBreaker(bank string) *CircuitBreaker {
+	if f.breakerConfig == nil {
+		return nil
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	breaker, exists := f.breakers[bank]
+	if !exists {
+		breaker = NewCircuitBreaker(bank, *f.breakerConfig)
+		f.breakers[bank] = breaker
+	}
+	return breaker
+}
+
+// decorate applies the configured breaker, retries and decorators to api.
+// Retries sit outside the breaker, so an open breaker stops them at once.
+func (f *BankTransferFacade) decorate(bank string, api BankApi) BankApi {
+	if breaker := f.CircuitBreaker(bank); breaker != nil {
+		api = NewCircuitBreakerBankApi(api, breaker)
+	}
+	if f.retry != nil {
+		api = NewRetryingBankApi(api, *f.retry)
+	}
+	for _, decorator := range f.decorators {
+		api = decorator(bank, api)
+	}
+	return api
+}
@@ -0,0 +1,286 @@
+package facade_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"go-design-patterns/structural/facade"
+)
+
+// stubApi is a BankApi whose Balance and Transfer return the next error of
+// their script, nil once it runs out.
+type stubApi struct {
+	mu        sync.Mutex
+	balance   []error
+	transfer  []error
+	calls     map[facade.BankOperation]int
+	beforeRun func(op facade.BankOperation)
+}
+
+func (s *stubApi) next(op facade.BankOperation, script *[]error) error {
+	s.mu.Lock()
+	if s.calls == nil {
+		s.calls = make(map[facade.BankOperation]int)
+	}
+	s.calls[op]++
+	var err error
+	if len(*script) > 0 {
+		err, *script = (*script)[0], (*script)[1:]
+	}
+	before := s.beforeRun
+	s.mu.Unlock()
+
+	if before != nil {
+		before(op)
+	}
+	return err
+}
+
+func (s *stubApi) Calls(op facade.BankOperation) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[op]
+}
+
+func (s *stubApi) Login(ctx context.Context) error                     { return nil }
+func (s *stubApi) IsLoggedIn() bool                                    { return true }
+func (s *stubApi) Confirm(ctx context.Context, confToken string) error { return nil }
+func (s *stubApi) Cancel(ctx context.Context, confToken string) error  { return nil }
+
+func (s *stubApi) Transfer(ctx context.Context, toAcc, toBank string, amount facade.Money) (string, error) {
+	return "token", s.next(facade.OpTransfer, &s.transfer)
+}
+
+func (s *stubApi) Balance(ctx context.Context) (facade.Money, error) {
+	return facade.Money{}, s.next(facade.OpBalance, &s.balance)
+}
+
+func transient() error {
+	return &facade.BankError{Bank: "Stub", Op: facade.OpBalance, Retryable: true, Err: errors.New("unavailable")}
+}
+
+func TestRetryPolicy(t *testing.T) {
+	permanent := errors.New("account closed")
+	tests := []struct {
+		name      string
+		balance   []error
+		transfer  []error
+		op        facade.BankOperation
+		wantCalls int
+		wantErr   bool
+	}{
+		{name: "transient failures of a safe call are retried", op: facade.OpBalance, balance: []error{transient(), transient()}, wantCalls: 3},
+		{name: "attempts are capped", op: facade.OpBalance, balance: []error{transient(), transient(), transient(), transient()}, wantCalls: 4, wantErr: true},
+		{name: "permanent failures are not retried", op: facade.OpBalance, balance: []error{permanent}, wantCalls: 1, wantErr: true},
+		{name: "calls that move money are not retried", op: facade.OpTransfer, transfer: []error{transient()}, wantCalls: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := &stubApi{balance: tt.balance, transfer: tt.transfer}
+			var delays []time.Duration
+			api := facade.NewRetryingBankApi(stub, facade.RetryPolicy{
+				MaxAttempts: 4,
+				BaseDelay:   time.Millisecond,
+				MaxDelay:    3 * time.Millisecond,
+				OnRetry: func(op facade.BankOperation, attempt int, err error, delay time.Duration) {
+					delays = append(delays, delay)
+				},
+			})
+
+			var err error
+			if tt.op == facade.OpTransfer {
+				_, err = api.Transfer(context.Background(), "2001", "Beta", facade.Money{})
+			} else {
+				_, err = api.Balance(context.Background())
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("error = %v, want error %t", err, tt.wantErr)
+			}
+			if got := stub.Calls(tt.op); got != tt.wantCalls {
+				t.Errorf("%d calls, want %d", got, tt.wantCalls)
+			}
+			// Delays double from BaseDelay and stop at MaxDelay
+			want := []time.Duration{time.Millisecond, 2 * time.Millisecond, 3 * time.Millisecond}[:tt.wantCalls-1]
+			if len(delays) != len(want) || len(want) > 0 && !reflect.DeepEqual(delays, want) {
+				t.Errorf("delays %v, want %v", delays, want)
+			}
+		})
+	}
+}
+
+func TestRetryStopsWhenContextEnds(t *testing.T) {
+	stub := &stubApi{balance: []error{transient(), transient()}}
+	api := facade.NewRetryingBankApi(stub, facade.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Hour})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := api.Balance(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("error = %v, want context.DeadlineExceeded", err)
+	}
+	if calls := stub.Calls(facade.OpBalance); calls != 1 {
+		t.Errorf("%d calls, want 1", calls)
+	}
+}
+
+// breakerUnderTest is a breaker with a test clock that records its state changes.
+type breakerUnderTest struct {
+	*facade.CircuitBreaker
+	clock   *testClock
+	changes []string
+}
+
+func newBreakerUnderTest(config facade.BreakerConfig) *breakerUnderTest {
+	b := &breakerUnderTest{clock: newTestClock()}
+	config.Clock = b.clock.Now
+	config.OnStateChange = func(change facade.BreakerStateChange) {
+		b.changes = append(b.changes, string(change.From)+">"+string(change.To))
+	}
+	b.CircuitBreaker = facade.NewCircuitBreaker("Stub", config)
+	return b
+}
+
+func TestCircuitBreakerStates(t *testing.T) {
+	b := newBreakerUnderTest(facade.BreakerConfig{FailureThreshold: 2, OpenTimeout: time.Minute, HalfOpenProbes: 1})
+	stub := &stubApi{balance: []error{
+		transient(),
+		facade.ErrInsufficientFunds, // the bank answered, so the count starts over
+		transient(),
+		transient(), // opens
+		transient(), // probe fails, opens again
+		// the next probe succeeds and closes
+	}}
+	api := facade.NewCircuitBreakerBankApi(stub, b.CircuitBreaker)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		api.Balance(ctx)
+	}
+	if b.State() != facade.BreakerClosed {
+		t.Fatalf("breaker %s after failures interrupted by a bank answer", b.State())
+	}
+	api.Balance(ctx)
+	if b.State() != facade.BreakerOpen {
+		t.Fatalf("breaker %s after 2 failures in a row", b.State())
+	}
+	if _, err := api.Balance(ctx); !errors.Is(err, facade.ErrCircuitOpen) {
+		t.Errorf("call while open = %v, want ErrCircuitOpen", err)
+	}
+	if calls := stub.Calls(facade.OpBalance); calls != 4 {
+		t.Errorf("open breaker let a call through: %d calls", calls)
+	}
+
+	b.clock.Advance(time.Minute)
+	if b.State() != facade.BreakerHalfOpen {
+		t.Fatalf("breaker %s after the open timeout", b.State())
+	}
+	api.Balance(ctx)
+	if b.State() != facade.BreakerOpen {
+		t.Fatalf("breaker %s after a failed probe", b.State())
+	}
+	b.clock.Advance(time.Minute)
+	if _, err := api.Balance(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if b.State() != facade.BreakerClosed {
+		t.Fatalf("breaker %s after a successful probe", b.State())
+	}
+
+	want := []string{"closed>open", "open>half-open", "half-open>open", "open>half-open", "half-open>closed"}
+	if !reflect.DeepEqual(b.changes, want) {
+		t.Errorf("state changes %v, want %v", b.changes, want)
+	}
+}
+
+func TestCircuitBreakerLimitsProbes(t *testing.T) {
+	b := newBreakerUnderTest(facade.BreakerConfig{FailureThreshold: 1, OpenTimeout: time.Minute, HalfOpenProbes: 1})
+	release := make(chan struct{})
+	started := make(chan struct{})
+	stub := &stubApi{balance: []error{transient()}}
+	api := facade.NewCircuitBreakerBankApi(stub, b.CircuitBreaker)
+	api.Balance(context.Background())
+	b.clock.Advance(time.Minute)
+
+	// The probe blocks until released; a second call meanwhile is refused
+	stub.beforeRun = func(facade.BankOperation) {
+		close(started)
+		<-release
+	}
+	done := make(chan error)
+	go func() {
+		_, err := api.Balance(context.Background())
+		done <- err
+	}()
+	<-started
+	if _, err := api.Balance(context.Background()); !errors.Is(err, facade.ErrCircuitOpen) {
+		t.Errorf("second call while probing = %v, want ErrCircuitOpen", err)
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if b.State() != facade.BreakerClosed {
+		t.Errorf("breaker %s after the probe succeeded", b.State())
+	}
+}
+
+// TestCircuitBreakerIgnoresStaleOutcomes checks that a call admitted before
+// a state change cannot move the breaker of a later generation.
+func TestCircuitBreakerIgnoresStaleOutcomes(t *testing.T) {
+	tests := []struct {
+		name      string
+		slowErr   error
+		wantState facade.BreakerState
+	}{
+		{name: "stale success does not close a half-open breaker", slowErr: nil, wantState: facade.BreakerHalfOpen},
+		{name: "stale failure does not reopen a half-open breaker", slowErr: transient(), wantState: facade.BreakerHalfOpen},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newBreakerUnderTest(facade.BreakerConfig{FailureThreshold: 1, OpenTimeout: time.Minute, HalfOpenProbes: 1})
+			release := make(chan struct{})
+			started := make(chan struct{})
+			slow := &stubApi{balance: []error{tt.slowErr}, beforeRun: func(facade.BankOperation) {
+				close(started)
+				<-release
+			}}
+			fast := &stubApi{balance: []error{transient()}}
+
+			// The slow call is admitted while closed
+			done := make(chan struct{})
+			go func() {
+				facade.NewCircuitBreakerBankApi(slow, b.CircuitBreaker).Balance(context.Background())
+				close(done)
+			}()
+			<-started
+
+			// Meanwhile the breaker opens and its timeout passes
+			facade.NewCircuitBreakerBankApi(fast, b.CircuitBreaker).Balance(context.Background())
+			b.clock.Advance(time.Minute)
+			if b.State() != facade.BreakerHalfOpen {
+				t.Fatalf("breaker %s, want half-open", b.State())
+			}
+
+			close(release)
+			<-done
+			if got := b.State(); got != tt.wantState {
+				t.Errorf("breaker %s after the stale outcome, want %s", got, tt.wantState)
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerIgnoresCancelledCalls(t *testing.T) {
+	b := newBreakerUnderTest(facade.BreakerConfig{FailureThreshold: 2})
+	stub := &stubApi{balance: []error{transient(), context.Canceled, transient()}}
+	api := facade.NewCircuitBreakerBankApi(stub, b.CircuitBreaker)
+	for i := 0; i < 3; i++ {
+		api.Balance(context.Background())
+	}
+	if b.State() != facade.BreakerOpen {
+		t.Errorf("breaker %s: a cancelled call reset the failure count", b.State())
+	}
+}
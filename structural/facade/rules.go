@@ -0,0 +1,414 @@
+package facade
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrTransferRejected is returned when a pre-transfer rule refuses a transfer.
+var ErrTransferRejected = errors.New("transfer rejected")
+
+// TransferRequest is a transfer about to be made, as seen by rules.
+type TransferRequest struct {
+	FromBank    string
+	FromAccount string
+	ToBank      string
+	ToAccount   string
+	Amount      Money
+	Time        time.Time
+	// Rates converts the amount for rules whose limits are in another
+	// currency; the facade passes its own rate provider.
+	Rates RateProvider
+}
+
+// RejectionError says which rule refused a transfer and why. Code is stable
+// and meant for programs; Reason is meant for people.
+type RejectionError struct {
+	Rule   string
+	Code   string
+	Reason string
+}
+
+func (e *RejectionError) Error() string {
+	return fmt.Sprintf("%v by %s: %s", ErrTransferRejected, e.Rule, e.Reason)
+}
+
+func (e *RejectionError) Unwrap() error {
+	return ErrTransferRejected
+}
+
+// TransferRule checks a transfer before any bank is called. It returns a
+// *RejectionError to refuse the transfer.
+type TransferRule interface {
+	Check(ctx context.Context, req TransferRequest) error
+}
+
+// TransferRecorder is implemented by rules that keep state about transfers
+// they let through. Record is called once for every passed check, with
+// committed reporting whether the money actually moved.
+type TransferRecorder interface {
+	Record(req TransferRequest, committed bool)
+}
+
+// RuleChain hands a transfer from rule to rule until one refuses it. A chain
+// is itself a rule, so chains can be nested.
+type RuleChain struct {
+	rules []TransferRule
+}
+
+// NewRuleChain creates a chain running rules in order.
+func NewRuleChain(rules ...TransferRule) *RuleChain {
+	return &RuleChain{rules: rules}
+}
+
+// Then appends rule to the end of the chain.
+func (c *RuleChain) Then(rule TransferRule) *RuleChain {
+	c.rules = append(c.rules, rule)
+	return c
+}
+
+// Check runs the rules in order. When one refuses, the rules that already
+// passed the transfer are told it was not committed.
+func (c *RuleChain) Check(ctx context.Context, req TransferRequest) error {
+	for i, rule := range c.rules {
+		if err := rule.Check(ctx, req); err != nil {
+			recordAll(c.rules[:i], req, false)
+			return err
+		}
+	}
+	return nil
+}
+
+// Record passes the outcome of a transfer on to every rule that keeps state.
+func (c *RuleChain) Record(req TransferRequest, committed bool) {
+	recordAll(c.rules, req, committed)
+}
+
+func recordAll(rules []TransferRule, req TransferRequest, committed bool) {
+	for _, rule := range rules {
+		if recorder, ok := rule.(TransferRecorder); ok {
+			recorder.Record(req, committed)
+		}
+	}
+}
+
+// limitAmount returns the amount of req in the currency of limit,
+// converting it with req.Rates. Amounts that cannot be converted are
+// refused by rule, so no currency slips past a limit.
+func limitAmount(rule string, req TransferRequest, limit Money) (Money, error) {
+	if req.Amount.Currency() == limit.Currency() {
+		return req.Amount, nil
+	}
+	var err error = ErrRateUnavailable
+	if req.Rates != nil {
+		var rate Rate
+		if rate, err = req.Rates.Rate(req.Amount.Currency(), limit.Currency()); err == nil {
+			var converted Money
+			if converted, err = rate.Convert(req.Amount, RoundHalfUp); err == nil {
+				return converted, nil
+			}
+		}
+	}
+	return Money{}, &RejectionError{
+		Rule:   rule,
+		Code:   "currency_not_convertible",
+		Reason: fmt.Sprintf("%s cannot be checked against a limit in %s: %v", req.Amount, limit.Currency(), err),
+	}
+}
+
+// MaxAmount refuses single transfers above Limit. Amounts in other
+// currencies are converted with the facade's rates, and refused if there
+// is no rate.
+type MaxAmount struct {
+	Limit Money
+}
+
+func (r MaxAmount) Check(ctx context.Context, req TransferRequest) error {
+	amount, err := limitAmount("max-amount", req, r.Limit)
+	if err != nil {
+		return err
+	}
+	if cmp, _ := amount.Cmp(r.Limit); cmp > 0 {
+		return &RejectionError{
+			Rule:   "max-amount",
+			Code:   "amount_over_limit",
+			Reason: fmt.Sprintf("%s exceeds the per-transfer limit of %s", req.Amount, r.Limit),
+		}
+	}
+	return nil
+}
+
+// SelfTransfer refuses transfers whose source and destination are the same account.
+type SelfTransfer struct{}
+
+func (SelfTransfer) Check(ctx context.Context, req TransferRequest) error {
+	if req.FromBank == req.ToBank && req.FromAccount == req.ToAccount {
+		return &RejectionError{
+			Rule:   "self-transfer",
+			Code:   "self_transfer",
+			Reason: fmt.Sprintf("%s/%s cannot transfer to itself", req.FromBank, req.FromAccount),
+		}
+	}
+	return nil
+}
+
+// Denylist refuses transfers to blocked destination accounts. An entry with
+// an empty AccountNo blocks the whole bank.
+type Denylist struct {
+	mu      sync.RWMutex
+	entries map[AccountID]string
+}
+
+// NewDenylist creates a denylist blocking accounts.
+func NewDenylist(accounts ...AccountID) *Denylist {
+	d := &Denylist{entries: make(map[AccountID]string)}
+	for _, account := range accounts {
+		d.Block(account, "")
+	}
+	return d
+}
+
+// Block adds account to the denylist; reason is reported on rejection.
+func (d *Denylist) Block(account AccountID, reason string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.entries == nil {
+		d.entries = make(map[AccountID]string)
+	}
+	d.entries[account] = reason
+}
+
+// Unblock removes account from the denylist.
+func (d *Denylist) Unblock(account AccountID) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delete(d.entries, account)
+}
+
+func (d *Denylist) Check(ctx context.Context, req TransferRequest) error {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	for _, account := range []AccountID{{Bank: req.ToBank, AccountNo: req.ToAccount}, {Bank: req.ToBank}} {
+		reason, blocked := d.entries[account]
+		if !blocked {
+			continue
+		}
+		if reason == "" {
+			reason = "destination is blocked"
+		}
+		return &RejectionError{
+			Rule:   "denylist",
+			Code:   "destination_blocked",
+			Reason: fmt.Sprintf("%s/%s: %s", req.ToBank, req.ToAccount, reason),
+		}
+	}
+	return nil
+}
+
+// DailyLimit refuses transfers that would take the total sent from one
+// account on one calendar day above Limit. Transfers count from the moment
+// they pass the check, so concurrent transfers cannot overshoot the limit;
+// those that end up not committed are taken off again. Amounts in other
+// currencies count at their converted value, as with MaxAmount.
+type DailyLimit struct {
+	Limit    Money
+	Location *time.Location
+
+	mu   sync.Mutex
+	used map[dailyUsageKey]Money
+}
+
+type dailyUsageKey struct {
+	account AccountID
+	day     string
+}
+
+// NewDailyLimit creates a daily limit whose days start at midnight in loc.
+// A nil loc means UTC.
+func NewDailyLimit(limit Money, loc *time.Location) *DailyLimit {
+	if loc == nil {
+		loc = time.UTC
+	}
+	return &DailyLimit{Limit: limit, Location: loc, used: make(map[dailyUsageKey]Money)}
+}
+
+func (r *DailyLimit) key(req TransferRequest) dailyUsageKey {
+	return dailyUsageKey{
+		account: AccountID{Bank: req.FromBank, AccountNo: req.FromAccount},
+		day:     req.Time.In(r.Location).Format(time.DateOnly),
+	}
+}
+
+// Used returns the amount counted against the limit of account on the day of t.
+func (r *DailyLimit) Used(account AccountID, t time.Time) Money {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	used, exists := r.used[dailyUsageKey{account: account, day: t.In(r.Location).Format(time.DateOnly)}]
+	if !exists {
+		return Money{currency: r.Limit.Currency()}
+	}
+	return used
+}
+
+func (r *DailyLimit) Check(ctx context.Context, req TransferRequest) error {
+	amount, err := limitAmount("daily-limit", req, r.Limit)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.used == nil {
+		r.used = make(map[dailyUsageKey]Money)
+	}
+	key := r.key(req)
+	used, exists := r.used[key]
+	if !exists {
+		used = Money{currency: r.Limit.Currency()}
+	}
+	total, err := used.Add(amount)
+	if err != nil {
+		return err
+	}
+	if cmp, _ := total.Cmp(r.Limit); cmp > 0 {
+		remaining, _ := r.Limit.Sub(used)
+		return &RejectionError{
+			Rule:   "daily-limit",
+			Code:   "daily_limit_exceeded",
+			Reason: fmt.Sprintf("%s exceeds the remaining daily limit of %s", req.Amount, remaining),
+		}
+	}
+	r.used[key] = total
+	return nil
+}
+
+func (r *DailyLimit) Record(req TransferRequest, committed bool) {
+	if committed {
+		return
+	}
+	amount, err := limitAmount("daily-limit", req, r.Limit)
+	if err != nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := r.key(req)
+	if used, exists := r.used[key]; exists {
+		if remaining, err := used.Sub(amount); err == nil && remaining.IsPositive() {
+			r.used[key] = remaining
+		} else {
+			delete(r.used, key)
+		}
+	}
+}
+
+// VelocityLimit refuses a transfer when Max transfers from the same account
+// already passed the check within the Window before it. Like DailyLimit it
+// counts transfers from the moment they pass, and forgets those that end up
+// not committed.
+type VelocityLimit struct {
+	Max    int
+	Window time.Duration
+
+	mu   sync.Mutex
+	seen map[AccountID][]time.Time
+}
+
+// NewVelocityLimit creates a limit of max transfers per account in any
+// window of the given length.
+func NewVelocityLimit(max int, window time.Duration) *VelocityLimit {
+	return &VelocityLimit{Max: max, Window: window, seen: make(map[AccountID][]time.Time)}
+}
+
+// recent returns the times in seen that fall within the window ending at t.
+func (r *VelocityLimit) recent(seen []time.Time, t time.Time) []time.Time {
+	start := t.Add(-r.Window)
+	kept := seen[:0]
+	for _, at := range seen {
+		if at.After(start) {
+			kept = append(kept, at)
+		}
+	}
+	return kept
+}
+
+// Count returns the number of transfers counted against account in the
+// window ending at t.
+func (r *VelocityLimit) Count(account AccountID, t time.Time) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := 0
+	for _, at := range r.seen[account] {
+		if at.After(t.Add(-r.Window)) && !at.After(t) {
+			n++
+		}
+	}
+	return n
+}
+
+func (r *VelocityLimit) Check(ctx context.Context, req TransferRequest) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.seen == nil {
+		r.seen = make(map[AccountID][]time.Time)
+	}
+	account := AccountID{Bank: req.FromBank, AccountNo: req.FromAccount}
+	seen := r.recent(r.seen[account], req.Time)
+	if len(seen) >= r.Max {
+		r.seen[account] = seen
+		return &RejectionError{
+			Rule:   "velocity",
+			Code:   "velocity_exceeded",
+			Reason: fmt.Sprintf("%s/%s already made %d transfers in the last %s", req.FromBank, req.FromAccount, len(seen), r.Window),
+		}
+	}
+	r.seen[account] = append(seen, req.Time)
+	return nil
+}
+
+func (r *VelocityLimit) Record(req TransferRequest, committed bool) {
+	if committed {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	account := AccountID{Bank: req.FromBank, AccountNo: req.FromAccount}
+	seen := r.seen[account]
+	for i, at := range seen {
+		if at.Equal(req.Time) {
+			r.seen[account] = append(seen[:i], seen[i+1:]...)
+			break
+		}
+	}
+	if len(r.seen[account]) == 0 {
+		delete(r.seen, account)
+	}
+}
+
+// WithRules checks every transfer against rules, in order, before any bank
+// is called. Repeated use appends to the chain.
+func WithRules(rules ...TransferRule) Option {
+	return func(f *BankTransferFacade) error {
+		for _, rule := range rules {
+			if rule == nil {
+				return errors.New("transfer rule must not be nil")
+			}
+			f.rules.Then(rule)
+		}
+		return nil
+	}
+}
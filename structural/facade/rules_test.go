@@ -0,0 +1,186 @@
+package facade_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go-design-patterns/structural/facade"
+)
+
+func money(t *testing.T, amount, currency string) facade.Money {
+	t.Helper()
+	m, err := facade.ParseMoney(amount, currency)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestLimitsConvertOtherCurrencies(t *testing.T) {
+	rate, err := facade.ParseRate("USD", "THB", "35.00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rates := facade.NewStaticRates(rate)
+	limit := money(t, "10000.00", "THB")
+
+	tests := []struct {
+		name     string
+		rule     facade.TransferRule
+		amount   facade.Money
+		rates    facade.RateProvider
+		wantCode string
+	}{
+		{"same currency under limit", facade.MaxAmount{Limit: limit}, money(t, "9000.00", "THB"), nil, ""},
+		{"same currency over limit", facade.MaxAmount{Limit: limit}, money(t, "10000.01", "THB"), nil, "amount_over_limit"},
+		{"converted under limit", facade.MaxAmount{Limit: limit}, money(t, "200.00", "USD"), rates, ""},
+		{"converted over limit", facade.MaxAmount{Limit: limit}, money(t, "300.00", "USD"), rates, "amount_over_limit"},
+		{"no rates", facade.MaxAmount{Limit: limit}, money(t, "1.00", "USD"), nil, "currency_not_convertible"},
+		{"no rate for currency", facade.MaxAmount{Limit: limit}, money(t, "1.00", "EUR"), rates, "currency_not_convertible"},
+		{"daily converted over limit", facade.NewDailyLimit(limit, nil), money(t, "300.00", "USD"), rates, "daily_limit_exceeded"},
+		{"daily no rates", facade.NewDailyLimit(limit, nil), money(t, "1.00", "USD"), nil, "currency_not_convertible"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := facade.TransferRequest{
+				FromBank: "KBank", FromAccount: "0123456789", ToBank: "SiamBank", ToAccount: "0123456789",
+				Amount: tt.amount, Time: time.Now(), Rates: tt.rates,
+			}
+			err := tt.rule.Check(context.Background(), req)
+			var rejection *facade.RejectionError
+			switch {
+			case tt.wantCode == "" && err != nil:
+				t.Fatalf("Check: %v", err)
+			case tt.wantCode != "" && !errors.As(err, &rejection):
+				t.Fatalf("Check: %v, want a rejection %s", err, tt.wantCode)
+			case tt.wantCode != "" && rejection.Code != tt.wantCode:
+				t.Fatalf("rejection code %s, want %s", rejection.Code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestDailyLimitCountsConvertedAmounts(t *testing.T) {
+	rate, _ := facade.ParseRate("USD", "THB", "35.00")
+	limit := facade.NewDailyLimit(money(t, "10000.00", "THB"), nil)
+	req := facade.TransferRequest{
+		FromBank: "KBank", FromAccount: "0123456789",
+		Amount: money(t, "200.00", "USD"), Time: time.Now(), Rates: facade.NewStaticRates(rate),
+	}
+	account := facade.AccountID{Bank: req.FromBank, AccountNo: req.FromAccount}
+
+	if err := limit.Check(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	if used := limit.Used(account, req.Time); used.String() != money(t, "7000.00", "THB").String() {
+		t.Fatalf("used %s after 200.00 USD, want 7000.00 THB", used)
+	}
+	if err := limit.Check(context.Background(), req); !errors.Is(err, facade.ErrTransferRejected) {
+		t.Fatalf("second 200.00 USD: %v, want rejection", err)
+	}
+	limit.Record(req, false)
+	if used := limit.Used(account, req.Time); !used.IsZero() {
+		t.Fatalf("used %s after the transfer was not committed, want 0", used)
+	}
+}
+
+func TestZeroValueRules(t *testing.T) {
+	var denylist facade.Denylist
+	blocked := facade.AccountID{Bank: "KBank", AccountNo: "9876543210"}
+	denylist.Block(blocked, "fraud")
+	req := facade.TransferRequest{ToBank: blocked.Bank, ToAccount: blocked.AccountNo}
+	if err := denylist.Check(context.Background(), req); !errors.Is(err, facade.ErrTransferRejected) {
+		t.Fatalf("zero-value Denylist: %v, want rejection", err)
+	}
+
+	daily := facade.DailyLimit{Limit: money(t, "100.00", "THB"), Location: time.UTC}
+	req.Amount, req.Time = money(t, "50.00", "THB"), time.Now()
+	if err := daily.Check(context.Background(), req); err != nil {
+		t.Fatalf("zero-value DailyLimit: %v", err)
+	}
+
+	velocity := facade.VelocityLimit{Max: 1, Window: time.Hour}
+	if err := velocity.Check(context.Background(), req); err != nil {
+		t.Fatalf("zero-value VelocityLimit: %v", err)
+	}
+}
+
+func TestVelocityLimit(t *testing.T) {
+	start := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		offsets  []time.Duration
+		from     []string
+		wantCode []string
+	}{
+		{
+			name:     "transfers within the window count",
+			offsets:  []time.Duration{0, time.Minute, 2 * time.Minute},
+			wantCode: []string{"", "", "velocity_exceeded"},
+		},
+		{
+			name:     "transfers older than the window drop out",
+			offsets:  []time.Duration{0, time.Minute, 10 * time.Minute, 11 * time.Minute},
+			wantCode: []string{"", "", "", ""},
+		},
+		{
+			name:     "accounts are counted apart",
+			offsets:  []time.Duration{0, time.Minute, 2 * time.Minute},
+			from:     []string{"0123456789", "0123456789", "9876543210"},
+			wantCode: []string{"", "", ""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limit := facade.NewVelocityLimit(2, 10*time.Minute)
+			for i, offset := range tt.offsets {
+				req := facade.TransferRequest{
+					FromBank: "KBank", FromAccount: "0123456789",
+					Amount: money(t, "1.00", "THB"), Time: start.Add(offset),
+				}
+				if tt.from != nil {
+					req.FromAccount = tt.from[i]
+				}
+				err := limit.Check(context.Background(), req)
+				var rejection *facade.RejectionError
+				switch {
+				case tt.wantCode[i] == "" && err != nil:
+					t.Fatalf("transfer %d: %v", i, err)
+				case tt.wantCode[i] != "" && (!errors.As(err, &rejection) || rejection.Code != tt.wantCode[i]):
+					t.Fatalf("transfer %d: %v, want rejection %s", i, err, tt.wantCode[i])
+				}
+			}
+		})
+	}
+}
+
+func TestVelocityLimitForgetsUncommitted(t *testing.T) {
+	limit := facade.NewVelocityLimit(1, time.Hour)
+	req := facade.TransferRequest{FromBank: "KBank", FromAccount: "0123456789", Amount: money(t, "1.00", "THB"), Time: time.Now()}
+	account := facade.AccountID{Bank: req.FromBank, AccountNo: req.FromAccount}
+
+	if err := limit.Check(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	if err := limit.Check(context.Background(), req); !errors.Is(err, facade.ErrTransferRejected) {
+		t.Fatalf("second transfer: %v, want rejection", err)
+	}
+	limit.Record(req, false)
+	if n := limit.Count(account, req.Time); n != 0 {
+		t.Fatalf("%d transfers counted after the transfer was not committed, want 0", n)
+	}
+	if err := limit.Check(context.Background(), req); err != nil {
+		t.Fatalf("transfer after the uncommitted one: %v", err)
+	}
+
+	// A rule further down the chain refusing the transfer uncounts it too
+	chain := facade.NewRuleChain(facade.NewVelocityLimit(1, time.Hour), facade.MaxAmount{Limit: money(t, "0.50", "THB")})
+	for i := 0; i < 2; i++ {
+		var rejection *facade.RejectionError
+		if err := chain.Check(context.Background(), req); !errors.As(err, &rejection) || rejection.Code != "amount_over_limit" {
+			t.Fatalf("chain check %d: %v, want amount_over_limit", i, err)
+		}
+	}
+}
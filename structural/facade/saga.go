@@ -0,0 +1,368 @@
+package facade
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrTransferNotFound is returned when a transfer ID is not in the store.
+	ErrTransferNotFound = errors.New("transfer not found")
+	// ErrInvalidTransition is returned for a state change the state machine forbids.
+	ErrInvalidTransition = errors.New("invalid transfer state transition")
+)
+
+// TransferState is a state of the transfer saga.
+type TransferState string
+
+const (
+	// StatePending means the transfer was accepted but nothing has moved yet.
+	StatePending TransferState = "pending"
+	// StateReserved means the funds are held on the source bank.
+	StateReserved TransferState = "reserved"
+	// StateCommitted means the destination was credited and the hold committed.
+	StateCommitted TransferState = "committed"
+	// StateCompensated means a later step failed and every earlier step was undone.
+	StateCompensated TransferState = "compensated"
+	// StateFailed means the transfer stopped; any compensation that was
+	// needed could not be completed and the transfer needs manual attention.
+	StateFailed TransferState = "failed"
+)
+
+// transitions lists the states each state may move to.
+var transitions = map[TransferState][]TransferState{
+	StatePending:  {StateReserved, StateFailed},
+	StateReserved: {StateCommitted, StateCompensated, StateFailed},
+}
+
+// CanTransition reports whether the saga may move from s to next.
+func (s TransferState) CanTransition(next TransferState) bool {
+	for _, allowed := range transitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// IsFinal reports whether no further transitions are possible.
+func (s TransferState) IsFinal() bool {
+	return len(transitions[s]) == 0
+}
+
+// StateChange records when a transfer entered a state.
+type StateChange struct {
+	State TransferState
+	At    time.Time
+}
+
+// TransferRecord is the persisted state of one transfer saga.
+type TransferRecord struct {
+	ID          string
+	FromBank    string
+	FromAccount string
+	ToBank      string
+	ToAccount   string
+	Amount      Money
+	Fee         Money
+	Credit      Money
+	Rate        string
+	State       TransferState
+	Reference   string
+	Credited    bool
+	FailedStep  string
+	Error       string
+	History     []StateChange
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// TransferStore persists transfer records.
+type TransferStore interface {
+	Save(record TransferRecord) error
+	Get(id string) (TransferRecord, error)
+	List() ([]TransferRecord, error)
+}
+
+// MemoryTransferStore keeps transfer records in memory.
+type MemoryTransferStore struct {
+	mu      sync.RWMutex
+	records map[string]TransferRecord
+}
+
+// NewMemoryTransferStore creates an empty in-memory transfer store.
+func NewMemoryTransferStore() *MemoryTransferStore {
+	return &MemoryTransferStore{records: make(map[string]TransferRecord)}
+}
+
+func (s *MemoryTransferStore) Save(record TransferRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record.History = append([]StateChange(nil), record.History...)
+	s.records[record.ID] = record
+	return nil
+}
+
+func (s *MemoryTransferStore) Get(id string) (TransferRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	record, exists := s.records[id]
+	if !exists {
+		return TransferRecord{}, fmt.Errorf("%w: %s", ErrTransferNotFound, id)
+	}
+	return record, nil
+}
+
+// List returns all records ordered by creation time.
+func (s *MemoryTransferStore) List() ([]TransferRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	list := make([]TransferRecord, 0, len(s.records))
+	for _, record := range s.records {
+		list = append(list, record)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].CreatedAt.Equal(list[j].CreatedAt) {
+			return list[i].CreatedAt.Before(list[j].CreatedAt)
+		}
+		return list[i].ID < list[j].ID
+	})
+	return list, nil
+}
+
+// TransferError reports the step at which a transfer saga stopped, the bank
+// that step called and the state the transfer was left in. Steps that fail
+// outside a transfer, such as the login of Balance, have no TransferID or
+// State.
+type TransferError struct {
+	TransferID string
+	Step       string
+	Bank       string
+	State      TransferState
+	Err        error
+}
+
+func (e *TransferError) Error() string {
+	return fmt.Sprintf("%s failed: %v", e.Step, e.Err)
+}
+
+func (e *TransferError) Unwrap() error {
+	return e.Err
+}
+
+// Temporary reports whether the whole transfer may be retried: no money
+// moved and the cause may pass on its own.
+func (e *TransferError) Temporary() bool {
+	if e.State == "" {
+		return retryable(e.Err)
+	}
+	return e.undone() && retryable(e.Err)
+}
+
+// undone reports whether the transfer ended without moving money: it was
+// compensated, or failed before the hold was placed.
+func (e *TransferError) undone() bool {
+	return e.State == StateCompensated || (e.State == StateFailed && (e.Step == "login" || e.Step == "transfer"))
+}
+
+// newTransferError attributes err to a step of the transfer from fromBank
+// to toBank.
+func newTransferError(transferID, step, fromBank, toBank string, state TransferState, err error) *TransferError {
+	return &TransferError{
+		TransferID: transferID,
+		Step:       step,
+		Bank:       stepBank(step, fromBank, toBank),
+		State:      state,
+		Err:        err,
+	}
+}
+
+// stepBank returns the bank called by a saga step.
+func stepBank(step, fromBank, toBank string) string {
+	switch step {
+	case "credit":
+		return toBank
+	case "history":
+		return ""
+	default:
+		return fromBank
+	}
+}
+
+// WithTransferStore persists transfer sagas in store instead of memory.
+func WithTransferStore(store TransferStore) Option {
+	return func(f *BankTransferFacade) error {
+		if store == nil {
+			return errors.New("transfer store must not be nil")
+		}
+		f.transfers = store
+		return nil
+	}
+}
+
+// TransferStatus returns the persisted record of a transfer.
+func (f *BankTransferFacade) TransferStatus(id string) (TransferRecord, error) {
+	return f.transfers.Get(id)
+}
+
+// saga drives a single transfer through its states and persists every step.
+type saga struct {
+	facade *BankTransferFacade
+	record TransferRecord
+}
+
+func (f *BankTransferFacade) newSaga(accountNo, fromBank, toAcc, toBank string, amount Money, q quote) (*saga, error) {
+	id, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+	now := f.now()
+	s := &saga{
+		facade: f,
+		record: TransferRecord{
+			ID:          "tx-" + id[:16],
+			FromBank:    fromBank,
+			FromAccount: accountNo,
+			ToBank:      toBank,
+			ToAccount:   toAcc,
+			Amount:      amount,
+			Fee:         q.fee,
+			Credit:      q.credit,
+			Rate:        q.rateText(),
+			State:       StatePending,
+			History:     []StateChange{{State: StatePending, At: now}},
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		},
+	}
+	if err := f.transfers.Save(s.record); err != nil {
+		return nil, fmt.Errorf("save transfer: %w", err)
+	}
+	return s, nil
+}
+
+// advance moves the saga to next and persists the record.
+func (s *saga) advance(next TransferState) error {
+	if !s.record.State.CanTransition(next) {
+		return fmt.Errorf("%w: %s to %s", ErrInvalidTransition, s.record.State, next)
+	}
+	now := s.facade.now()
+	s.record.State = next
+	s.record.UpdatedAt = now
+	s.record.History = append(s.record.History, StateChange{State: next, At: now})
+	return s.facade.transfers.Save(s.record)
+}
+
+// save persists the record without changing state.
+func (s *saga) save() error {
+	s.record.UpdatedAt = s.facade.now()
+	return s.facade.transfers.Save(s.record)
+}
+
+// fail records the failed step and moves the saga to a final state.
+func (s *saga) fail(step string, cause error, final TransferState) error {
+	s.record.FailedStep = step
+	s.record.Error = cause.Error()
+	if err := s.advance(final); err != nil {
+		cause = errors.Join(cause, fmt.Errorf("save transfer: %w", err))
+	}
+	event := transferEvent(EventTransferFailed, s.record)
+	event.Step = step
+	event.Error = cause.Error()
+	s.facade.emit(event)
+	return newTransferError(s.record.ID, step, s.record.FromBank, s.record.ToBank, s.record.State, cause)
+}
+
+// compensate undoes the credit (if any) and the hold, in reverse order.
+//
+// Compensation runs on a context detached from the caller's, so a cancelled
+// or expired request still undoes its own side effects within the banks'
+// timeouts and ends in a final state.
+func (s *saga) compensate(ctx context.Context, step string, cause error, source BankApi, confToken string, receiver Receiver) error {
+	ctx = context.WithoutCancel(ctx)
+
+	var undo []error
+	if s.record.Credited {
+		reverseCtx, cancel := s.facade.bankContext(ctx, s.record.ToBank)
+		err := receiver.Reverse(reverseCtx, s.record.ID)
+		cancel()
+		if err != nil {
+			undo = append(undo, fmt.Errorf("reverse credit: %w", err))
+		} else {
+			s.record.Credited = false
+			if err := s.save(); err != nil {
+				undo = append(undo, fmt.Errorf("save transfer: %w", err))
+			}
+		}
+	}
+	cancelCtx, cancel := s.facade.bankContext(ctx, s.record.FromBank)
+	defer cancel()
+	if err := source.Cancel(cancelCtx, confToken); err != nil {
+		undo = append(undo, fmt.Errorf("cancel hold: %w", err))
+	}
+
+	if len(undo) > 0 {
+		return s.fail(step, errors.Join(append([]error{cause}, undo...)...), StateFailed)
+	}
+	return s.fail(step, cause, StateCompensated)
+}
+
+// run executes reserve, credit and commit, compensating on failure. Each
+// bank call gets its own timeout; once funds are held, cancellation of ctx
+// between or during steps triggers compensation.
+func (s *saga) run(ctx context.Context, source BankApi, receiver Receiver) error {
+	if !source.IsLoggedIn() {
+		if err := s.facade.login(ctx, source, s.record.FromBank, s.record.FromAccount); err != nil {
+			return s.fail("login", err, StateFailed)
+		}
+	}
+
+	transferCtx, cancel := s.facade.bankContext(ctx, s.record.FromBank)
+	defer cancel()
+	total, err := s.record.Amount.Add(s.record.Fee)
+	if err != nil {
+		return s.fail("transfer", err, StateFailed)
+	}
+	confToken, err := source.Transfer(transferCtx, s.record.ToAccount, s.record.ToBank, total)
+	if err != nil {
+		return s.fail("transfer", err, StateFailed)
+	}
+	s.record.Reference = receiptReference(s.record.FromBank, confToken)
+	if err := s.advance(StateReserved); err != nil {
+		return s.compensate(ctx, "transfer", err, source, confToken, receiver)
+	}
+	s.facade.emit(transferEvent(EventTransferInitiated, s.record))
+
+	creditCtx, cancel := s.facade.bankContext(ctx, s.record.ToBank)
+	defer cancel()
+	if err := receiver.Credit(creditCtx, s.record.ID, s.record.Credit); err != nil {
+		return s.compensate(ctx, "credit", err, source, confToken, receiver)
+	}
+	s.record.Credited = true
+	if err := s.save(); err != nil {
+		return s.compensate(ctx, "credit", err, source, confToken, receiver)
+	}
+
+	confirmCtx, cancel := s.facade.bankContext(ctx, s.record.FromBank)
+	defer cancel()
+	if err := source.Confirm(confirmCtx, confToken); err != nil {
+		return s.compensate(ctx, "confirmation", err, source, confToken, receiver)
+	}
+	err = s.advance(StateCommitted)
+	s.facade.emit(transferEvent(EventTransferConfirmed, s.record))
+	if err != nil {
+		// The money has moved; report the bookkeeping failure without undoing it
+		return newTransferError(s.record.ID, "confirmation", s.record.FromBank, s.record.ToBank, StateCommitted, err)
+	}
+	if err := s.facade.bookFee(ctx, source, s.record); err != nil {
+		return newTransferError(s.record.ID, "fee", s.record.FromBank, s.record.ToBank, StateCommitted, err)
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+package facade_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"go-design-patterns/structural/facade"
+	"go-design-patterns/structural/facade/facadetest"
+)
+
+func TestTransferStateTransitions(t *testing.T) {
+	tests := []struct {
+		from, to facade.TransferState
+		want     bool
+	}{
+		{facade.StatePending, facade.StateReserved, true},
+		{facade.StatePending, facade.StateFailed, true},
+		{facade.StatePending, facade.StateCommitted, false},
+		{facade.StatePending, facade.StateCompensated, false},
+		{facade.StateReserved, facade.StateCommitted, true},
+		{facade.StateReserved, facade.StateCompensated, true},
+		{facade.StateReserved, facade.StateFailed, true},
+		{facade.StateCommitted, facade.StateCompensated, false},
+		{facade.StateCompensated, facade.StateReserved, false},
+		{facade.StateFailed, facade.StatePending, false},
+	}
+	for _, tt := range tests {
+		if got := tt.from.CanTransition(tt.to); got != tt.want {
+			t.Errorf("%s to %s allowed = %t, want %t", tt.from, tt.to, got, tt.want)
+		}
+	}
+	for _, state := range []facade.TransferState{facade.StateCommitted, facade.StateCompensated, facade.StateFailed} {
+		if !state.IsFinal() {
+			t.Errorf("%s is not final", state)
+		}
+	}
+	if facade.StateReserved.IsFinal() {
+		t.Error("reserved is final")
+	}
+}
+
+// sagaOutcome is what a transfer left behind in the harness.
+type sagaOutcome struct {
+	err    *facade.TransferError
+	record facade.TransferRecord
+	source string
+	dest   string
+}
+
+// runSaga transfers 10.00 from AlphaMain to BetaMain with faults injected
+// into Alpha and Beta.
+func runSaga(t *testing.T, alpha, beta []facadetest.Fault) sagaOutcome {
+	t.Helper()
+	h, err := facadetest.NewHarness(facadetest.StandardBanks())
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Bank("Alpha").Inject(alpha...)
+	h.Bank("Beta").Inject(beta...)
+
+	from, to := facadetest.AlphaMain, facadetest.BetaMain
+	amount, _ := facade.ParseMoney("10.00", "THB")
+	_, err = h.Facade.Transfer(context.Background(), from.AccountNo, from.Pin, from.Bank, to.AccountNo, to.Bank, amount)
+	var out sagaOutcome
+	if !errors.As(err, &out.err) {
+		t.Fatalf("transfer = %v, want a *TransferError", err)
+	}
+	if out.record, err = h.Facade.TransferStatus(out.err.TransferID); err != nil {
+		t.Fatal(err)
+	}
+	source, _ := h.Balance(from.Bank, from.AccountNo)
+	dest, _ := h.Balance(to.Bank, to.AccountNo)
+	out.source, out.dest = source.Decimal(), dest.Decimal()
+	return out
+}
+
+func states(record facade.TransferRecord) []facade.TransferState {
+	var list []facade.TransferState
+	for _, change := range record.History {
+		list = append(list, change.State)
+	}
+	return list
+}
+
+func TestSagaCompensation(t *testing.T) {
+	tests := []struct {
+		name         string
+		alpha, beta  []facadetest.Fault
+		wantStep     string
+		wantBank     string
+		wantState    facade.TransferState
+		wantCredited bool
+		wantSource   string
+		wantDest     string
+		wantRetry    bool
+	}{
+		{
+			name:       "hold refused needs no compensation",
+			alpha:      []facadetest.Fault{{Op: facade.OpTransfer}},
+			wantStep:   "transfer",
+			wantBank:   "Alpha",
+			wantState:  facade.StateFailed,
+			wantSource: "1000.00",
+			wantDest:   "1000.00",
+		},
+		{
+			name:       "credit refused releases the hold",
+			beta:       []facadetest.Fault{{Op: facade.OpCredit, Retryable: true}},
+			wantStep:   "credit",
+			wantBank:   "Beta",
+			wantState:  facade.StateCompensated,
+			wantSource: "1000.00",
+			wantDest:   "1000.00",
+			wantRetry:  true,
+		},
+		{
+			name:       "confirmation refused reverses the credit and releases the hold",
+			alpha:      []facadetest.Fault{{Op: facade.OpConfirm}},
+			wantStep:   "confirmation",
+			wantBank:   "Alpha",
+			wantState:  facade.StateCompensated,
+			wantSource: "1000.00",
+			wantDest:   "1000.00",
+		},
+		{
+			name:       "hold cannot be released",
+			alpha:      []facadetest.Fault{{Op: facade.OpCancel}},
+			beta:       []facadetest.Fault{{Op: facade.OpCredit, Retryable: true}},
+			wantStep:   "credit",
+			wantBank:   "Beta",
+			wantState:  facade.StateFailed,
+			wantSource: "990.00",
+			wantDest:   "1000.00",
+		},
+		{
+			name:         "credit cannot be reversed",
+			alpha:        []facadetest.Fault{{Op: facade.OpConfirm}},
+			beta:         []facadetest.Fault{{Op: facade.OpReverse}},
+			wantStep:     "confirmation",
+			wantBank:     "Alpha",
+			wantState:    facade.StateFailed,
+			wantCredited: true,
+			wantSource:   "1000.00",
+			wantDest:     "1010.00",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := runSaga(t, tt.alpha, tt.beta)
+			if out.err.Step != tt.wantStep || out.err.Bank != tt.wantBank || out.err.State != tt.wantState {
+				t.Errorf("error at %s of %s in state %s, want %s of %s in %s",
+					out.err.Step, out.err.Bank, out.err.State, tt.wantStep, tt.wantBank, tt.wantState)
+			}
+			if !errors.Is(out.err, facadetest.ErrInjected) {
+				t.Errorf("error %v does not wrap the injected fault", out.err)
+			}
+			if got := out.err.Temporary(); got != tt.wantRetry {
+				t.Errorf("Temporary() = %t, want %t", got, tt.wantRetry)
+			}
+
+			record := out.record
+			if record.State != tt.wantState || record.FailedStep != tt.wantStep || record.Error == "" {
+				t.Errorf("record in %s failed at %q with %q", record.State, record.FailedStep, record.Error)
+			}
+			if record.Credited != tt.wantCredited {
+				t.Errorf("record credited = %t, want %t", record.Credited, tt.wantCredited)
+			}
+			want := []facade.TransferState{facade.StatePending, facade.StateReserved, tt.wantState}
+			if tt.wantStep == "transfer" {
+				want = []facade.TransferState{facade.StatePending, tt.wantState}
+			}
+			if got := states(record); !reflect.DeepEqual(got, want) {
+				t.Errorf("state history %v, want %v", got, want)
+			}
+
+			// The hold stays on the source while the bank refuses to release it,
+			// and a credit that cannot be reversed stays with the destination
+			if out.source != tt.wantSource || out.dest != tt.wantDest {
+				t.Errorf("balances %s and %s, want %s and %s", out.source, out.dest, tt.wantSource, tt.wantDest)
+			}
+		})
+	}
+}
+
+func TestSagaCompensationCalls(t *testing.T) {
+	h, err := facadetest.NewHarness(facadetest.StandardBanks())
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Bank("Alpha").Inject(facadetest.Fault{Op: facade.OpConfirm})
+
+	from, to := facadetest.AlphaMain, facadetest.BetaMain
+	amount, _ := facade.ParseMoney("10.00", "THB")
+	if _, err := h.Facade.Transfer(context.Background(), from.AccountNo, from.Pin, from.Bank, to.AccountNo, to.Bank, amount); err == nil {
+		t.Fatal("transfer with a failing confirmation succeeded")
+	}
+	calls := map[string]int{
+		"Alpha transfer": h.Bank("Alpha").Calls(facade.OpTransfer),
+		"Alpha confirm":  h.Bank("Alpha").Calls(facade.OpConfirm),
+		"Alpha cancel":   h.Bank("Alpha").Calls(facade.OpCancel),
+		"Beta credit":    h.Bank("Beta").Calls(facade.OpCredit),
+		"Beta reverse":   h.Bank("Beta").Calls(facade.OpReverse),
+	}
+	for call, n := range calls {
+		if n != 1 {
+			t.Errorf("%s called %d times, want once", call, n)
+		}
+	}
+}
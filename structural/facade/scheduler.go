@@ -0,0 +1,430 @@
+package facade
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrInstructionNotFound is returned for an unknown standing order ID.
+	ErrInstructionNotFound = errors.New("transfer instruction not found")
+	// ErrInvalidSchedule is returned when an instruction cannot be scheduled.
+	ErrInvalidSchedule = errors.New("invalid transfer schedule")
+)
+
+// Frequency says how often a scheduled transfer repeats.
+type Frequency string
+
+const (
+	Once    Frequency = "once"
+	Daily   Frequency = "daily"
+	Weekly  Frequency = "weekly"
+	Monthly Frequency = "monthly"
+)
+
+// Instruction is a future-dated or recurring transfer. Monthly transfers
+// keep the day of the month of Start, moving to the last day of shorter
+// months: a standing order starting on 31 January runs on 28 or 29
+// February and on 31 March.
+type Instruction struct {
+	ID          string
+	FromBank    string
+	FromAccount string
+	// Pin is kept in memory only so the scheduler can log in when the
+	// transfer is due.
+	Pin       string `json:"-"`
+	ToBank    string
+	ToAccount string
+	Amount    Money
+	Frequency Frequency
+	// Start is the first run; later runs keep its time of day and location.
+	Start time.Time
+	// End, if set, is the time after which no run happens.
+	End time.Time
+
+	// NextRun is the time of the next run; zero once the instruction is done.
+	NextRun time.Time
+	// Runs counts the occurrences that have come due, including skipped ones.
+	Runs      int
+	Cancelled bool
+}
+
+// Active reports whether the instruction has runs left.
+func (in Instruction) Active() bool {
+	return !in.Cancelled && !in.NextRun.IsZero()
+}
+
+// occurrence returns the time of the n-th run, counting from zero.
+func (in Instruction) occurrence(n int) time.Time {
+	switch in.Frequency {
+	case Daily:
+		return in.Start.AddDate(0, 0, n)
+	case Weekly:
+		return in.Start.AddDate(0, 0, 7*n)
+	case Monthly:
+		return addMonthsClamped(in.Start, n)
+	default:
+		return in.Start
+	}
+}
+
+// following returns the time of the run after the n-th, or zero if there is none.
+func (in Instruction) following(n int) time.Time {
+	if in.Frequency == Once {
+		return time.Time{}
+	}
+	next := in.occurrence(n + 1)
+	if !in.End.IsZero() && next.After(in.End) {
+		return time.Time{}
+	}
+	return next
+}
+
+// addMonthsClamped adds months to t, keeping its day of the month unless the
+// target month is shorter, in which case its last day is used.
+func addMonthsClamped(t time.Time, months int) time.Time {
+	year, month, day := t.Date()
+	first := time.Date(year, month+time.Month(months), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	lastDay := first.AddDate(0, 1, -1).Day()
+	return first.AddDate(0, 0, min(day, lastDay)-1)
+}
+
+// ScheduledRun is the outcome of one attempt at a due occurrence of an
+// instruction.
+type ScheduledRun struct {
+	InstructionID string
+	Due           time.Time
+	RanAt         time.Time
+	// Skipped is set for occurrences missed while the scheduler was not
+	// running that were not executed, see MissedRuns.
+	Skipped bool
+	// Attempt counts the attempts at this occurrence, starting at 1.
+	Attempt int
+	// RetryAt is when a failed attempt will be made again; zero if it will not.
+	RetryAt time.Time
+	Receipt Receipt
+	Error   string
+}
+
+// MissedRuns says what the scheduler does with the occurrences of an
+// instruction that came due while it was not running.
+type MissedRuns int
+
+const (
+	// SkipMissedRuns executes only the latest missed occurrence and records
+	// the earlier ones as skipped runs.
+	SkipMissedRuns MissedRuns = iota
+	// RunMissedRuns executes every missed occurrence, oldest first.
+	RunMissedRuns
+)
+
+// defaultScheduleRetry retries a transient failure twice, one and then two
+// minutes later.
+var defaultScheduleRetry = RetryPolicy{MaxAttempts: 3, BaseDelay: time.Minute, MaxDelay: 15 * time.Minute}
+
+// SchedulerOption configures a Scheduler.
+type SchedulerOption func(*Scheduler)
+
+// WithSchedulerClock makes the scheduler read the time from now.
+func WithSchedulerClock(now func() time.Time) SchedulerOption {
+	return func(s *Scheduler) {
+		s.now = now
+	}
+}
+
+// WithMissedRuns sets what happens to occurrences missed while the
+// scheduler was not running. The default is SkipMissedRuns.
+func WithMissedRuns(policy MissedRuns) SchedulerOption {
+	return func(s *Scheduler) {
+		s.missed = policy
+	}
+}
+
+// WithScheduleRetry sets how occurrences that fail with a retryable error
+// are attempted again. Retries are made by later calls of RunDue once their
+// delay has passed, under the idempotency key of the occurrence, so a
+// transfer that did go through is not repeated. SafeOperations is ignored.
+// A MaxAttempts of 1 or less turns retries off; by default a transient
+// failure is attempted twice more, one and two minutes later.
+func WithScheduleRetry(policy RetryPolicy) SchedulerOption {
+	return func(s *Scheduler) {
+		s.retry = policy
+	}
+}
+
+// Scheduler keeps transfer instructions and executes them through the
+// facade when they come due.
+type Scheduler struct {
+	facade *BankTransferFacade
+	now    func() time.Time
+	missed MissedRuns
+	retry  RetryPolicy
+
+	mu           sync.Mutex
+	instructions map[string]*Instruction
+	runs         map[string][]ScheduledRun
+	retries      map[string][]dueRun
+}
+
+// NewScheduler creates a scheduler executing transfers with facade.
+func NewScheduler(facade *BankTransferFacade, opts ...SchedulerOption) *Scheduler {
+	s := &Scheduler{
+		facade:       facade,
+		now:          time.Now,
+		retry:        defaultScheduleRetry,
+		instructions: make(map[string]*Instruction),
+		runs:         make(map[string][]ScheduledRun),
+		retries:      make(map[string][]dueRun),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Schedule validates and stores an instruction and returns it with its ID
+// and first run filled in.
+func (s *Scheduler) Schedule(in Instruction) (Instruction, error) {
+	if err := in.Amount.Validate(); err != nil {
+		return Instruction{}, fmt.Errorf("%w: %w", ErrInvalidSchedule, err)
+	}
+	if !in.Amount.IsPositive() {
+		return Instruction{}, fmt.Errorf("%w: %w: %s", ErrInvalidSchedule, ErrNonPositiveAmount, in.Amount)
+	}
+	if in.Frequency == "" {
+		in.Frequency = Once
+	}
+	switch in.Frequency {
+	case Once, Daily, Weekly, Monthly:
+	default:
+		return Instruction{}, fmt.Errorf("%w: unknown frequency %q", ErrInvalidSchedule, in.Frequency)
+	}
+	if in.Start.IsZero() {
+		return Instruction{}, fmt.Errorf("%w: start time is required", ErrInvalidSchedule)
+	}
+	if !in.End.IsZero() && in.End.Before(in.Start) {
+		return Instruction{}, fmt.Errorf("%w: ends before it starts", ErrInvalidSchedule)
+	}
+	if _, err := s.facade.bankFactory(in.FromBank); err != nil {
+		return Instruction{}, err
+	}
+	if _, err := s.facade.bankFactory(in.ToBank); err != nil {
+		return Instruction{}, err
+	}
+	toAcc, err := s.facade.ValidateAccount(in.ToBank, in.ToAccount)
+	if err != nil {
+		return Instruction{}, err
+	}
+	in.ToAccount = toAcc
+
+	token, err := newToken()
+	if err != nil {
+		return Instruction{}, err
+	}
+	in.ID = "so-" + token[:16]
+	in.NextRun = in.Start
+	in.Runs = 0
+	in.Cancelled = false
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := in
+	s.instructions[in.ID] = &stored
+	return in, nil
+}
+
+// Cancel stops an instruction from running again, including retries of
+// failed occurrences.
+func (s *Scheduler) Cancel(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	in, exists := s.instructions[id]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrInstructionNotFound, id)
+	}
+	in.Cancelled = true
+	delete(s.retries, id)
+	return nil
+}
+
+// Instruction returns the instruction with the given ID.
+func (s *Scheduler) Instruction(id string) (Instruction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	in, exists := s.instructions[id]
+	if !exists {
+		return Instruction{}, fmt.Errorf("%w: %s", ErrInstructionNotFound, id)
+	}
+	return *in, nil
+}
+
+// Instructions returns all instructions ordered by their next run.
+func (s *Scheduler) Instructions() []Instruction {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := make([]Instruction, 0, len(s.instructions))
+	for _, in := range s.instructions {
+		list = append(list, *in)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].NextRun.Equal(list[j].NextRun) {
+			return list[i].NextRun.Before(list[j].NextRun)
+		}
+		return list[i].ID < list[j].ID
+	})
+	return list
+}
+
+// Runs returns the recorded outcomes of an instruction, oldest first.
+func (s *Scheduler) Runs(id string) []ScheduledRun {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]ScheduledRun(nil), s.runs[id]...)
+}
+
+// Skipped returns the occurrences of an instruction that were missed and
+// never executed, oldest first.
+func (s *Scheduler) Skipped(id string) []ScheduledRun {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var skipped []ScheduledRun
+	for _, run := range s.runs[id] {
+		if run.Skipped {
+			skipped = append(skipped, run)
+		}
+	}
+	return skipped
+}
+
+// dueRun is an occurrence claimed for execution. Retries of a failed
+// occurrence wait in Scheduler.retries until at.
+type dueRun struct {
+	instruction Instruction
+	due         time.Time
+	attempt     int
+	at          time.Time
+}
+
+// RunDue executes every instruction that is due and every failed occurrence
+// whose retry is due, and returns the outcomes, including skipped
+// occurrences.
+func (s *Scheduler) RunDue(ctx context.Context) []ScheduledRun {
+	now := s.now()
+	due, skipped := s.claimDue(now)
+
+	retryable := s.retry.Retryable
+	if retryable == nil {
+		retryable = IsTransient
+	}
+	executed := make([]ScheduledRun, 0, len(due))
+	var retries []dueRun
+	for _, run := range due {
+		in := run.instruction
+		key := fmt.Sprintf("%s@%s", in.ID, run.due.UTC().Format(time.RFC3339))
+		receipt, err := s.facade.Transfer(ctx, in.FromAccount, in.Pin, in.FromBank, in.ToAccount, in.ToBank, in.Amount,
+			WithIdempotencyKey(key))
+		outcome := ScheduledRun{InstructionID: in.ID, Due: run.due, RanAt: now, Attempt: run.attempt, Receipt: receipt}
+		if err != nil {
+			outcome.Error = err.Error()
+			if run.attempt < s.retry.MaxAttempts && retryable(err) {
+				delay := s.retry.delay(run.attempt)
+				if s.retry.OnRetry != nil {
+					s.retry.OnRetry(OpTransfer, run.attempt, err, delay)
+				}
+				outcome.RetryAt = now.Add(delay)
+				retries = append(retries, dueRun{instruction: in, due: run.due, attempt: run.attempt + 1, at: outcome.RetryAt})
+			}
+		}
+		executed = append(executed, outcome)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, outcome := range executed {
+		s.runs[outcome.InstructionID] = append(s.runs[outcome.InstructionID], outcome)
+	}
+	for _, retry := range retries {
+		if in, exists := s.instructions[retry.instruction.ID]; exists && !in.Cancelled {
+			s.retries[in.ID] = append(s.retries[in.ID], retry)
+		}
+	}
+	return append(skipped, executed...)
+}
+
+// claimDue takes the retries due by now and advances every due instruction
+// past now, so concurrent callers never run the same occurrence, and
+// records the occurrences it skips.
+func (s *Scheduler) claimDue(now time.Time) ([]dueRun, []ScheduledRun) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := make([]string, 0, len(s.instructions))
+	for id := range s.instructions {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var due []dueRun
+	var skipped []ScheduledRun
+	for _, id := range ids {
+		in := s.instructions[id]
+		waiting := s.retries[id][:0]
+		for _, retry := range s.retries[id] {
+			if retry.at.After(now) {
+				waiting = append(waiting, retry)
+			} else {
+				due = append(due, retry)
+			}
+		}
+		if len(waiting) == 0 {
+			delete(s.retries, id)
+		} else {
+			s.retries[id] = waiting
+		}
+
+		if !in.Active() || in.NextRun.After(now) {
+			continue
+		}
+		latest := in.NextRun
+		for in.NextRun = in.following(in.Runs); !in.NextRun.IsZero() && !in.NextRun.After(now); in.NextRun = in.following(in.Runs) {
+			if s.missed == RunMissedRuns {
+				due = append(due, dueRun{instruction: *in, due: latest, attempt: 1})
+			} else {
+				skip := ScheduledRun{InstructionID: id, Due: latest, RanAt: now, Skipped: true}
+				skipped = append(skipped, skip)
+				s.runs[id] = append(s.runs[id], skip)
+			}
+			in.Runs++
+			latest = in.NextRun
+		}
+		in.Runs++
+		due = append(due, dueRun{instruction: *in, due: latest, attempt: 1})
+	}
+	return due, skipped
+}
+
+// Run calls RunDue on every tick of interval until ctx ends.
+func (s *Scheduler) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	s.RunDue(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			s.RunDue(ctx)
+		}
+	}
+}
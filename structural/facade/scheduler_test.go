@@ -0,0 +1,170 @@
+package facade_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go-design-patterns/structural/facade"
+	"go-design-patterns/structural/facade/facadetest"
+)
+
+// newTestScheduler schedules a daily 10.00 transfer from AlphaMain to
+// BetaMain starting now on clock.
+func newTestScheduler(t *testing.T, h *facadetest.Harness, clock *testClock, opts ...facade.SchedulerOption) (*facade.Scheduler, string) {
+	t.Helper()
+	scheduler := facade.NewScheduler(h.Facade, append([]facade.SchedulerOption{facade.WithSchedulerClock(clock.Now)}, opts...)...)
+	from, to := facadetest.AlphaMain, facadetest.BetaMain
+	in, err := scheduler.Schedule(facade.Instruction{
+		FromBank: from.Bank, FromAccount: from.AccountNo, Pin: from.Pin,
+		ToBank: to.Bank, ToAccount: to.AccountNo,
+		Amount: mustMoney(t, "10.00", "THB"), Frequency: facade.Daily, Start: clock.Now(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return scheduler, in.ID
+}
+
+func TestSchedulerRetriesFailedRuns(t *testing.T) {
+	tests := []struct {
+		name         string
+		faults       []facadetest.Fault
+		wantAttempts int
+		wantSource   string
+	}{
+		{
+			name:         "transient failure is retried",
+			faults:       []facadetest.Fault{{Op: facade.OpTransfer, Retryable: true, Times: 1}},
+			wantAttempts: 2,
+			wantSource:   "990.00",
+		},
+		{
+			name:         "permanent failure is not retried",
+			faults:       []facadetest.Fault{{Op: facade.OpTransfer, Times: 1}},
+			wantAttempts: 1,
+			wantSource:   "1000.00",
+		},
+		{
+			name:         "attempts are capped",
+			faults:       []facadetest.Fault{{Op: facade.OpTransfer, Retryable: true}},
+			wantAttempts: 3,
+			wantSource:   "1000.00",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, err := facadetest.NewHarness(facadetest.StandardBanks())
+			if err != nil {
+				t.Fatal(err)
+			}
+			h.Bank("Alpha").Inject(tt.faults...)
+			clock := newTestClock()
+			scheduler, id := newTestScheduler(t, h, clock)
+			due := clock.Now()
+
+			// Retries wait one and then two minutes; the next occurrence is a day away
+			for _, step := range []time.Duration{0, time.Minute, 2 * time.Minute, time.Hour} {
+				clock.Advance(step)
+				scheduler.RunDue(context.Background())
+			}
+
+			runs := scheduler.Runs(id)
+			if len(runs) != tt.wantAttempts {
+				t.Fatalf("%d runs, want %d: %+v", len(runs), tt.wantAttempts, runs)
+			}
+			for i, run := range runs {
+				if !run.Due.Equal(due) || run.Attempt != i+1 {
+					t.Errorf("run %d is attempt %d of %s, want attempt %d of %s", i, run.Attempt, run.Due, i+1, due)
+				}
+				last := i == len(runs)-1
+				if !last && (run.Error == "" || run.RetryAt.IsZero()) {
+					t.Errorf("run %d was retried after error %q with RetryAt %s", i, run.Error, run.RetryAt)
+				}
+				if last && !run.RetryAt.IsZero() {
+					t.Errorf("last run %d has a retry at %s", i, run.RetryAt)
+				}
+			}
+			if balance, _ := h.Balance("Alpha", facadetest.AlphaMain.AccountNo); balance.Decimal() != tt.wantSource {
+				t.Errorf("source balance %s, want %s", balance.Decimal(), tt.wantSource)
+			}
+		})
+	}
+}
+
+func TestSchedulerCancelDropsRetries(t *testing.T) {
+	h, err := facadetest.NewHarness(facadetest.StandardBanks())
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Bank("Alpha").Inject(facadetest.Fault{Op: facade.OpTransfer, Retryable: true, Times: 1})
+	clock := newTestClock()
+	scheduler, id := newTestScheduler(t, h, clock)
+
+	scheduler.RunDue(context.Background())
+	if err := scheduler.Cancel(id); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(time.Hour)
+	if runs := scheduler.RunDue(context.Background()); len(runs) != 0 {
+		t.Errorf("cancelled instruction ran again: %+v", runs)
+	}
+}
+
+func TestSchedulerMissedRuns(t *testing.T) {
+	tests := []struct {
+		name         string
+		policy       facade.MissedRuns
+		wantExecuted int
+		wantSkipped  int
+		wantSource   string
+	}{
+		{name: "skipped by default", policy: facade.SkipMissedRuns, wantExecuted: 1, wantSkipped: 3, wantSource: "990.00"},
+		{name: "run when asked", policy: facade.RunMissedRuns, wantExecuted: 4, wantSkipped: 0, wantSource: "960.00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, err := facadetest.NewHarness(facadetest.StandardBanks())
+			if err != nil {
+				t.Fatal(err)
+			}
+			clock := newTestClock()
+			start := clock.Now()
+			scheduler, id := newTestScheduler(t, h, clock, facade.WithMissedRuns(tt.policy))
+
+			// The scheduler was down for the first three days
+			clock.Advance(3 * 24 * time.Hour)
+			var executed []facade.ScheduledRun
+			for _, run := range scheduler.RunDue(context.Background()) {
+				if !run.Skipped {
+					executed = append(executed, run)
+				}
+			}
+			if len(executed) != tt.wantExecuted {
+				t.Fatalf("%d runs executed, want %d", len(executed), tt.wantExecuted)
+			}
+			for i, run := range executed {
+				if run.Error != "" {
+					t.Errorf("run due %s: %s", run.Due, run.Error)
+				}
+				// The runs are the latest occurrences, oldest first
+				if want := start.AddDate(0, 0, 4-len(executed)+i); !run.Due.Equal(want) {
+					t.Errorf("run %d due %s, want %s", i, run.Due, want)
+				}
+			}
+
+			skipped := scheduler.Skipped(id)
+			if len(skipped) != tt.wantSkipped {
+				t.Fatalf("%d occurrences skipped, want %d", len(skipped), tt.wantSkipped)
+			}
+			for i, run := range skipped {
+				if want := start.AddDate(0, 0, i); !run.Due.Equal(want) {
+					t.Errorf("skipped run %d due %s, want %s", i, run.Due, want)
+				}
+			}
+			if balance, _ := h.Balance("Alpha", facadetest.AlphaMain.AccountNo); balance.Decimal() != tt.wantSource {
+				t.Errorf("source balance %s, want %s", balance.Decimal(), tt.wantSource)
+			}
+		})
+	}
+}
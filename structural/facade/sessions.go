@@ -0,0 +1,327 @@
+package facade
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// defaultRefreshBefore is how long before expiry a cached session is replaced.
+const defaultRefreshBefore = time.Minute
+
+// sessionExpirer is implemented by bank APIs that know when their session ends.
+type sessionExpirer interface {
+	SessionExpiry() time.Time
+}
+
+// sessionLogouter is implemented by bank APIs that can end their session.
+type sessionLogouter interface {
+	Logout(ctx context.Context) error
+}
+
+// SessionOption configures a SessionManager.
+type SessionOption func(*SessionManager)
+
+// WithRefreshBefore replaces cached sessions d before they expire, so a
+// transfer never starts on a session about to end. The default is a minute.
+func WithRefreshBefore(d time.Duration) SessionOption {
+	return func(m *SessionManager) {
+		m.refreshBefore = d
+	}
+}
+
+// WithSessionClock makes the session manager read the time from now.
+func WithSessionClock(now func() time.Time) SessionOption {
+	return func(m *SessionManager) {
+		m.now = now
+	}
+}
+
+// SessionInfo describes a cached session.
+type SessionInfo struct {
+	Bank      string
+	AccountNo string
+	LoggedIn  time.Time
+	// ExpiresAt is zero if the bank does not say when sessions end.
+	ExpiresAt time.Time
+}
+
+type sessionKey struct {
+	bank      string
+	accountNo string
+}
+
+// managedSession is the cached session of one account. Its mutex is held
+// while logging in, so concurrent callers share a single login.
+type managedSession struct {
+	mu       sync.Mutex
+	api      BankApi
+	pinHash  [32]byte
+	loggedIn time.Time
+	expires  time.Time
+}
+
+// SessionManager caches logged-in bank APIs per account so transfers from
+// the same account do not log in every time. Sessions are replaced shortly
+// before they expire and dropped when the bank no longer accepts them. It
+// is safe for concurrent use.
+type SessionManager struct {
+	facade        *BankTransferFacade
+	refreshBefore time.Duration
+	now           func() time.Time
+
+	mu       sync.Mutex
+	sessions map[sessionKey]*managedSession
+}
+
+// NewSessionManager creates a manager logging in through the banks
+// registered with facade.
+func NewSessionManager(facade *BankTransferFacade, opts ...SessionOption) *SessionManager {
+	m := &SessionManager{
+		facade:        facade,
+		refreshBefore: defaultRefreshBefore,
+		now:           time.Now,
+		sessions:      make(map[sessionKey]*managedSession),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+func sessionPinHash(bank, accountNo, pin string) [32]byte {
+	return sha256.Sum256([]byte(bank + "\x00" + accountNo + "\x00" + pin))
+}
+
+// Session returns a logged-in API for the account, reusing the cached
+// session when the PIN matches and the session is not close to expiry.
+func (m *SessionManager) Session(ctx context.Context, bank, accountNo, pin string) (BankApi, error) {
+	key := sessionKey{bank: bank, accountNo: accountNo}
+
+	m.mu.Lock()
+	session, exists := m.sessions[key]
+	if !exists {
+		session = &managedSession{}
+		m.sessions[key] = session
+	}
+	m.mu.Unlock()
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	pinHash := sessionPinHash(bank, accountNo, pin)
+	if session.api != nil && subtle.ConstantTimeCompare(session.pinHash[:], pinHash[:]) == 1 && m.fresh(session) {
+		return session.api, nil
+	}
+
+	// Log in on a new instance; callers still using the old one keep it
+	// until its session ends
+	factory, err := m.facade.bankFactory(bank)
+	if err != nil {
+		return nil, err
+	}
+	api := factory(accountNo, bank, pin)
+	if err := m.facade.login(ctx, api, bank, accountNo); err != nil {
+		return nil, err
+	}
+	session.api = api
+	session.pinHash = pinHash
+	session.loggedIn = m.now()
+	session.expires = time.Time{}
+	if expirer, ok := api.(sessionExpirer); ok {
+		session.expires = expirer.SessionExpiry()
+	}
+	return api, nil
+}
+
+// fresh reports whether a cached session can still be handed out.
+func (m *SessionManager) fresh(session *managedSession) bool {
+	if !session.expires.IsZero() && !m.now().Before(session.expires.Add(-m.refreshBefore)) {
+		return false
+	}
+	return session.api.IsLoggedIn()
+}
+
+// Invalidate drops the cached session of the account without logging out,
+// e.g. after the bank rejected it.
+func (m *SessionManager) Invalidate(bank, accountNo string) {
+	m.invalidate(sessionKey{bank: bank, accountNo: accountNo}, nil)
+}
+
+// invalidate drops the cached session of key, but only if it still is api
+// when api is given.
+func (m *SessionManager) invalidate(key sessionKey, api BankApi) {
+	m.mu.Lock()
+	session, exists := m.sessions[key]
+	m.mu.Unlock()
+	if !exists {
+		return
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	if api == nil || session.api == api {
+		session.api = nil
+	}
+}
+
+// Logout ends the cached session of the account at its bank and forgets it.
+func (m *SessionManager) Logout(ctx context.Context, bank, accountNo string) error {
+	key := sessionKey{bank: bank, accountNo: accountNo}
+
+	m.mu.Lock()
+	session, exists := m.sessions[key]
+	delete(m.sessions, key)
+	m.mu.Unlock()
+	if !exists {
+		return nil
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	api := session.api
+	session.api = nil
+	if logouter, ok := api.(sessionLogouter); ok {
+		return logouter.Logout(ctx)
+	}
+	return nil
+}
+
+// LogoutAll ends every cached session.
+func (m *SessionManager) LogoutAll(ctx context.Context) error {
+	var errs []error
+	for _, info := range m.Sessions() {
+		errs = append(errs, m.Logout(ctx, info.Bank, info.AccountNo))
+	}
+	return errors.Join(errs...)
+}
+
+// Sessions lists the cached sessions ordered by bank and account.
+func (m *SessionManager) Sessions() []SessionInfo {
+	m.mu.Lock()
+	keys := make([]sessionKey, 0, len(m.sessions))
+	sessions := make([]*managedSession, 0, len(m.sessions))
+	for key, session := range m.sessions {
+		keys = append(keys, key)
+		sessions = append(sessions, session)
+	}
+	m.mu.Unlock()
+
+	var list []SessionInfo
+	for i, session := range sessions {
+		session.mu.Lock()
+		if session.api != nil {
+			list = append(list, SessionInfo{
+				Bank:      keys[i].bank,
+				AccountNo: keys[i].accountNo,
+				LoggedIn:  session.loggedIn,
+				ExpiresAt: session.expires,
+			})
+		}
+		session.mu.Unlock()
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Bank != list[j].Bank {
+			return list[i].Bank < list[j].Bank
+		}
+		return list[i].AccountNo < list[j].AccountNo
+	})
+	return list
+}
+
+// managedBankApi is the source API the facade uses while sessions are
+// managed. Login takes a session from the manager instead of logging in,
+// and a session the bank stops accepting is dropped from the cache.
+type managedBankApi struct {
+	manager   *SessionManager
+	bank      string
+	accountNo string
+	pin       string
+	api       BankApi
+}
+
+func (s *managedBankApi) Login(ctx context.Context) error {
+	api, err := s.manager.Session(ctx, s.bank, s.accountNo, s.pin)
+	if err != nil {
+		return err
+	}
+	s.api = api
+	return nil
+}
+
+func (s *managedBankApi) IsLoggedIn() bool {
+	return s.api != nil && s.api.IsLoggedIn()
+}
+
+// checked drops the session from the cache if err came with it ending.
+func (s *managedBankApi) checked(err error) error {
+	if err != nil && (errors.Is(err, ErrNotLoggedIn) || !s.api.IsLoggedIn()) {
+		s.manager.invalidate(sessionKey{bank: s.bank, accountNo: s.accountNo}, s.api)
+	}
+	return err
+}
+
+func (s *managedBankApi) Transfer(ctx context.Context, toAcc, toBank string, amount Money) (string, error) {
+	if s.api == nil {
+		return "", &BankError{Bank: s.bank, Op: OpTransfer, Err: ErrNotLoggedIn}
+	}
+	confToken, err := s.api.Transfer(ctx, toAcc, toBank, amount)
+	return confToken, s.checked(err)
+}
+
+func (s *managedBankApi) Confirm(ctx context.Context, confToken string) error {
+	if s.api == nil {
+		return &BankError{Bank: s.bank, Op: OpConfirm, Err: ErrNotLoggedIn}
+	}
+	return s.checked(s.api.Confirm(ctx, confToken))
+}
+
+func (s *managedBankApi) Cancel(ctx context.Context, confToken string) error {
+	if s.api == nil {
+		return &BankError{Bank: s.bank, Op: OpCancel, Err: ErrNotLoggedIn}
+	}
+	return s.checked(s.api.Cancel(ctx, confToken))
+}
+
+func (s *managedBankApi) Balance(ctx context.Context) (Money, error) {
+	if s.api == nil {
+		return Money{}, &BankError{Bank: s.bank, Op: OpBalance, Err: ErrNotLoggedIn}
+	}
+	balance, err := s.api.Balance(ctx)
+	return balance, s.checked(err)
+}
+
+// CollectFee forwards to the session's API, if it collects fees.
+func (s *managedBankApi) CollectFee(ctx context.Context, ref string, fee Money) error {
+	collector, ok := s.api.(FeeCollector)
+	if !ok {
+		return nil
+	}
+	return s.checked(collector.CollectFee(ctx, ref, fee))
+}
+
+// WithSessionReuse makes the facade keep source bank sessions between
+// transfers, managed by a SessionManager configured with opts.
+func WithSessionReuse(opts ...SessionOption) Option {
+	return func(f *BankTransferFacade) error {
+		f.sessions = NewSessionManager(f, opts...)
+		return nil
+	}
+}
+
+// Sessions returns the session manager, or nil if sessions are not reused.
+func (f *BankTransferFacade) Sessions() *SessionManager {
+	return f.sessions
+}
+
+// sourceApi creates the API the facade transfers from.
+func (f *BankTransferFacade) sourceApi(factory BankFactory, accountNo, bank, pin string) BankApi {
+	if f.sessions == nil {
+		return factory(accountNo, bank, pin)
+	}
+	return &managedBankApi{manager: f.sessions, bank: bank, accountNo: accountNo, pin: pin}
+}
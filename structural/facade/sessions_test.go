@@ -0,0 +1,199 @@
+package facade_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"go-design-patterns/structural/facade"
+)
+
+// expiringBank hands out APIs whose sessions end ttl after login on its clock.
+type expiringBank struct {
+	clock *testClock
+	ttl   time.Duration
+
+	mu      sync.Mutex
+	logins  int
+	logouts int
+	// reject is returned by the next Balance call, ending the session.
+	reject error
+}
+
+func (b *expiringBank) factory(accountNo, bank, pin string) facade.BankApi {
+	return &expiringApi{bank: b}
+}
+
+func (b *expiringBank) counts() (logins, logouts int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.logins, b.logouts
+}
+
+type expiringApi struct {
+	stubApi
+	bank    *expiringBank
+	expires time.Time
+}
+
+func (a *expiringApi) Login(ctx context.Context) error {
+	a.bank.mu.Lock()
+	defer a.bank.mu.Unlock()
+	a.bank.logins++
+	a.expires = a.bank.clock.Now().Add(a.bank.ttl)
+	return nil
+}
+
+func (a *expiringApi) IsLoggedIn() bool {
+	return a.bank.clock.Now().Before(a.expires)
+}
+
+func (a *expiringApi) SessionExpiry() time.Time {
+	return a.expires
+}
+
+func (a *expiringApi) Logout(ctx context.Context) error {
+	a.bank.mu.Lock()
+	defer a.bank.mu.Unlock()
+	a.bank.logouts++
+	a.expires = time.Time{}
+	return nil
+}
+
+func (a *expiringApi) Balance(ctx context.Context) (facade.Money, error) {
+	a.bank.mu.Lock()
+	defer a.bank.mu.Unlock()
+	if err := a.bank.reject; err != nil {
+		a.bank.reject = nil
+		a.expires = time.Time{}
+		return facade.Money{}, err
+	}
+	return facade.Money{}, nil
+}
+
+// newSessionFacade registers an expiringBank as "Stub" on a facade that
+// reuses sessions, refreshing them a minute before they end.
+func newSessionFacade(t *testing.T) (*facade.BankTransferFacade, *expiringBank) {
+	t.Helper()
+	bank := &expiringBank{clock: newTestClock(), ttl: 10 * time.Minute}
+	f, err := facade.NewBankTransferFacade(
+		facade.WithBackend(facade.NewBackend(facade.NewMemoryCredentialStore(), facade.WithPinHashIterations(1000))),
+		facade.WithoutDefaultBanks(),
+		facade.WithBank("Stub", bank.factory),
+		facade.WithSessionReuse(facade.WithRefreshBefore(time.Minute), facade.WithSessionClock(bank.clock.Now)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f, bank
+}
+
+func TestSessionRefreshedBeforeExpiry(t *testing.T) {
+	f, bank := newSessionFacade(t)
+	sessions := f.Sessions()
+	ctx := context.Background()
+
+	first, err := sessions.Session(ctx, "Stub", "1001", "1111")
+	if err != nil {
+		t.Fatal(err)
+	}
+	loggedIn := bank.clock.Now()
+	bank.clock.Advance(9*time.Minute - time.Second)
+	if again, _ := sessions.Session(ctx, "Stub", "1001", "1111"); again != first {
+		t.Error("session replaced before the refresh window")
+	}
+	if logins, _ := bank.counts(); logins != 1 {
+		t.Errorf("%d logins, want 1", logins)
+	}
+
+	info := sessions.Sessions()
+	if len(info) != 1 || !info[0].LoggedIn.Equal(loggedIn) || !info[0].ExpiresAt.Equal(loggedIn.Add(10*time.Minute)) {
+		t.Errorf("sessions %+v, want one logged in at %s expiring 10 minutes later", info, loggedIn)
+	}
+
+	// A minute before expiry the session is replaced, not handed out
+	bank.clock.Advance(time.Second)
+	refreshed, err := sessions.Session(ctx, "Stub", "1001", "1111")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refreshed == first {
+		t.Error("session handed out inside the refresh window")
+	}
+	if logins, _ := bank.counts(); logins != 2 {
+		t.Errorf("%d logins, want 2", logins)
+	}
+}
+
+func TestSessionNotSharedAcrossPins(t *testing.T) {
+	f, bank := newSessionFacade(t)
+	ctx := context.Background()
+
+	first, _ := f.Sessions().Session(ctx, "Stub", "1001", "1111")
+	second, _ := f.Sessions().Session(ctx, "Stub", "1001", "9999")
+	if first == second {
+		t.Error("session reused for a different PIN")
+	}
+	if logins, _ := bank.counts(); logins != 2 {
+		t.Errorf("%d logins, want 2", logins)
+	}
+}
+
+func TestSessionInvalidate(t *testing.T) {
+	f, bank := newSessionFacade(t)
+	ctx := context.Background()
+
+	first, _ := f.Sessions().Session(ctx, "Stub", "1001", "1111")
+	f.Sessions().Invalidate("Stub", "1001")
+	if len(f.Sessions().Sessions()) != 0 {
+		t.Error("invalidated session still listed")
+	}
+	if again, _ := f.Sessions().Session(ctx, "Stub", "1001", "1111"); again == first {
+		t.Error("invalidated session handed out")
+	}
+	if logins, logouts := bank.counts(); logins != 2 || logouts != 0 {
+		t.Errorf("%d logins and %d logouts, want 2 and none", logins, logouts)
+	}
+
+	if err := f.Sessions().Logout(ctx, "Stub", "1001"); err != nil {
+		t.Fatal(err)
+	}
+	if _, logouts := bank.counts(); logouts != 1 {
+		t.Errorf("%d logouts, want 1", logouts)
+	}
+}
+
+func TestSessionDroppedWhenBankRejectsIt(t *testing.T) {
+	f, bank := newSessionFacade(t)
+	ctx := context.Background()
+	balance := func() error {
+		_, err := f.Balance(ctx, "1001", "1111", "Stub")
+		return err
+	}
+
+	if err := balance(); err != nil {
+		t.Fatal(err)
+	}
+	if err := balance(); err != nil {
+		t.Fatal(err)
+	}
+	if logins, _ := bank.counts(); logins != 1 {
+		t.Fatalf("%d logins for two calls, want 1", logins)
+	}
+
+	bank.reject = &facade.BankError{Bank: "Stub", Op: facade.OpBalance, Err: facade.ErrNotLoggedIn}
+	if err := balance(); !errors.Is(err, facade.ErrNotLoggedIn) {
+		t.Fatalf("balance = %v, want ErrNotLoggedIn", err)
+	}
+	if len(f.Sessions().Sessions()) != 0 {
+		t.Error("rejected session still cached")
+	}
+	if err := balance(); err != nil {
+		t.Fatal(err)
+	}
+	if logins, _ := bank.counts(); logins != 2 {
+		t.Errorf("%d logins after the bank rejected the session, want 2", logins)
+	}
+}
@@ -0,0 +1,44 @@
+package facade
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// defaultBankTimeout bounds bank calls unless configured otherwise.
+const defaultBankTimeout = 30 * time.Second
+
+// WithBankTimeout limits every call to the given bank to d.
+func WithBankTimeout(bank string, d time.Duration) Option {
+	return func(f *BankTransferFacade) error {
+		if d <= 0 {
+			return errors.New("bank timeout must be positive")
+		}
+		f.timeouts[bank] = d
+		return nil
+	}
+}
+
+// WithDefaultBankTimeout limits calls to banks without their own timeout.
+func WithDefaultBankTimeout(d time.Duration) Option {
+	return func(f *BankTransferFacade) error {
+		if d <= 0 {
+			return errors.New("bank timeout must be positive")
+		}
+		f.defaultTimeout = d
+		return nil
+	}
+}
+
+// bankContext derives the context for a single call to bank.
+func (f *BankTransferFacade) bankContext(ctx context.Context, bank string) (context.Context, context.CancelFunc) {
+	timeout, exists := f.timeouts[bank]
+	if !exists {
+		timeout = f.defaultTimeout
+	}
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
@@ -0,0 +1,120 @@
+package facade_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go-design-patterns/structural/facade"
+	"go-design-patterns/structural/facade/facadetest"
+)
+
+func TestBankTimeoutOptions(t *testing.T) {
+	for _, opt := range []facade.Option{
+		facade.WithBankTimeout("Alpha", 0),
+		facade.WithBankTimeout("Alpha", -time.Second),
+		facade.WithDefaultBankTimeout(0),
+	} {
+		if _, err := facade.NewBankTransferFacade(opt); err == nil {
+			t.Error("non-positive bank timeout was accepted")
+		}
+	}
+}
+
+// transferTen transfers 10.00 from AlphaMain to BetaMain.
+func transferTen(ctx context.Context, h *facadetest.Harness) (facade.Receipt, error) {
+	from, to := facadetest.AlphaMain, facadetest.BetaMain
+	amount, _ := facade.ParseMoney("10.00", "THB")
+	return h.Facade.Transfer(ctx, from.AccountNo, from.Pin, from.Bank, to.AccountNo, to.Bank, amount)
+}
+
+func TestSlowHoldTimesOut(t *testing.T) {
+	h, err := facadetest.NewHarness(facadetest.StandardBanks(), facade.WithDefaultBankTimeout(20*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Bank("Alpha").Inject(facadetest.Fault{Op: facade.OpTransfer, Delay: time.Second})
+
+	start := time.Now()
+	_, err = transferTen(context.Background(), h)
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("transfer took %s despite a 20ms timeout", elapsed)
+	}
+	var transferErr *facade.TransferError
+	if !errors.As(err, &transferErr) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("transfer = %v, want a TransferError wrapping context.DeadlineExceeded", err)
+	}
+	if transferErr.Step != "transfer" || transferErr.State != facade.StateFailed || !transferErr.Temporary() {
+		t.Errorf("timeout at %s in state %s, temporary %t", transferErr.Step, transferErr.State, transferErr.Temporary())
+	}
+	if balance, _ := h.Balance("Alpha", facadetest.AlphaMain.AccountNo); balance.Decimal() != "1000.00" {
+		t.Errorf("source balance %s after a timed out hold", balance)
+	}
+}
+
+func TestBankTimeoutOverridesDefault(t *testing.T) {
+	h, err := facadetest.NewHarness(facadetest.StandardBanks(),
+		facade.WithDefaultBankTimeout(20*time.Millisecond),
+		facade.WithBankTimeout("Beta", time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Bank("Beta").Inject(facadetest.Fault{Op: facade.OpCredit, Delay: 50 * time.Millisecond})
+
+	receipt, err := transferTen(context.Background(), h)
+	if err != nil || receipt.Status != facade.StateCommitted {
+		t.Fatalf("transfer = %s, %v; want committed within Beta's own timeout", receipt.Status, err)
+	}
+}
+
+func TestCancelDuringCreditCompensates(t *testing.T) {
+	h, err := facadetest.NewHarness(facadetest.StandardBanks())
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Bank("Beta").Inject(facadetest.Fault{Op: facade.OpCredit, Delay: time.Second})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	receipt, err := transferTen(ctx, h)
+
+	var transferErr *facade.TransferError
+	if !errors.As(err, &transferErr) || !errors.Is(err, context.Canceled) {
+		t.Fatalf("transfer = %v, want a TransferError wrapping context.Canceled", err)
+	}
+	// The hold is released on a context detached from the cancelled one
+	if transferErr.Step != "credit" || receipt.Status != facade.StateCompensated {
+		t.Errorf("cancelled at %s with status %s, want credit compensated", transferErr.Step, receipt.Status)
+	}
+	if calls := h.Bank("Alpha").Calls(facade.OpCancel); calls != 1 {
+		t.Errorf("hold cancelled %d times, want once", calls)
+	}
+	if balance, _ := h.Balance("Alpha", facadetest.AlphaMain.AccountNo); balance.Decimal() != "1000.00" {
+		t.Errorf("source balance %s after a cancelled transfer", balance)
+	}
+}
+
+func TestCancelledContextCallsNoBank(t *testing.T) {
+	h, err := facadetest.NewHarness(facadetest.StandardBanks())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := transferTen(ctx, h); !errors.Is(err, context.Canceled) {
+		t.Errorf("transfer = %v, want context.Canceled", err)
+	}
+	if _, err := h.Facade.Balance(ctx, facadetest.AlphaMain.AccountNo, facadetest.AlphaMain.Pin, "Alpha"); !errors.Is(err, context.Canceled) {
+		t.Errorf("balance = %v, want context.Canceled", err)
+	}
+	for _, op := range []facade.BankOperation{facade.OpTransfer, facade.OpBalance} {
+		if calls := h.Bank("Alpha").Calls(op); calls != 0 {
+			t.Errorf("%s called %d times with a cancelled context", op, calls)
+		}
+	}
+	if history, err := h.Facade.History(facade.HistoryFilter{}); err != nil || len(history) != 0 {
+		t.Errorf("cancelled transfer left history %v, %v", history, err)
+	}
+}